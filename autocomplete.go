@@ -6,32 +6,69 @@ package autocomplete
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"runtime"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const SERVICE_NAME = "autocomplete"
 
+// ErrServiceClosed is returned, wrapped, by the methods of a closed service
+// that return an error. Use errors.Is to check for it.
+var ErrServiceClosed = errors.New("service is closed")
+
+// ErrWordTooLong is returned, wrapped, by AddE for the words longer than
+// MaxWordLength, see WithMaxWordLength().
+var ErrWordTooLong = errors.New("word is longer than the maximum word length")
+
+// ErrNoSnapshotDest is returned, wrapped, by CreateSnapshot and
+// RestoreFromSnapshot when SnapshotDest is nil or has no provider.
+var ErrNoSnapshotDest = errors.New("no snapshot destination set")
+
 type autocompleter interface {
 	// Insert will insert the word into the in-memory data structure
 	// representing the store.
 	Insert(word string)
+	// InsertBatch inserts every word, locking the store once for the whole
+	// batch instead of once per word.
+	InsertBatch(words []string)
 	// Autocomplete will take a prefix and generate a list of words
-	// that begin with that prefix.
+	// that begin with that prefix, the prefix included when it is a word.
+	// The built in stores return the words sorted lexically.
 	Autocomplete(prefix string) []string
+	// Delete will remove the word from the store, and return whether or
+	// not it existed. Words the deleted word is a prefix of are kept.
+	Delete(word string) bool
 	// Contains will take in a word and return whether or not it
 	// exists in the store.
 	Contains(word string) bool
 	// ListContents will return every word currently stored in the
-	// completion service.
+	// completion service, sorted lexically by the built in stores.
 	ListContents() []string
 	// Visualize returns a graphviz `.dot` file in the form of a byte slice
 	// so that the caller can use it to visualize the data structure.
 	Visualize(w io.Writer) error
 	// Clear will clear the contents of the data structure.
 	Clear()
+	// Count returns the number of words stored, without collecting them.
+	Count() int
+	// PrefixCount returns the number of stored words starting with prefix,
+	// without collecting them.
+	PrefixCount(prefix string) int
+	// NodeCount returns the number of nodes holding a character, which is
+	// what the memory used by the store grows with.
+	NodeCount() int
 }
 
 // Autocomplete service is the main object you will be interacting with.
@@ -43,10 +80,110 @@ type AutocompleteService struct {
 
 	store autocompleter
 
+	// Errors are also logged to Config.Logger, see WithLogger(). Both are
+	// written by the background goroutines too, use GetErrors() and
+	// GetLastUpdated() to read them while the service is in use.
 	Errors      []error
 	LastUpdated int64
-	isClosed    bool
-	// TODO: Log
+	// state guards Errors and LastUpdated. The completion paths never take
+	// it, they only hold the lifecycle read lock.
+	state sync.RWMutex
+
+	// closed is only set while holding the lifecycle write lock, while every
+	// guarded operation holds the read lock for its whole duration. This way
+	// Close waits for the operations in flight and no operation can start
+	// once the service is closed.
+	closed    atomic.Bool
+	lifecycle sync.RWMutex
+
+	queries queryCounter
+
+	// words skipped by loads, see WithLoadDedupe().
+	deduped atomic.Int64
+	// words longer than MaxWordLength, see WithMaxWordLength().
+	rejected atomic.Int64
+
+	// per data source circuit breakers keyed by the index in Config.DataSources,
+	// nil when they are disabled. Filled as the sources are loaded.
+	breakers   map[int]*sourceBreaker
+	breakersMu sync.Mutex
+
+	// now is swapped out by tests.
+	now func() time.Time
+
+	// rng is used by every randomized operation, see WithSeed().
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	// automatic snapshots, see snapshot.go.
+	snapshots  *snapshotLoop
+	snapshotMu sync.Mutex
+	newTicker  func(d time.Duration) ticker
+	// snapshotInterval is the exact interval given to SetSnapshotInterval(),
+	// Config.SnapshotInterval only holds whole seconds.
+	snapshotInterval time.Duration
+
+	// automatic updates, see watch.go.
+	watcher   *fileWatcher
+	watcherMu sync.Mutex
+
+	// namespaced stores, see namespace.go.
+	namespaces map[string]autocompleter
+	nsMu       sync.RWMutex
+
+	// built on first use, see fold.go.
+	folds foldIndex
+
+	// only built when WithSubstringIndex() is set, see substring.go.
+	substrings substringIndex
+
+	// nil unless WithSuggestionCache() is set, see cache.go. Every write to the
+	// store must call invalidate() once done.
+	cache *suggestionCache
+
+	// recent inserts, see trending.go.
+	trends trendTracker
+}
+
+// QueryStats is a point in time view of the completion queries served
+// by the service. See AutocompleteService.QueryStats().
+type QueryStats struct {
+	// Total is the number of completion queries served.
+	Total int64
+	// PrefixLengths maps a prefix length (in runes) to the number of
+	// queries that were made with a prefix of that length.
+	PrefixLengths map[int]int64
+}
+
+// queryCounter keeps the query counts using atomics so Complete can be
+// called from any number of goroutines without taking a lock.
+type queryCounter struct {
+	total atomic.Int64
+	// map[int]*atomic.Int64 keyed by prefix length.
+	lengths sync.Map
+}
+
+func (q *queryCounter) record(prefix string) {
+	q.total.Add(1)
+
+	length := utf8.RuneCountInString(prefix)
+	counter, ok := q.lengths.Load(length)
+	if !ok {
+		counter, _ = q.lengths.LoadOrStore(length, new(atomic.Int64))
+	}
+	counter.(*atomic.Int64).Add(1)
+}
+
+func (q *queryCounter) snapshot() QueryStats {
+	stats := QueryStats{
+		Total:         q.total.Load(),
+		PrefixLengths: make(map[int]int64),
+	}
+	q.lengths.Range(func(key, value any) bool {
+		stats.PrefixLengths[key.(int)] = value.(*atomic.Int64).Load()
+		return true
+	})
+	return stats
 }
 
 // New creates a new AutocompleteService instance and performs all of the setup.
@@ -54,26 +191,68 @@ type AutocompleteService struct {
 // set the LoadDataSourcesOnStart option to false.
 //
 // You can also pass in a slice of keywords when calling this function to initialize
-// your service store with.
+// your service store with. They are inserted after the ones of opts.Keywords, see
+// WithKeywords(), skipping the words already in there. When reusing an already
+// populated store via WithStore(), use WithSkipInitialInsert() so the keywords
+// aren't inserted a second time.
 func New(opts *ServiceConfig, keywords []string) (*AutocompleteService, error) {
 	if opts == nil {
 		return nil, fmt.Errorf("autocompleteservice: new: opts cannot be nil")
 	}
-	var store autocompleter
-	if opts.LowMemoryMode {
-		store = newTernarySearchTree("")
-	} else {
-		store = newTrie()
+	backend, err := opts.backend()
+	if err != nil {
+		return nil, fmt.Errorf("autocompleteservice: new: %w", err)
+	}
+	if opts.GraphemeClusters && backend != BackendTrie {
+		return nil, fmt.Errorf("autocompleteservice: new: grapheme clusters are not supported by the %s backend", backend)
+	}
+	if opts.CaseInsensitive && backend == BackendDAWG {
+		return nil, fmt.Errorf("autocompleteservice: new: case insensitive mode is not supported by the %s backend", backend)
+	}
+	for i, src := range opts.DataSources {
+		if err := src.Validate(); err != nil {
+			return nil, fmt.Errorf("autocompleteservice: new: data source %d (%q): %w", i, src.Filepath, err)
+		}
+	}
+	// A snapshot destination without a provider is the same as none.
+	if dest := opts.SnapshotDest; dest != nil && dest.Provider != nil {
+		if err := dest.Validate(); err != nil {
+			return nil, fmt.Errorf("autocompleteservice: new: snapshot destination: %w", err)
+		}
+	}
+	store := opts.Store
+	if store == nil {
+		store = newStore(opts)
+	}
+
+	if opts.SpillStore != nil {
+		store = newSpillStore(store, opts.SpillStore, opts.SpillThreshold)
+	}
+
+	if opts.Logger == nil {
+		opts.Logger = discardLogger()
 	}
 
 	service := &AutocompleteService{
 		Config: opts,
 		store:  store,
 		Errors: make([]error, 0),
+		now:    time.Now,
+		cache:  newSuggestionCache(opts.SuggestionCacheSize),
+
+		newTicker: newTimeTicker,
+	}
+
+	service.rng = newRand(opts.Seed)
+	if opts.BreakerFailures > 0 {
+		service.breakers = make(map[int]*sourceBreaker)
 	}
 
-	for _, keyword := range keywords {
-		service.store.Insert(keyword)
+	if !opts.SkipInitialInsert {
+		service.store.InsertBatch(service.acceptable(mergeKeywords(opts.Keywords, keywords, opts.storeOptions())))
+	}
+	if opts.SubstringIndex {
+		service.substrings.build(service.store, opts.storeOptions())
 	}
 
 	if opts.LoadDataSourcesOnStart {
@@ -87,9 +266,89 @@ func New(opts *ServiceConfig, keywords []string) (*AutocompleteService, error) {
 		service.LastUpdated = time.Now().Unix()
 	}
 
+	if opts.AutomaticUpdates {
+		if err := service.startWatcher(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.SnapshotsEnabled && opts.SnapshotInterval > 0 {
+		service.snapshotMu.Lock()
+		service.startSnapshotLoop(time.Duration(opts.SnapshotInterval) * time.Second)
+		service.snapshotMu.Unlock()
+	}
+
 	return service, nil
 }
 
+// NewFromConfig creates a new AutocompleteService with the keywords of the
+// config only, see WithKeywords().
+func NewFromConfig(opts *ServiceConfig) (*AutocompleteService, error) {
+	return New(opts, nil)
+}
+
+// mergeKeywords appends the keywords to the configured ones, skipping the
+// words stored under the same key as an earlier one so an insert isn't counted
+// twice, e.g. with WithHitTracking(). Without configured keywords, keywords are
+// returned untouched.
+func mergeKeywords(configured, keywords []string, opts storeOptions) []string {
+	if len(configured) == 0 {
+		return keywords
+	}
+
+	merged := make([]string, 0, len(configured)+len(keywords))
+	seen := make(map[string]struct{}, len(configured)+len(keywords))
+	for _, words := range [][]string{configured, keywords} {
+		for _, word := range words {
+			key := opts.key(word)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			merged = append(merged, word)
+		}
+	}
+	return merged
+}
+
+// newRand returns a random number generator seeded with seed, or with the
+// current time when seed is 0.
+func newRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// newStore creates an empty store of the backend selected by the config, which
+// New() made sure is valid.
+func newStore(opts *ServiceConfig) autocompleter {
+	backend, _ := opts.backend()
+	switch backend {
+	case BackendTST:
+		tst := newTernarySearchTree("")
+		tst.storeOptions = opts.storeOptions()
+		return tst
+	case BackendRadix:
+		radix := newRadixTree()
+		radix.storeOptions = opts.storeOptions()
+		return radix
+	case BackendDAWG:
+		dawg := newDAWG()
+		dawg.storeOptions = opts.storeOptions()
+		return dawg
+	}
+
+	var t *trie
+	if opts.GraphemeClusters {
+		t = newGraphemeTrie()
+	} else {
+		t = newTrie()
+	}
+	t.storeOptions = opts.storeOptions()
+	return t
+}
+
 // Close will check for the SnapshotDest, and DataSources and close
 // the providers associated with each. This is useful for a graceful
 // shutdown to make sure all writes/reads are complete before exiting.
@@ -103,15 +362,36 @@ func New(opts *ServiceConfig, keywords []string) (*AutocompleteService, error) {
 //
 // With this approach we no longer need a complex management system for in
 // place for the Errors slice on our service.
+//
+// Close waits for the operations already in flight to complete, every operation
+// started afterwards behaves as closed.
 func (a *AutocompleteService) Close() error {
-	if a.isClosed {
+	// The snapshot loop and the file watcher have to be stopped before taking
+	// the lifecycle lock, as they may be waiting on it.
+	a.snapshotMu.Lock()
+	defer a.snapshotMu.Unlock()
+	a.stopSnapshotLoop()
+
+	a.watcherMu.Lock()
+	watchErr := a.stopWatcher()
+	a.watcherMu.Unlock()
+
+	a.lifecycle.Lock()
+	defer a.lifecycle.Unlock()
+
+	if a.closed.Load() {
 		return nil
 	}
-	// Check SnapshotDest DataSource
+	// Check SnapshotDest DataSource, which is optional.
 	var errs []error
-	snpErr := a.Config.SnapshotDest.Provider.Close()
-	if snpErr != nil {
-		errs = append(errs, snpErr)
+	if watchErr != nil {
+		errs = append(errs, watchErr)
+	}
+	if dest := a.snapshotDest(); dest != nil {
+		snpErr := dest.Provider.Close()
+		if snpErr != nil {
+			errs = append(errs, snpErr)
+		}
 	}
 
 	for i := range a.Config.DataSources {
@@ -123,96 +403,492 @@ func (a *AutocompleteService) Close() error {
 
 	if len(errs) > 0 {
 		compositeErr := fmt.Errorf("autocompleteservice: close: encountered %d errors while closing data sources: %v", len(errs), errs)
-		a.Errors = append(a.Errors, compositeErr)
+		a.recordError(compositeErr)
+		a.Config.Logger.Error("service close failed", "error", compositeErr)
 		return compositeErr
 	}
 
 	// no need to run GC our service is exiting.
-	a.Clear(false)
+	a.clear()
 
-	a.isClosed = true
+	a.closed.Store(true)
+	a.Config.Logger.Info("service closed", "service", a.Config.ServiceName)
 
 	return nil
 }
 
+// IsClosed reports whether the service was closed, and not reopened since.
+func (a *AutocompleteService) IsClosed() bool {
+	return a.closed.Load()
+}
+
+// Reopen makes a closed service usable again, started over the way New() does:
+// the keywords of the config are inserted, the data sources are loaded when
+// LoadDataSourcesOnStart is set, and the file watcher and the snapshot loop
+// are restarted when configured. The errors of the previous run are cleared.
+// Reopening a service that isn't closed does nothing.
+//
+// The providers were closed by Close(), so they have to support being used
+// again, as the LocalFileProvider does. The service is reopened even when a
+// data source fails to load, the error is returned.
+func (a *AutocompleteService) Reopen() error {
+	a.snapshotMu.Lock()
+	defer a.snapshotMu.Unlock()
+
+	a.lifecycle.Lock()
+	if !a.closed.Load() {
+		a.lifecycle.Unlock()
+		return nil
+	}
+	a.state.Lock()
+	a.Errors = make([]error, 0)
+	a.state.Unlock()
+	if !a.Config.SkipInitialInsert {
+		a.store.InsertBatch(a.acceptable(mergeKeywords(a.Config.Keywords, nil, a.Config.storeOptions())))
+	}
+	a.closed.Store(false)
+	a.lifecycle.Unlock()
+
+	var errs []error
+	if a.Config.LoadDataSourcesOnStart {
+		if err := a.LoadDataSources(); err != nil {
+			errs = append(errs, err)
+		}
+	} else {
+		a.markUpdated()
+	}
+
+	if a.Config.AutomaticUpdates {
+		a.watcherMu.Lock()
+		if err := a.startWatcher(); err != nil {
+			errs = append(errs, err)
+		}
+		a.watcherMu.Unlock()
+	}
+
+	if d := a.snapshotPeriod(); a.Config.SnapshotsEnabled && d > 0 {
+		a.startSnapshotLoop(d)
+	}
+
+	a.Config.Logger.Info("service reopened", "service", a.Config.ServiceName)
+	return errors.Join(errs...)
+}
+
+// acquire marks the start of an operation that requires the service to be open,
+// and reports whether it is. When it returns true, the caller must call release()
+// once done.
+//
+// NOTE: Guarded operations must not call each other, acquiring the read lock twice
+// from the same goroutine deadlocks if Close is waiting in between.
+func (a *AutocompleteService) acquire() bool {
+	a.lifecycle.RLock()
+	if a.closed.Load() {
+		a.lifecycle.RUnlock()
+		return false
+	}
+	return true
+}
+
+// release marks the end of an operation started with acquire().
+func (a *AutocompleteService) release() {
+	a.lifecycle.RUnlock()
+}
+
+// recordError adds err to Errors.
+func (a *AutocompleteService) recordError(err error) {
+	a.state.Lock()
+	defer a.state.Unlock()
+	a.Errors = append(a.Errors, err)
+}
+
+// markUpdated sets LastUpdated to now.
+func (a *AutocompleteService) markUpdated() {
+	a.state.Lock()
+	defer a.state.Unlock()
+	a.LastUpdated = time.Now().Unix()
+}
+
+// GetErrors returns a copy of the errors the service ran into, which is safe
+// to call while other goroutines use the service.
+func (a *AutocompleteService) GetErrors() []error {
+	a.state.RLock()
+	defer a.state.RUnlock()
+	return append([]error(nil), a.Errors...)
+}
+
+// GetLastUpdated returns LastUpdated, which is safe to call while other
+// goroutines use the service.
+func (a *AutocompleteService) GetLastUpdated() int64 {
+	a.state.RLock()
+	defer a.state.RUnlock()
+	return a.LastUpdated
+}
+
+// LoadDataSources reads every configured data source into the store. A failing
+// source doesn't stop the others from loading, every error is added to Errors
+// and they are returned together once all the sources have been attempted.
+//
+// When a circuit breaker is configured with WithSourceCircuitBreaker(), a
+// source that keeps failing is skipped until its cooldown has passed.
 func (a *AutocompleteService) LoadDataSources() error {
-	if a.isClosed {
-		return fmt.Errorf("autocompleteservice: loaddatasources: service is closed.")
+	return a.LoadDataSourcesContext(context.Background())
+}
+
+// LoadDataSourcesContext behaves like LoadDataSources, but stops as soon as ctx
+// is done and returns the context error, which is also added to Errors. The
+// sources loaded before are kept. Providers implementing ContextDataProvider are
+// interrupted mid-load, others finish the source they are loading first.
+func (a *AutocompleteService) LoadDataSourcesContext(ctx context.Context) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: loaddatasources: %w", ErrServiceClosed)
 	}
+	defer a.release()
 
-	for _, source := range a.Config.DataSources {
-		err := source.Provider.ReadData(source.Filepath, a.store, source.Formatter)
-		if err != nil {
-			a.Errors = append(a.Errors, err)
+	var errs []error
+	loaded := 0
+	for i, source := range a.Config.DataSources {
+		if err := ctx.Err(); err != nil {
+			a.recordError(err)
+			a.Config.Logger.Warn("data source load cancelled", "error", err)
 			return err
 		}
+
+		ok, err := a.loadSource(ctx, i, source)
+		if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			loaded++
+		}
+	}
+	if loaded > 0 || len(errs) == 0 {
+		a.markUpdated()
 	}
-	a.LastUpdated = time.Now().Unix()
 
+	if len(errs) > 0 {
+		return fmt.Errorf("autocompleteservice: loaddatasources: %d of %d data sources failed: %w", len(errs), len(a.Config.DataSources), errors.Join(errs...))
+	}
 	return nil
 }
 
+// loadSource loads the data source at index i of Config.DataSources through its
+// circuit breaker, keeping track of the error. It reports whether the source
+// was loaded, a source skipped by its breaker is neither loaded nor failed.
+// When ctx is done the error is ctx.Err(), which isn't the fault of the source
+// and is kept out of the breaker.
+func (a *AutocompleteService) loadSource(ctx context.Context, i int, source DataSource) (bool, error) {
+	breaker := a.breaker(i)
+	if !breaker.allow(a.now()) {
+		// The source keeps failing, skip it until the cooldown passes.
+		return false, nil
+	}
+
+	err := readData(ctx, source, a.loadStore())
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		a.recordError(ctxErr)
+		a.Config.Logger.Warn("data source load cancelled", "source", i, "filepath", source.Filepath, "error", ctxErr)
+		return false, ctxErr
+	}
+	if err != nil {
+		if failures, tripped := breaker.failure(a.now(), a.Config.BreakerFailures, a.Config.BreakerCooldown); tripped {
+			a.Config.Logger.Warn("data source circuit breaker tripped",
+				"source", i, "filepath", source.Filepath, "failures", failures,
+				"cooldown", a.Config.BreakerCooldown, "error", err)
+		}
+		a.recordError(err)
+		a.logLoad(source.Filepath, err)
+		return false, err
+	}
+	a.logLoad(source.Filepath, nil)
+
+	if breaker.success() {
+		a.Config.Logger.Info("data source circuit breaker reset", "source", i, "filepath", source.Filepath)
+	}
+	return true, nil
+}
+
+// reloadSource loads the data source at index i of Config.DataSources again,
+// through its circuit breaker, see loadSource().
+func (a *AutocompleteService) reloadSource(i int) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: reloadsource: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	ok, err := a.loadSource(context.Background(), i, a.Config.DataSources[i])
+	if ok {
+		a.markUpdated()
+	}
+	return err
+}
+
 func (a *AutocompleteService) AddSnapshotDest(dest DataSource) {
 	a.Config.SnapshotDest = &dest
 }
 
+// snapshotDest returns SnapshotDest, nil when snapshots aren't configured:
+// a destination without a provider is the same as none.
+func (a *AutocompleteService) snapshotDest() *DataSource {
+	if dest := a.Config.SnapshotDest; dest != nil && dest.Provider != nil {
+		return dest
+	}
+	return nil
+}
+
+// CreateSnapshot dumps the store to SnapshotDest. With WithSnapshotRetention(),
+// a local file destination gets a new timestamped file every time instead of
+// being overwritten.
 func (a *AutocompleteService) CreateSnapshot() error {
-	if a.isClosed {
-		return fmt.Errorf("autocompleteservice: loaddatasources: service is closed.")
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: createsnapshot: %w", ErrServiceClosed)
 	}
+	defer a.release()
 
-	if a.Config.SnapshotDest == nil {
-		return fmt.Errorf("autocompleteservice: createsnapshot: no snapshot destination set")
+	dest := a.snapshotDest()
+	if dest == nil {
+		return fmt.Errorf("autocompleteservice: createsnapshot: %w", ErrNoSnapshotDest)
 	}
 
-	err := a.Config.SnapshotDest.Provider.DumpData(a.Config.SnapshotDest.Filepath, a.store, a.Config.SnapshotDest.Formatter)
+	filepath, err := a.dumpSnapshot(dest)
 	if err != nil {
-		a.Errors = append(a.Errors, err)
+		a.recordError(err)
 	}
+	a.logSnapshot("create", filepath, err)
 	return err
 }
 
+// CreateSnapshots dumps the store to every destination, each with its own
+// formatter, e.g. to keep both a JSON and a CSV snapshot. A failing destination
+// doesn't stop the others, the errors of all the failed ones are joined.
+func (a *AutocompleteService) CreateSnapshots(dests []DataSource) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: createsnapshots: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	var errs []error
+	for _, dest := range dests {
+		err := dest.Provider.DumpData(dest.Filepath, a.store, dest.formatter())
+		if err != nil {
+			err = fmt.Errorf("autocompleteservice: createsnapshots: %s: %w", dest.Filepath, err)
+			a.recordError(err)
+			errs = append(errs, err)
+		}
+		a.logSnapshot("create", dest.Filepath, err)
+	}
+	return errors.Join(errs...)
+}
+
+// RestoreFromSnapshot loads the snapshot of SnapshotDest into the store. For a
+// local file destination, the path can be a directory or a glob pattern, the
+// most recently modified file is restored. With WithSnapshotRetention(), the
+// newest timestamped snapshot is restored.
 func (a *AutocompleteService) RestoreFromSnapshot() error {
-	if a.isClosed {
-		return fmt.Errorf("autocompleteservice: loaddatasources: service is closed.")
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: restorefromsnapshot: %w", ErrServiceClosed)
 	}
+	defer a.release()
 
-	if a.Config.SnapshotDest == nil {
-		return fmt.Errorf("autocompleteservice: createsnapshot: no snapshot destination set")
+	dest := a.snapshotDest()
+	if dest == nil {
+		return fmt.Errorf("autocompleteservice: restorefromsnapshot: %w", ErrNoSnapshotDest)
 	}
 
-	err := a.Config.SnapshotDest.Provider.ReadData(a.Config.SnapshotDest.Filepath, a.store, a.Config.SnapshotDest.Formatter)
+	provider, filepath, err := a.snapshotSource(dest)
+	if err == nil {
+		err = provider.ReadData(filepath, a.loadStore(), dest.formatter())
+	}
+	a.logSnapshot("restore", filepath, err)
 	if err != nil {
-		a.Errors = append(a.Errors, err)
+		a.recordError(err)
 		return err
 	}
-	a.LastUpdated = time.Now().Unix()
+	a.markUpdated()
 	return err
 }
 
 func (a *AutocompleteService) LoadDataSource(src DataSource) error {
-	if a.isClosed {
-		return fmt.Errorf("autocompleteservice: loaddatasources: service is closed.")
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: loaddatasource: %w", ErrServiceClosed)
 	}
-	err := src.Provider.ReadData(src.Filepath, a.store, src.Formatter)
+	defer a.release()
+	err := src.Provider.ReadData(src.Filepath, a.loadStore(), src.formatter())
+	a.logLoad(src.Filepath, err)
 	if err != nil {
-		a.Errors = append(a.Errors, err)
+		a.recordError(err)
 		return err
 	}
-	a.LastUpdated = time.Now().Unix()
+	a.markUpdated()
 	return nil
 }
 
+// logLoad logs the outcome of loading the data source at filepath.
+func (a *AutocompleteService) logLoad(filepath string, err error) {
+	if err != nil {
+		a.Config.Logger.Error("data source load failed", "filepath", filepath, "error", err)
+		return
+	}
+	a.Config.Logger.Info("data source loaded", "filepath", filepath)
+}
+
+// logSnapshot logs the outcome of a snapshot operation, create or restore.
+func (a *AutocompleteService) logSnapshot(op, filepath string, err error) {
+	if err != nil {
+		a.Config.Logger.Error("snapshot "+op+" failed", "filepath", filepath, "error", err)
+		return
+	}
+	a.Config.Logger.Info("snapshot "+op+"d", "filepath", filepath)
+}
+
+// LoadDataSourceWithProgress behaves like LoadDataSource, but invokes progress
+// with the running number of inserted keywords every Config.ProgressInterval
+// inserts, and once more when the load completes. This is useful to give
+// feedback while loading very large sources.
+//
+// The callback is invoked on the loading goroutine, so keep it cheap.
+func (a *AutocompleteService) LoadDataSourceWithProgress(src DataSource, progress func(loaded int)) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: loaddatasourcewithprogress: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	store := &progressStore{
+		PublicProviderStore: a.loadStore(),
+		interval:            a.Config.ProgressInterval,
+		progress:            progress,
+	}
+
+	err := src.Provider.ReadData(src.Filepath, store, src.formatter())
+	a.logLoad(src.Filepath, err)
+	if err != nil {
+		a.recordError(err)
+		return err
+	}
+	store.finish()
+
+	a.markUpdated()
+	return nil
+}
+
+// loadStore returns the store handed to the data providers when loading.
+func (a *AutocompleteService) loadStore() PublicProviderStore {
+	var store PublicProviderStore = a.store
+	if a.Config.LoadDedupe {
+		store = &dedupeStore{store: a.store, deduped: &a.deduped}
+	}
+	store = &indexStore{PublicProviderStore: store, folds: &a.folds, substrings: &a.substrings, cache: a.cache}
+	// Outermost, so the rejected words never reach the indexes either.
+	if a.Config.MaxWordLength > 0 {
+		store = &limitStore{PublicProviderStore: store, service: a}
+	}
+	return store
+}
+
+// dedupeStore skips the words already in the store, see WithLoadDedupe().
+type dedupeStore struct {
+	store   autocompleter
+	deduped *atomic.Int64
+}
+
+func (d *dedupeStore) Insert(word string) {
+	if d.store.Contains(word) {
+		d.deduped.Add(1)
+		return
+	}
+	d.store.Insert(word)
+}
+
+func (d *dedupeStore) ListContents() []string {
+	return d.store.ListContents()
+}
+
+// DedupedOnLoad returns the number of words skipped while loading because they
+// were already stored. Only counted when WithLoadDedupe() is set.
+func (a *AutocompleteService) DedupedOnLoad() int64 {
+	return a.deduped.Load()
+}
+
+// progressStore wraps the store handed to a DataProvider so we can count
+// inserts as they stream in.
+type progressStore struct {
+	PublicProviderStore
+
+	interval int
+	loaded   int
+	reported int
+	progress func(loaded int)
+}
+
+func (p *progressStore) Insert(word string) {
+	p.PublicProviderStore.Insert(word)
+	p.loaded++
+
+	if p.progress != nil && p.interval > 0 && p.loaded%p.interval == 0 {
+		p.report()
+	}
+}
+
+func (p *progressStore) report() {
+	p.reported = p.loaded
+	p.progress(p.loaded)
+}
+
+// finish reports the final count, unless it was just reported.
+func (p *progressStore) finish() {
+	if p.progress != nil && p.reported != p.loaded {
+		p.report()
+	}
+}
+
 func (a *AutocompleteService) ExportToDataSource(dest DataSource) error {
-	err := dest.Provider.DumpData(dest.Filepath, a.store, dest.Formatter)
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: exporttodatasource: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	err := dest.Provider.DumpData(dest.Filepath, a.store, dest.formatter())
 	if err != nil {
-		a.Errors = append(a.Errors, err)
+		a.recordError(err)
 		return err
 	}
 	return nil
 }
 
-// Clear will remove all data from the store, in the event you want to start fresh.
+// ExportPrefixToDataSource behaves like ExportToDataSource, but only exports
+// the words starting with prefix. This is useful for partitioned backups.
+func (a *AutocompleteService) ExportPrefixToDataSource(prefix string, dest DataSource) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: exportprefixtodatasource: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	words := wordList(a.store.Autocomplete(prefix))
+	err := dest.Provider.DumpData(dest.Filepath, &words, dest.formatter())
+	if err != nil {
+		a.recordError(err)
+		return err
+	}
+	return nil
+}
+
+// wordList is a PublicProviderStore backed by a slice, used to hand a subset
+// of the store to a DataProvider.
+type wordList []string
+
+func (w *wordList) Insert(word string) {
+	*w = append(*w, word)
+}
+
+func (w *wordList) ListContents() []string {
+	return *w
+}
+
+// Clear will remove all data from the store and the namespaces, in the event you
+// want to start fresh.
 // There are two ways we can approach this, the safe way and just set an empty node
 // to the root, and just wait for the GC take care of the old one.
 //
@@ -225,59 +901,806 @@ func (a *AutocompleteService) ExportToDataSource(dest DataSource) error {
 //	Block the caller until the garbage collection is complete.
 //	It may also block the entire program.
 //	Per the runtime.DC() godocs.
+//
+// Clear waits for the operations in flight to complete, so it never runs
+// while the store is being swapped by Reload().
 func (a *AutocompleteService) Clear(runGC bool) {
-	a.LastUpdated = time.Now().Unix()
-
-	a.store.Clear()
-	// TODO: Check to see if just setting the store to nil or creating a new empty store
-	// is enough to remove all references to the old data and trigger the GC.
+	a.lifecycle.Lock()
+	a.clear()
+	a.lifecycle.Unlock()
 
 	if runGC {
 		runtime.GC()
 	}
 }
 
+// clear empties the store and the namespaces and drops the indexes, the
+// caller must hold the lifecycle write lock.
+func (a *AutocompleteService) clear() {
+	a.markUpdated()
+
+	a.store.Clear()
+	a.nsMu.Lock()
+	a.namespaces = nil
+	a.nsMu.Unlock()
+	a.cache.invalidate()
+	a.folds.reset()
+	a.substrings.reset()
+	a.trends.reset()
+	// TODO: Check to see if just setting the store to nil or creating a new empty store
+	// is enough to remove all references to the old data and trigger the GC.
+}
+
 // I am providing different names to these functions to avoid
 // implementing the internal interface autocompleter on itself.
 // This also provides quick access instead of having to go through
 // the store. And gives us room to add more functionality later.
+//
+// Complete returns the heaviest completions first, see AddWeighted() and
+// WithHitTracking(), with ties broken lexically. Without weights the results
+// are always sorted lexically, so repeated calls return the same order. The
+// prefixes shorter than WithMinPrefixLength() get no completions.
 func (a *AutocompleteService) Complete(prefix string) []string {
-	if a.isClosed {
+	if !a.acquire() {
+		return []string{}
+	}
+	defer a.release()
+	return a.completeQuery(prefix)
+}
+
+// CompleteE behaves like Complete, but returns ErrServiceClosed once the
+// service is closed instead of no completions.
+func (a *AutocompleteService) CompleteE(prefix string) ([]string, error) {
+	if !a.acquire() {
+		return nil, fmt.Errorf("autocompleteservice: complete: %w", ErrServiceClosed)
+	}
+	defer a.release()
+	return a.completeQuery(prefix), nil
+}
+
+// completeQuery serves a Complete() query, see completeQueryContext().
+func (a *AutocompleteService) completeQuery(prefix string) []string {
+	// Background is never done, so there is no error to report.
+	results, _ := a.completeQueryContext(context.Background(), prefix)
+	return results
+}
+
+// completeQueryContext serves a Complete() query: recorded, and logged when
+// slow. The prefixes shorter than MinPrefixLength get no completions.
+func (a *AutocompleteService) completeQueryContext(ctx context.Context, prefix string) ([]string, error) {
+	a.queries.record(prefix)
+	if min := a.Config.MinPrefixLength; min > 0 && utf8.RuneCountInString(prefix) < min {
+		return []string{}, nil
+	}
+
+	threshold := a.Config.SlowQueryThreshold
+	if threshold <= 0 {
+		return a.complete(ctx, prefix)
+	}
+
+	start := time.Now()
+	results, err := a.complete(ctx, prefix)
+	if elapsed := time.Since(start); err == nil && elapsed > threshold {
+		a.Config.Logger.Warn("slow completion query",
+			"prefix_length", utf8.RuneCountInString(prefix), "results", len(results), "duration", elapsed)
+	}
+	return results, err
+}
+
+// complete returns the completions for prefix, heaviest first with ties
+// broken lexically. Served from the suggestion cache when enabled. Only fails
+// when ctx is done before the completions are collected.
+func (a *AutocompleteService) complete(ctx context.Context, prefix string) ([]string, error) {
+	if results, ok := a.cache.get(prefix); ok {
+		return results, nil
+	}
+
+	gen := a.cache.generation()
+	entries, err := storeEntriesContext(ctx, a.store, prefix)
+	if err != nil {
+		return nil, err
+	}
+	results := a.completions(entries)
+	a.cache.put(prefix, gen, results)
+	return results, nil
+}
+
+// completions ranks entries into the completions returned by Complete().
+func (a *AutocompleteService) completions(entries []entry) []string {
+	rankEntries(entries, false)
+
+	results := entryWords(entries)
+	if a.Config.Stemming {
+		results = mergeStems(results)
+	}
+	return results
+}
+
+// CompleteJSON returns the completions for prefix already marshaled as a
+// JSON array, in the same order as Complete and limited to MaxResults when
+// set. Handy when the results are only proxied, e.g. as an HTTP response.
+func (a *AutocompleteService) CompleteJSON(prefix string) ([]byte, error) {
+	results := a.Complete(prefix)
+	if max := a.Config.MaxResults; max > 0 && len(results) > max {
+		results = results[:max]
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("autocompleteservice: completejson: %w", err)
+	}
+	return data, nil
+}
+
+// CompletePage returns the window of limit completions for prefix starting at
+// offset, in the same order as Complete, along with the total number of
+// completions. Since Complete always returns the same order for the same
+// contents, consecutive pages neither skip nor repeat completions as long as
+// no word is added or removed in between. Use WithSuggestionCache() to avoid
+// collecting the completions again for every page.
+//
+// An offset past the end returns no completions with the real total, and a
+// limit of 0 only returns the total.
+func (a *AutocompleteService) CompletePage(prefix string, offset, limit int) (results []string, total int) {
+	if !a.acquire() {
+		return []string{}, 0
+	}
+	defer a.release()
+
+	completions := a.completeQuery(prefix)
+	total = len(completions)
+
+	offset = max(offset, 0)
+	if limit <= 0 || offset >= total {
+		return []string{}, total
+	}
+	return completions[offset:min(offset+limit, total)], total
+}
+
+// TreeNode is a node of the prefix tree returned by CompleteTreeJSON. Char is
+// a single character, except for the root which holds the whole prefix.
+type TreeNode struct {
+	Char     string      `json:"char"`
+	IsEnd    bool        `json:"isEnd"`
+	Children []*TreeNode `json:"children"`
+}
+
+// CompleteTreeJSON returns the completions for prefix as a JSON prefix tree
+// rooted at prefix instead of a flat list, for clients rendering them
+// hierarchically. Children are ordered lexically and IsEnd marks the nodes
+// ending a word, e.g. "bi" with "bike" and "bikes" stored gives
+//
+//	{"char":"bi","isEnd":false,"children":[{"char":"k","isEnd":false,"children":[
+//	  {"char":"e","isEnd":true,"children":[{"char":"s","isEnd":true,"children":[]}]}]}]}
+func (a *AutocompleteService) CompleteTreeJSON(prefix string) ([]byte, error) {
+	if !a.acquire() {
+		return nil, fmt.Errorf("autocompleteservice: completetreejson: %w", ErrServiceClosed)
+	}
+	defer a.release()
+	a.queries.record(prefix)
+
+	words := a.store.Autocomplete(prefix)
+	sort.Strings(words)
+
+	// Words are stored normalized, so only the length of the normalized
+	// prefix can be cut from them.
+	cut := utf8.RuneCountInString(a.Config.storeOptions().normalize(prefix))
+	root := &TreeNode{Char: prefix, Children: []*TreeNode{}}
+	for _, word := range words {
+		curr := root
+		for _, r := range []rune(word)[cut:] {
+			// Sorted words share their leading characters, so a child is
+			// always either the last one added or a new one.
+			char := string(r)
+			if n := len(curr.Children); n > 0 && curr.Children[n-1].Char == char {
+				curr = curr.Children[n-1]
+				continue
+			}
+			child := &TreeNode{Char: char, Children: []*TreeNode{}}
+			curr.Children = append(curr.Children, child)
+			curr = child
+		}
+		curr.IsEnd = true
+	}
+
+	data, err := json.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("autocompleteservice: completetreejson: %w", err)
+	}
+	return data, nil
+}
+
+// CompleteExcluding behaves like Complete, but omits any of the words in
+// exclude from the results. This is useful when the user has already picked
+// some of the suggestions (e.g. tags) and they shouldn't be offered again.
+func (a *AutocompleteService) CompleteExcluding(prefix string, exclude []string) []string {
+	results := a.Complete(prefix)
+	if len(exclude) == 0 || len(results) == 0 {
+		return results
+	}
+
+	excluded := make(map[string]struct{}, len(exclude))
+	for _, word := range exclude {
+		excluded[word] = struct{}{}
+	}
+
+	filtered := results[:0]
+	for _, word := range results {
+		if _, ok := excluded[word]; !ok {
+			filtered = append(filtered, word)
+		}
+	}
+	return filtered
+}
+
+// CompleteDiverse behaves like Complete, but keeps at most perBranch
+// completions for every character following the prefix, so a handful of
+// "be..." words can't crowd out the "bi..." ones. Results are sorted lexically
+// and the prefix itself, when it is a word, is a branch of its own. Leave
+// perBranch 0 for no limit.
+func (a *AutocompleteService) CompleteDiverse(prefix string, perBranch int) []string {
+	results := a.Complete(prefix)
+	sort.Strings(results)
+	if perBranch <= 0 {
+		return results
+	}
+
+	taken := make(map[rune]int)
+	filtered := results[:0]
+	for _, word := range results {
+		// -1 is the prefix itself, no rune follows it.
+		branch := rune(-1)
+		if rest := word[a.prefixEnd(word, prefix):]; rest != "" {
+			branch, _ = utf8.DecodeRuneInString(rest)
+		}
+		if taken[branch] == perBranch {
+			continue
+		}
+		taken[branch]++
+		filtered = append(filtered, word)
+	}
+	return filtered
+}
+
+// prefixEnd returns the byte offset of the end of prefix in word, one of its
+// completions. The words are stored normalized, so with whitespace compaction
+// the prefix may be longer than the part of the word it matched. In case
+// insensitive mode, the word keeps the casing it was inserted with, and some
+// runes change length when lower cased (e.g. the Kelvin sign folds to "k"),
+// so the prefix is matched against the lower cased word rune by rune.
+func (a *AutocompleteService) prefixEnd(word, prefix string) int {
+	opts := a.Config.storeOptions()
+	key := opts.key(prefix)
+	if !opts.foldCase {
+		return min(len(key), len(word))
+	}
+
+	// key is lower cased rune by rune too, see strings.ToLower.
+	n := 0
+	for i, r := range word {
+		if n >= len(key) {
+			return i
+		}
+		n += utf8.RuneLen(unicode.ToLower(r))
+	}
+	return len(word)
+}
+
+// OffsetSuggestion is a completion along with where the prefix ends within it.
+// Offsets are given both in bytes and runes so clients don't have to compute
+// them on multibyte prefixes.
+type OffsetSuggestion struct {
+	Word        string
+	PrefixRunes int
+	PrefixBytes int
+}
+
+// CompleteOffsets behaves like Complete, but returns each completion with the
+// rune and byte offsets of the end of the prefix.
+func (a *AutocompleteService) CompleteOffsets(prefix string) []OffsetSuggestion {
+	words := a.Complete(prefix)
+
+	results := make([]OffsetSuggestion, len(words))
+	for i, word := range words {
+		// The prefix may match a differently spaced or cased part of word.
+		end := a.prefixEnd(word, prefix)
+		results[i] = OffsetSuggestion{Word: word, PrefixRunes: utf8.RuneCountInString(word[:end]), PrefixBytes: end}
+	}
+	return results
+}
+
+// CompleteSortedBy returns the completions for prefix ordered by less.
+//
+// NOTE: Every completion has to be collected before it can be sorted, so
+// this materializes the full result set regardless of MaxResults.
+func (a *AutocompleteService) CompleteSortedBy(prefix string, less func(a, b string) bool) []string {
+	results := a.Complete(prefix)
+	sort.SliceStable(results, func(i, j int) bool {
+		return less(results[i], results[j])
+	})
+	return results
+}
+
+// CompleteRandom returns up to n completions for prefix picked at random.
+// Set WithSeed() to make the picks reproducible.
+func (a *AutocompleteService) CompleteRandom(prefix string, n int) []string {
+	results := a.Complete(prefix)
+	if n <= 0 {
 		return []string{}
 	}
-	return a.store.Autocomplete(prefix)
+
+	// The stores don't guarantee an order, sort so the same seed always
+	// makes the same picks.
+	sort.Strings(results)
+
+	a.rngMu.Lock()
+	defer a.rngMu.Unlock()
+
+	// Partial Fisher-Yates, we only need to shuffle the first n.
+	if n > len(results) {
+		n = len(results)
+	}
+	for i := 0; i < n; i++ {
+		j := i + a.rng.Intn(len(results)-i)
+		results[i], results[j] = results[j], results[i]
+	}
+	return results[:n]
+}
+
+// EstimateResults returns the number of completions Complete would return for
+// prefix, without collecting them. With WithStemming() the words still have to
+// be collected, to count the stems they merge into.
+func (a *AutocompleteService) EstimateResults(prefix string) int {
+	if !a.acquire() {
+		return 0
+	}
+	defer a.release()
+
+	if min := a.Config.MinPrefixLength; min > 0 && utf8.RuneCountInString(prefix) < min {
+		return 0
+	}
+	if !a.Config.Stemming {
+		return a.store.PrefixCount(prefix)
+	}
+
+	stems := make(map[string]struct{})
+	for _, word := range a.store.Autocomplete(prefix) {
+		stems[stem(word)] = struct{}{}
+	}
+	return len(stems)
+}
+
+// Count returns the number of words stored.
+func (a *AutocompleteService) Count() int {
+	if !a.acquire() {
+		return 0
+	}
+	defer a.release()
+	return a.store.Count()
+}
+
+// ServiceStats is a point in time view of the service internals, see
+// AutocompleteService.Stats().
+type ServiceStats struct {
+	WordCount int
+	// NodeCount is the number of nodes of the store, see NodeCount().
+	NodeCount   int
+	LastUpdated int64
+	// Backend is "trie", "tst", "radix", "dawg", "spill" for stores spilling
+	// to a second store, or "custom" for a store provided with WithStore().
+	Backend    string
+	ErrorCount int
+	// RejectedWords is the number of words skipped for being longer than
+	// MaxWordLength, see WithMaxWordLength().
+	RejectedWords int64
+}
+
+// Stats returns the service metrics, e.g. to expose them on a monitoring
+// endpoint. Counting the nodes walks the whole store.
+func (a *AutocompleteService) Stats() ServiceStats {
+	if !a.acquire() {
+		return ServiceStats{}
+	}
+	defer a.release()
+
+	return ServiceStats{
+		WordCount:   a.store.Count(),
+		NodeCount:   a.store.NodeCount(),
+		LastUpdated: a.GetLastUpdated(),
+		Backend:     backendName(a.store),
+		ErrorCount:  len(a.GetErrors()),
+
+		RejectedWords: a.rejected.Load(),
+	}
+}
+
+func backendName(store autocompleter) string {
+	switch store.(type) {
+	case *trie:
+		return BackendTrie.String()
+	case *ternarysearchtree:
+		return BackendTST.String()
+	case *radixTree:
+		return BackendRadix.String()
+	case *DAWG:
+		return BackendDAWG.String()
+	case *spillStore:
+		return "spill"
+	default:
+		return "custom"
+	}
+}
+
+// NodeCount returns the number of nodes of the store, which is what its memory
+// use grows with. It walks the whole store.
+func (a *AutocompleteService) NodeCount() int {
+	if !a.acquire() {
+		return 0
+	}
+	defer a.release()
+	return a.store.NodeCount()
+}
+
+// PrefixCount returns the number of stored words starting with prefix.
+func (a *AutocompleteService) PrefixCount(prefix string) int {
+	if !a.acquire() {
+		return 0
+	}
+	defer a.release()
+	return a.store.PrefixCount(prefix)
+}
+
+// CompletionCountHistogram maps a number of completions to how many of the
+// prefixes have that many completions, to help tune MaxResults. The counts
+// come from EstimateResults() so the prefixes aren't recorded in QueryStats().
+func (a *AutocompleteService) CompletionCountHistogram(prefixes []string) map[int]int {
+	histogram := make(map[int]int)
+	for _, prefix := range prefixes {
+		histogram[a.EstimateResults(prefix)]++
+	}
+	return histogram
+}
+
+// PrefixInfo describes a node on the path between a prefix and its completions.
+type PrefixInfo struct {
+	Prefix string
+	// IsWord is set when Prefix is itself a stored word.
+	IsWord bool
+	// Completions is the number of stored words starting with Prefix.
+	Completions int
+}
+
+// CompletePrefixes enumerates every intermediate prefix between prefix (included)
+// and its completions, sorted lexically. For example with "bike" and "bike path"
+// stored, "bike pa" is reported as a prefix that isn't a word, with 1 completion.
+//
+// This is meant for UIs that want to know whether what has been typed so far is a
+// whole word or just a path to one. The number of results grows with the total
+// length of the completions, so keep the prefix reasonably specific.
+func (a *AutocompleteService) CompletePrefixes(prefix string) []PrefixInfo {
+	words := a.Complete(prefix)
+	if len(words) == 0 {
+		return []PrefixInfo{}
+	}
+
+	infos := make(map[string]*PrefixInfo)
+	for _, word := range words {
+		end := a.prefixEnd(word, prefix)
+		for i := range word[end:] {
+			// i is the byte offset of every rune after the prefix, so word[:i]
+			// walks every prefix of the word without splitting runes.
+			p := word[:end+i]
+			if p == "" {
+				continue
+			}
+			info, ok := infos[p]
+			if !ok {
+				info = &PrefixInfo{Prefix: p}
+				infos[p] = info
+			}
+			info.Completions++
+		}
+
+		info, ok := infos[word]
+		if !ok {
+			info = &PrefixInfo{Prefix: word}
+			infos[word] = info
+		}
+		info.IsWord = true
+		info.Completions++
+	}
+
+	results := make([]PrefixInfo, 0, len(infos))
+	for _, info := range infos {
+		results = append(results, *info)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Prefix < results[j].Prefix
+	})
+	return results
+}
+
+// CompleteMatchingClass behaves like Complete, but drops every completion
+// containing a rune allow rejects. E.g. pass unicode.IsDigit to only complete
+// phone numbers or numeric codes.
+func (a *AutocompleteService) CompleteMatchingClass(prefix string, allow func(rune) bool) []string {
+	for _, r := range prefix {
+		if !allow(r) {
+			return []string{}
+		}
+	}
+
+	results := a.Complete(prefix)
+	filtered := results[:0]
+
+	for _, word := range results {
+		matches := true
+		// The prefix was already checked.
+		for _, r := range word[a.prefixEnd(word, prefix):] {
+			if !allow(r) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, word)
+		}
+	}
+	return filtered
+}
+
+// QueryStats returns the number of completion queries served by Complete
+// along with a histogram of the prefix lengths they were made with.
+//
+// NOTE: The counters are updated independently, so a snapshot taken while
+// queries are in flight may be off by the queries currently running.
+func (a *AutocompleteService) QueryStats() QueryStats {
+	return a.queries.snapshot()
 }
 
 func (a *AutocompleteService) Exists(word string) bool {
-	if a.isClosed {
+	if !a.acquire() {
 		return false
 	}
+	defer a.release()
 	return a.store.Contains(word)
 }
 
+// ExistsE behaves like Exists, but returns ErrServiceClosed once the service
+// is closed.
+func (a *AutocompleteService) ExistsE(word string) (bool, error) {
+	if !a.acquire() {
+		return false, fmt.Errorf("autocompleteservice: exists: %w", ErrServiceClosed)
+	}
+	defer a.release()
+	return a.store.Contains(word), nil
+}
+
+// Add inserts the word into the store. Adding an empty word is a no-op.
 func (a *AutocompleteService) Add(word string) {
-	if a.isClosed {
+	if word == "" {
+		return
+	}
+	if !a.acquire() {
 		return
 	}
+	defer a.release()
+	a.add(word)
+}
+
+// AddE behaves like Add, but returns ErrServiceClosed once the service is
+// closed instead of dropping the word, and ErrWordTooLong for a word longer
+// than MaxWordLength.
+func (a *AutocompleteService) AddE(word string) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: add: %w", ErrServiceClosed)
+	}
+	defer a.release()
+	if word != "" && !a.add(word) {
+		return fmt.Errorf("autocompleteservice: add: %w", ErrWordTooLong)
+	}
+	return nil
+}
+
+// AddBatch inserts every word into the store, locking it once for the whole
+// batch, which is much faster than calling Add for every word. Empty words and
+// the ones longer than MaxWordLength are skipped.
+func (a *AutocompleteService) AddBatch(words []string) {
+	if !a.acquire() {
+		return
+	}
+	defer a.release()
+
+	words = a.acceptable(words)
+	a.store.InsertBatch(words)
+	a.cache.invalidate()
+	for _, word := range words {
+		if word != "" {
+			a.folds.insert(word)
+			a.substrings.insert(word)
+			a.touch(word)
+		}
+	}
+}
+
+// reportingStore is implemented by the stores that can tell whether an insert
+// added a new word, see AddCounted().
+type reportingStore interface {
+	InsertReport(word string) bool
+}
+
+// AddCounted inserts every word into the store like AddBatch, and returns the
+// number of words newly added and the number skipped, either because they
+// were already stored, or repeated within words, or empty, or longer than
+// MaxWordLength. Handy to report on an import.
+func (a *AutocompleteService) AddCounted(words []string) (added, skipped int) {
+	if !a.acquire() {
+		return 0, 0
+	}
+	defer a.release()
+
+	rs, ok := a.store.(reportingStore)
+	for _, word := range words {
+		if word == "" || !a.accept(word) {
+			skipped++
+			continue
+		}
+
+		var isNew bool
+		if ok {
+			isNew = rs.InsertReport(word)
+		} else {
+			// Custom stores are checked first, the insert isn't atomic.
+			isNew = !a.store.Contains(word)
+			a.store.Insert(word)
+		}
+		if isNew {
+			added++
+		} else {
+			skipped++
+		}
+		a.folds.insert(word)
+		a.substrings.insert(word)
+		a.touch(word)
+	}
+	a.cache.invalidate()
+	return added, skipped
+}
+
+// add inserts a non empty word, keeping the indexes up to date. It reports
+// whether the word was accepted, see accept().
+func (a *AutocompleteService) add(word string) bool {
+	if !a.accept(word) {
+		return false
+	}
 	a.store.Insert(word)
+	a.cache.invalidate()
+	a.folds.insert(word)
+	a.substrings.insert(word)
+	a.touch(word)
+	return true
+}
+
+// accept reports whether word is within MaxWordLength, counting the rejected
+// words.
+func (a *AutocompleteService) accept(word string) bool {
+	if max := a.Config.MaxWordLength; max > 0 && utf8.RuneCountInString(word) > max {
+		a.rejected.Add(1)
+		return false
+	}
+	return true
+}
+
+// acceptable returns the words within MaxWordLength, words itself when they
+// all are.
+func (a *AutocompleteService) acceptable(words []string) []string {
+	if a.Config.MaxWordLength <= 0 {
+		return words
+	}
+	for i, word := range words {
+		if a.accept(word) {
+			continue
+		}
+		// Copied from the first rejected word on, the caller owns words.
+		kept := slices.Clone(words[:i])
+		for _, word := range words[i+1:] {
+			if a.accept(word) {
+				kept = append(kept, word)
+			}
+		}
+		return kept
+	}
+	return words
+}
+
+// RejectedWords returns the number of words skipped because they were longer
+// than MaxWordLength, see WithMaxWordLength().
+func (a *AutocompleteService) RejectedWords() int64 {
+	return a.rejected.Load()
+}
+
+// limitStore skips the words longer than MaxWordLength when loading.
+type limitStore struct {
+	PublicProviderStore
+
+	service *AutocompleteService
+}
+
+func (l *limitStore) Insert(word string) {
+	if l.service.accept(word) {
+		l.PublicProviderStore.Insert(word)
+	}
+}
+
+// Remove deletes the word from the store, and reports whether it existed.
+func (a *AutocompleteService) Remove(word string) bool {
+	if !a.acquire() {
+		return false
+	}
+	defer a.release()
+	return a.remove(word)
+}
+
+// RemoveE behaves like Remove, but returns ErrServiceClosed once the service
+// is closed.
+func (a *AutocompleteService) RemoveE(word string) (bool, error) {
+	if !a.acquire() {
+		return false, fmt.Errorf("autocompleteservice: remove: %w", ErrServiceClosed)
+	}
+	defer a.release()
+	return a.remove(word), nil
+}
+
+// remove deletes the word, keeping the indexes up to date.
+func (a *AutocompleteService) remove(word string) bool {
+	if !a.store.Delete(word) {
+		return false
+	}
+	a.cache.invalidate()
+	a.folds.remove(word)
+	a.substrings.remove(word)
+	a.trends.forget(a.Config.storeOptions().key(word))
+	a.markUpdated()
+	return true
 }
 
 func (a *AutocompleteService) GetContents() []string {
-	if a.isClosed {
+	if !a.acquire() {
 		return []string{}
 	}
+	defer a.release()
 	return a.store.ListContents()
 }
 
+// GetContentsE behaves like GetContents, but returns ErrServiceClosed once the
+// service is closed instead of no words.
+func (a *AutocompleteService) GetContentsE() ([]string, error) {
+	if !a.acquire() {
+		return nil, fmt.Errorf("autocompleteservice: getcontents: %w", ErrServiceClosed)
+	}
+	defer a.release()
+	return a.store.ListContents(), nil
+}
+
 // TODO: Add future functionality to allow the user to pass in a data source instead.
 // This requires a redesign of the formatter and provider interfaces, mainly the formatter.
 // It was designed specifically around keywords, however it's probably going to need to grow
 // later on as we support various data structures.
 func (a *AutocompleteService) DisplayGraph() ([]byte, error) {
 	var buf bytes.Buffer
-	err := a.store.Visualize(&buf)
+	err := a.Visualize(&buf)
 	if err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
+
+// Visualize writes the store as a graphviz `.dot` file, see VisualizeMermaid()
+// for a diagram that renders in Markdown.
+func (a *AutocompleteService) Visualize(w io.Writer) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: visualize: %w", ErrServiceClosed)
+	}
+	defer a.release()
+	return a.store.Visualize(w)
+}
@@ -1 +1,1487 @@
 package autocomplete
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// mockProvider is a DataProvider backed by a slice of keywords.
+type mockProvider struct {
+	words []string
+	err   error
+
+	mu     sync.Mutex
+	reads  int
+	dumps  int
+	dumped []string
+}
+
+func (m *mockProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	if m.err != nil {
+		return m.err
+	}
+	for _, word := range m.words {
+		store.Insert(word)
+	}
+	return nil
+}
+
+func (m *mockProvider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dumps++
+	if m.err != nil {
+		return m.err
+	}
+	m.dumped = store.ListContents()
+	return nil
+}
+
+func (m *mockProvider) Close() error { return nil }
+
+func (m *mockProvider) dumpCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dumps
+}
+
+// blockingProvider is a ContextDataProvider whose reads block until their
+// context is done.
+type blockingProvider struct {
+	mockProvider
+	started chan struct{}
+}
+
+func (b *blockingProvider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	close(b.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (b *blockingProvider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return b.DumpData(fileName, store, fmtr)
+}
+
+// captureHandler is a slog.Handler that keeps every record it handles.
+type captureHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *captureHandler) WithGroup(string) slog.Handler { return h }
+
+// find returns the first record with the message msg.
+func (h *captureHandler) find(msg string) (slog.Record, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range h.records {
+		if r.Message == msg {
+			return r, true
+		}
+	}
+	return slog.Record{}, false
+}
+
+func testService(t *testing.T, keywords []string, opts ...ConfigFn) *AutocompleteService {
+	t.Helper()
+	service, err := New(NewServiceConfig(opts...), keywords)
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	return service
+}
+
+func TestQueryStats(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "pool", "beach"}
+	service := testService(t, words)
+
+	prefixes := []string{"b", "bi", "bik", "po"}
+	goroutines := 8
+	perGoroutine := 250
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				service.Complete(prefixes[j%len(prefixes)])
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := service.QueryStats()
+	expected := int64(goroutines * perGoroutine)
+	if stats.Total != expected {
+		t.Errorf("Expected %d total queries, got %d", expected, stats.Total)
+	}
+
+	var histogramTotal int64
+	for _, count := range stats.PrefixLengths {
+		histogramTotal += count
+	}
+	if histogramTotal != expected {
+		t.Errorf("Expected histogram to sum to %d, got %d", expected, histogramTotal)
+	}
+
+	// "bi" and "po" are both two runes long.
+	if stats.PrefixLengths[2] != expected/2 {
+		t.Errorf("Expected %d queries of length 2, got %d", expected/2, stats.PrefixLengths[2])
+	}
+}
+
+func TestLoadDataSourceWithProgress(t *testing.T) {
+	words := make([]string, 2500)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i)
+	}
+
+	service := testService(t, nil, WithProgressInterval(1000))
+	src := NewDataSource(&mockProvider{words: words}, nil, "words.txt", "")
+
+	var reports []int
+	err := service.LoadDataSourceWithProgress(*src, func(loaded int) {
+		reports = append(reports, loaded)
+	})
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	expected := []int{1000, 2000, 2500}
+	if len(reports) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, reports)
+	}
+	for i := range expected {
+		if reports[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, reports)
+		}
+		if i > 0 && reports[i] <= reports[i-1] {
+			t.Errorf("Expected monotonically increasing counts, got %v", reports)
+		}
+	}
+
+	if len(service.GetContents()) != len(words) {
+		t.Errorf("Expected %d words, got %d", len(words), len(service.GetContents()))
+	}
+}
+
+func TestCompleteExcluding(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "pool", "beach"}
+	service := testService(t, words)
+
+	results := service.CompleteExcluding("bi", []string{"bike", "pool", "unknown"})
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results, got %d: %v", len(results), results)
+	}
+	for _, word := range results {
+		if word == "bike" {
+			t.Errorf("Expected %q to be excluded, got %v", word, results)
+		}
+	}
+
+	// Nothing to exclude returns the plain completions.
+	results = service.CompleteExcluding("bi", nil)
+	if len(results) != 3 {
+		t.Errorf("Expected 3 results, got %d: %v", len(results), results)
+	}
+}
+
+// countingStore counts the inserts made into the wrapped store.
+type countingStore struct {
+	autocompleter
+	inserts int
+}
+
+func (c *countingStore) Insert(word string) {
+	c.inserts++
+	c.autocompleter.Insert(word)
+}
+
+func (c *countingStore) InsertBatch(words []string) {
+	c.inserts += len(words)
+	c.autocompleter.InsertBatch(words)
+}
+
+func TestNewWithStore(t *testing.T) {
+	words := []string{"bike", "bike path", "beach"}
+
+	t.Run("skip initial insert", func(t *testing.T) {
+		store := &countingStore{autocompleter: newTrie()}
+		for _, word := range words {
+			store.Insert(word)
+		}
+
+		service := testService(t, words, WithStore(store), WithSkipInitialInsert)
+		if store.inserts != len(words) {
+			t.Errorf("Expected %d inserts, got %d", len(words), store.inserts)
+		}
+
+		if len(service.Complete("b")) != len(words) {
+			t.Errorf("Expected %d results, got %v", len(words), service.Complete("b"))
+		}
+	})
+
+	t.Run("insert into provided store", func(t *testing.T) {
+		store := &countingStore{autocompleter: newTrie()}
+
+		service := testService(t, words, WithStore(store))
+		if store.inserts != len(words) {
+			t.Errorf("Expected %d inserts, got %d", len(words), store.inserts)
+		}
+
+		if !service.Exists("beach") {
+			t.Errorf("Expected %q to exist", "beach")
+		}
+	})
+}
+
+func TestCompleteOffsets(t *testing.T) {
+	service := testService(t, []string{"café au lait", "café", "cafeteria"})
+
+	results := service.CompleteOffsets("café")
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %v", results)
+	}
+	for _, result := range results {
+		if result.PrefixRunes != 4 {
+			t.Errorf("Expected 4 prefix runes, got %d", result.PrefixRunes)
+		}
+		if result.PrefixBytes != 5 {
+			t.Errorf("Expected 5 prefix bytes, got %d", result.PrefixBytes)
+		}
+		if result.Word[:result.PrefixBytes] != "café" {
+			t.Errorf("Expected %q to start with %q", result.Word, "café")
+		}
+	}
+
+	results = service.CompleteOffsets("caf")
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %v", results)
+	}
+	if results[0].PrefixRunes != 3 || results[0].PrefixBytes != 3 {
+		t.Errorf("Expected 3 runes and 3 bytes, got %d and %d", results[0].PrefixRunes, results[0].PrefixBytes)
+	}
+
+	// Offsets are within the stored word, not the prefix typed.
+	for _, tt := range []struct {
+		name         string
+		word, prefix string
+		opts         []ConfigFn
+		runes, size  int
+	}{
+		{"compacted", "dog park", "dog  p", []ConfigFn{WithWhitespaceCompaction}, 5, 5},
+		{"folded", "Café", "CAF", []ConfigFn{WithCaseInsensitive}, 3, 3},
+		// The Kelvin sign lower cases to a single byte "k".
+		{"length changing fold", "\u212Aelvin", "kel", []ConfigFn{WithCaseInsensitive}, 3, 5},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(t, []string{tt.word}, tt.opts...)
+			results := service.CompleteOffsets(tt.prefix)
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result, got %v", results)
+			}
+			if results[0].PrefixRunes != tt.runes || results[0].PrefixBytes != tt.size {
+				t.Errorf("Expected %d runes and %d bytes, got %d and %d", tt.runes, tt.size, results[0].PrefixRunes, results[0].PrefixBytes)
+			}
+		})
+	}
+}
+
+func TestExportPrefixToDataSource(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "pool", "beach"}
+	service := testService(t, words)
+
+	path := filepath.Join(t.TempDir(), "bi.json")
+	provider, err := NewLocalFileProvider(path)
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	if err := service.ExportPrefixToDataSource("bi", *NewDataSource(provider, DefaultFormat{}, path, "")); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	byts, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	var exported []string
+	if err := json.Unmarshal(byts, &exported); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	sort.Strings(exported)
+
+	assertWords(t, []string{"bicycle repair", "bike", "bike path"}, exported)
+
+	if err := provider.Close(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
+
+func TestCompleteSortedBy(t *testing.T) {
+	service := testService(t, []string{"bike", "bike path", "bicycle repair", "bin"})
+
+	byLastChar := func(a, b string) bool {
+		return a[len(a)-1] < b[len(b)-1]
+	}
+
+	expected := []string{"bike", "bike path", "bin", "bicycle repair"}
+	assertWords(t, expected, service.CompleteSortedBy("bi", byLastChar))
+}
+
+func TestCompleteRandom(t *testing.T) {
+	words := make([]string, 50)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i)
+	}
+
+	first := testService(t, words, WithSeed(42))
+	second := testService(t, words, WithSeed(42))
+
+	for i := 0; i < 5; i++ {
+		picks := first.CompleteRandom("word", 5)
+		if len(picks) != 5 {
+			t.Fatalf("Expected 5 results, got %v", picks)
+		}
+		assertWords(t, picks, second.CompleteRandom("word", 5))
+	}
+
+	if len(first.CompleteRandom("word", 100)) != len(words) {
+		t.Errorf("Expected at most %d results", len(words))
+	}
+}
+
+func TestEstimateResults(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "pool", "beach", "bikes", "cat", "cats", "car"}
+
+	for _, tt := range []struct {
+		name string
+		opts []ConfigFn
+	}{
+		{"default", nil},
+		{"min prefix length", []ConfigFn{WithMinPrefixLength(3)}},
+		{"stemming", []ConfigFn{WithStemming}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(t, words, tt.opts...)
+
+			for _, prefix := range []string{"", "b", "bi", "bike", "po", "x", "ca", "cat"} {
+				expected := len(service.Complete(prefix))
+				if got := service.EstimateResults(prefix); got != expected {
+					t.Errorf("Expected %d results for %q, got %d", expected, prefix, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSlowQueryLogging(t *testing.T) {
+	words := make([]string, 20000)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i)
+	}
+
+	handler := &captureHandler{}
+	service := testService(t, words, WithLogger(slog.New(handler)), WithSlowQueryThreshold(time.Nanosecond))
+
+	results := service.Complete("wo")
+
+	record, ok := handler.find("slow completion query")
+	if !ok {
+		t.Fatalf("Expected a slow query to be logged")
+	}
+
+	attrs := make(map[string]slog.Value)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value
+		return true
+	})
+	if attrs["prefix_length"].Int64() != 2 {
+		t.Errorf("Expected prefix length 2, got %v", attrs["prefix_length"])
+	}
+	if attrs["results"].Int64() != int64(len(results)) {
+		t.Errorf("Expected %d results, got %v", len(results), attrs["results"])
+	}
+}
+
+func TestCompletePrefixes(t *testing.T) {
+	service := testService(t, []string{"bike", "bike path", "bikes"})
+
+	expected := []PrefixInfo{
+		{Prefix: "bike", IsWord: true, Completions: 3},
+		{Prefix: "bike ", Completions: 1},
+		{Prefix: "bike p", Completions: 1},
+		{Prefix: "bike pa", Completions: 1},
+		{Prefix: "bike pat", Completions: 1},
+		{Prefix: "bike path", IsWord: true, Completions: 1},
+		{Prefix: "bikes", IsWord: true, Completions: 1},
+	}
+
+	results := service.CompletePrefixes("bike")
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], results[i])
+		}
+	}
+
+	if len(service.CompletePrefixes("x")) != 0 {
+		t.Errorf("Expected no results, got %v", service.CompletePrefixes("x"))
+	}
+}
+
+func TestCloseWithOperationsInFlight(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "pool", "beach"}
+	service := testService(t, words)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				service.Complete("b")
+				service.Exists("bike")
+				service.GetContents()
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := service.Close(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+
+	// Every operation started after Close sees the closed service.
+	if len(service.Complete("b")) != 0 {
+		t.Errorf("Expected no results after close, got %v", service.Complete("b"))
+	}
+	service.Add("bike")
+	if service.Exists("bike") {
+		t.Errorf("Expected %q to not exist after close", "bike")
+	}
+
+	close(stop)
+	wg.Wait()
+
+	// Closing twice is a no-op.
+	if err := service.Close(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
+
+func TestCompleteMatchingClass(t *testing.T) {
+	words := []string{"555-1234", "5551234", "555 main st", "555", "55a", "123"}
+	service := testService(t, words)
+
+	results := service.CompleteMatchingClass("55", unicode.IsDigit)
+	sort.Strings(results)
+	assertWords(t, []string{"555", "5551234"}, results)
+
+	if len(service.CompleteMatchingClass("5a", unicode.IsDigit)) != 0 {
+		t.Errorf("Expected no results for a disallowed prefix")
+	}
+}
+
+func TestCloseWithoutSnapshotDest(t *testing.T) {
+	config := NewServiceConfig()
+	config.SnapshotDest = nil
+
+	service, err := New(config, []string{"bike"})
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if err := service.Close(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+
+	// A destination without a provider is treated the same way.
+	config = NewServiceConfig(WithSnapshotDest(DataSource{Filepath: "snapshot.json"}))
+	service, err = New(config, []string{"bike"})
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if err := service.Close(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	source := &mockProvider{words: []string{"bike", "bike path"}}
+	dest := &mockProvider{}
+	service := testService(t, nil,
+		WithKeywords([]string{"pool"}),
+		WithDataSources([]DataSource{*NewDataSource(source, nil, "words.json", "")}),
+		WithLoadDataSourcesOnStart,
+		WithSnapshotDest(*NewDataSource(dest, nil, "snapshot.json", "")),
+		WithSnapshotsEnabled,
+		WithSnapshotInterval(60),
+	)
+	clock := &fakeClock{}
+	service.newTicker = clock.newTicker
+
+	// Reopening an open service does nothing.
+	if service.IsClosed() {
+		t.Fatalf("Expected the service to be open")
+	}
+	if err := service.Reopen(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if len(clock.tickers) != 0 || source.reads != 1 {
+		t.Errorf("Expected nothing to be restarted, got %d tickers and %d reads", len(clock.tickers), source.reads)
+	}
+
+	service.recordError(errors.New("transient failure"))
+	for i := 0; i < 2; i++ {
+		if err := service.Close(); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+	}
+	if !service.IsClosed() {
+		t.Fatalf("Expected the service to be closed")
+	}
+	assertWords(t, []string{}, service.Complete("b"))
+
+	if err := service.Reopen(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if service.IsClosed() {
+		t.Fatalf("Expected the service to be open")
+	}
+	// Started over: keywords inserted, data sources loaded, errors cleared.
+	assertWords(t, []string{"bike", "bike path", "pool"}, service.GetContents())
+	if errs := service.GetErrors(); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+	if source.reads != 2 {
+		t.Errorf("Expected the data sources to be loaded again, got %d reads", source.reads)
+	}
+
+	// The snapshot loop is running again.
+	clock.last().c <- time.Now()
+	waitFor(t, func() bool {
+		dest.mu.Lock()
+		defer dest.mu.Unlock()
+		return len(dest.dumped) == 3
+	})
+
+	t.Run("failing source", func(t *testing.T) {
+		service.Close()
+		source.err = errors.New("source unavailable")
+		if err := service.Reopen(); !errors.Is(err, source.err) {
+			t.Errorf("Expected the error to wrap %v, got %v", source.err, err)
+		}
+		if service.IsClosed() {
+			t.Errorf("Expected the service to be reopened anyway")
+		}
+		assertWords(t, []string{"pool"}, service.GetContents())
+		service.Close()
+	})
+}
+
+func TestLoadDedupe(t *testing.T) {
+	words := []string{"bike", "bike path", "beach"}
+	src := *NewDataSource(&mockProvider{words: words}, nil, "words.txt", "")
+
+	store := &countingStore{autocompleter: newTrie()}
+	service := testService(t, []string{"bike"}, WithStore(store), WithLoadDedupe)
+
+	for i := 0; i < 2; i++ {
+		if err := service.LoadDataSource(src); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+	}
+
+	// "bike" from New, then "bike path" and "beach" from the first load.
+	if store.inserts != 3 {
+		t.Errorf("Expected 3 inserts, got %d", store.inserts)
+	}
+	// "bike" on the first load, then every word on the second.
+	if service.DedupedOnLoad() != 4 {
+		t.Errorf("Expected 4 deduped words, got %d", service.DedupedOnLoad())
+	}
+	if len(service.GetContents()) != 3 {
+		t.Errorf("Expected 3 words, got %v", service.GetContents())
+	}
+}
+
+func TestRemove(t *testing.T) {
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}} {
+		service := testService(t, []string{"bike", "bike path", "beach"}, opts...)
+
+		if !service.Remove("bike") {
+			t.Errorf("Expected %q to be removed", "bike")
+		}
+		if service.Remove("bike") {
+			t.Errorf("Expected removing %q twice to return false", "bike")
+		}
+		assertWords(t, []string{"bike path"}, service.Complete("bi"))
+
+		service.Close()
+		if service.Remove("beach") {
+			t.Errorf("Expected remove to be a no-op once closed")
+		}
+	}
+}
+
+func TestCompleteDiverse(t *testing.T) {
+	words := []string{"be", "beach", "bean", "bear", "beard", "beast", "bike", "bike path", "bin", "blue"}
+	service := testService(t, words)
+
+	t.Run("caps every branch", func(t *testing.T) {
+		assertWords(t, []string{"be", "beach", "bike", "bike path", "blue"}, service.CompleteDiverse("b", 2))
+	})
+
+	t.Run("prefix is its own branch", func(t *testing.T) {
+		assertWords(t, []string{"be", "beach"}, service.CompleteDiverse("be", 1))
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		results := service.CompleteDiverse("b", 0)
+		if len(results) != len(words) {
+			t.Errorf("Expected %d results, got %v", len(words), results)
+		}
+	})
+}
+
+func TestAddEmptyWord(t *testing.T) {
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}} {
+		service := testService(t, []string{"bike", "", "beach"}, opts...)
+		service.Add("")
+
+		if service.Exists("") {
+			t.Errorf("Expected the empty word to not be stored")
+		}
+		assertWords(t, []string{"beach", "bike"}, service.CompleteSortedBy("", func(a, b string) bool { return a < b }))
+	}
+}
+
+func TestCompletionCountHistogram(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "beach", "pool"}
+	service := testService(t, words)
+
+	prefixes := []string{"b", "bi", "bik", "be", "p", "z", "x"}
+	histogram := service.CompletionCountHistogram(prefixes)
+
+	expected := map[int]int{4: 1, 3: 1, 2: 1, 1: 2, 0: 2}
+	total := 0
+	for count, n := range histogram {
+		total += n
+		if expected[count] != n {
+			t.Errorf("Expected %d prefixes with %d completions, got %d", expected[count], count, n)
+		}
+	}
+	if total != len(prefixes) {
+		t.Errorf("Expected %d prefixes, got %d", len(prefixes), total)
+	}
+
+	if service.QueryStats().Total != 0 {
+		t.Errorf("Expected the histogram to not be recorded as queries")
+	}
+}
+
+func TestCompleteLongWord(t *testing.T) {
+	long := strings.Repeat("a", 100000)
+
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}} {
+		service := testService(t, []string{long, long + "b", "bike"}, opts...)
+
+		// Walking the long word recursively would need well over this.
+		prev := debug.SetMaxStack(1 << 20)
+		results := service.Complete("a")
+		debug.SetMaxStack(prev)
+
+		if len(results) != 2 || results[0] != long || results[1] != long+"b" {
+			t.Errorf("Expected the long words to be completed, got %d results", len(results))
+		}
+	}
+}
+
+func TestCompleteOrder(t *testing.T) {
+	words := []string{"waterfront", "bike path", "bike", "beach", "bicycle repair", "bin", "dog park", "pool"}
+	expected := []string{"bicycle repair", "bike", "bike path", "bin"}
+
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}} {
+		service := testService(t, words, opts...)
+
+		for i := 0; i < 5; i++ {
+			assertWords(t, expected, service.Complete("bi"))
+			assertWords(t, expected, service.store.Autocomplete("bi"))
+		}
+
+		// The prefix itself is included when it is a word.
+		assertWords(t, []string{"bike", "bike path"}, service.store.Autocomplete("bike"))
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	words := []string{"Bike", "bike path", "BICYCLE repair", "beach", "bike"}
+
+	for _, opts := range [][]ConfigFn{{WithCaseInsensitive}, {WithCaseInsensitive, WithLowMemoryMode}, {WithCaseInsensitive, WithRadixMode}} {
+		service := testService(t, words, opts...)
+
+		// "bike" collapses into the first seen "Bike".
+		expected := []string{"BICYCLE repair", "Bike", "bike path"}
+		for _, prefix := range []string{"bi", "BI", "bI", "Bi"} {
+			assertWords(t, expected, service.Complete(prefix))
+		}
+		assertWords(t, []string{"Bike", "bike path"}, service.Complete("BIKE"))
+		assertWords(t, []string{"Bike", "bike path"}, service.store.Autocomplete("bIkE"))
+
+		for _, word := range []string{"bike", "BIKE", "Bike Path", "bicycle REPAIR"} {
+			if !service.Exists(word) {
+				t.Errorf("Expected %q to exist", word)
+			}
+		}
+		if n := len(service.GetContents()); n != 4 {
+			t.Errorf("Expected 4 words, got %d", n)
+		}
+
+		if !service.Remove("BIKE") {
+			t.Errorf("Expected %q to be removed", "BIKE")
+		}
+		service.Add("bIKE")
+		assertWords(t, []string{"bIKE", "bike path"}, service.Complete("bik"))
+	}
+
+	// U+212A, the Kelvin sign, is 3 bytes long and lower cases to "k".
+	t.Run("length changing fold", func(t *testing.T) {
+		service := testService(t, []string{"\u212Aite", "\u212Ayak", "kiwi1"}, WithCaseInsensitive)
+
+		for _, prefix := range []string{"k", "\u212A"} {
+			assertWords(t, []string{"kiwi1", "\u212Ayak"}, service.CompleteDiverse(prefix, 1))
+			assertWords(t, []string{"\u212Aite", "\u212Ayak"}, service.CompleteMatchingClass(prefix, func(r rune) bool { return !unicode.IsDigit(r) }))
+		}
+		assertWords(t, []string{"kiwi1", "\u212Aite"}, service.CompleteDiverse("KI", 1))
+
+		var prefixes []string
+		for _, info := range service.CompletePrefixes("k") {
+			prefixes = append(prefixes, info.Prefix)
+		}
+		assertWords(t, []string{"k", "ki", "kiw", "kiwi", "kiwi1", "\u212A", "\u212Ai", "\u212Ait", "\u212Aite", "\u212Ay", "\u212Aya", "\u212Ayak"}, prefixes)
+	})
+
+	t.Run("case sensitive by default", func(t *testing.T) {
+		service := testService(t, words)
+		assertWords(t, []string{"Bike"}, service.Complete("Bi"))
+	})
+}
+
+func TestWhitespaceCompaction(t *testing.T) {
+	// Leading and trailing runs are collapsed too, not trimmed.
+	words := []string{"dog  park", " bike\t path", "bike   path", "waterfront\n", "pool"}
+
+	for _, opts := range [][]ConfigFn{{WithWhitespaceCompaction}, {WithWhitespaceCompaction, WithLowMemoryMode}, {WithWhitespaceCompaction, WithRadixMode}} {
+		service := testService(t, words, opts...)
+
+		contents := service.GetContents()
+		sort.Strings(contents)
+		assertWords(t, []string{" bike path", "bike path", "dog park", "pool", "waterfront "}, contents)
+
+		assertWords(t, []string{"dog park"}, service.Complete("dog \t p"))
+		if !service.Exists("bike \n path") {
+			t.Errorf("Expected %q to exist", "bike path")
+		}
+	}
+
+	// The prefix is longer than the part of the words it matches.
+	t.Run("uncompacted prefix", func(t *testing.T) {
+		service := testService(t, []string{"a b", "a c", "a  d1"}, WithWhitespaceCompaction)
+		prefix := "a   "
+
+		assertWords(t, []string{"a b", "a c", "a d1"}, service.CompleteDiverse(prefix, 1))
+		assertWords(t, []string{"a b", "a c"}, service.CompleteMatchingClass(prefix, func(r rune) bool { return !unicode.IsDigit(r) }))
+
+		var prefixes []string
+		for _, info := range service.CompletePrefixes(prefix) {
+			prefixes = append(prefixes, info.Prefix)
+		}
+		assertWords(t, []string{"a ", "a b", "a c", "a d", "a d1"}, prefixes)
+	})
+}
+
+func TestCompleteJSON(t *testing.T) {
+	words := []string{`the "bike" shop`, `the \ path`, "the <beach>", "pool"}
+	service := testService(t, words)
+
+	data, err := service.CompleteJSON("the")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	var results []string
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", err, data)
+	}
+	assertWords(t, []string{`the "bike" shop`, "the <beach>", `the \ path`}, results)
+	if !strings.Contains(string(data), `"the \"bike\" shop"`) {
+		t.Errorf("Expected the quotes to be escaped, got %s", data)
+	}
+
+	t.Run("no results", func(t *testing.T) {
+		data, err := service.CompleteJSON("zebra")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if string(data) != "[]" {
+			t.Errorf("Expected [], got %s", data)
+		}
+	})
+
+	t.Run("max results", func(t *testing.T) {
+		service := testService(t, words, WithMaxResults(1))
+		data, err := service.CompleteJSON("the")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if string(data) != `["the \"bike\" shop"]` {
+			t.Errorf("Expected a single result, got %s", data)
+		}
+	})
+}
+
+func TestCount(t *testing.T) {
+	stores := map[string][]ConfigFn{
+		"trie":  {},
+		"tst":   {WithLowMemoryMode},
+		"spill": {WithSpillStore(newTrie(), 2)},
+	}
+
+	for name, opts := range stores {
+		t.Run(name, func(t *testing.T) {
+			service := testService(t, []string{"bike", "bike path", "beach"}, opts...)
+			if service.Count() != 3 {
+				t.Errorf("Expected 3 words, got %d", service.Count())
+			}
+
+			// Duplicates aren't counted twice.
+			service.Add("bike")
+			service.Add("bin")
+			service.Add("bin")
+			if service.Count() != 4 {
+				t.Errorf("Expected 4 words, got %d", service.Count())
+			}
+
+			prefixes := map[string]int{"": 4, "b": 4, "bi": 3, "bike": 2, "bike path": 1, "bikes": 0, "z": 0}
+			for prefix, expected := range prefixes {
+				if n := service.PrefixCount(prefix); n != expected {
+					t.Errorf("Expected %d words starting with %q, got %d", expected, prefix, n)
+				}
+			}
+
+			service.Remove("bike")
+			service.Remove("bike")
+			service.Remove("zebra")
+			if service.Count() != 3 {
+				t.Errorf("Expected 3 words, got %d", service.Count())
+			}
+			if n := service.PrefixCount("bike"); n != 1 {
+				t.Errorf("Expected 1 word starting with %q, got %d", "bike", n)
+			}
+
+			service.Clear(false)
+			if service.Count() != 0 {
+				t.Errorf("Expected 0 words, got %d", service.Count())
+			}
+		})
+	}
+}
+
+func TestLoadDataSourcesContext(t *testing.T) {
+	var _ ContextDataProvider = (*blockingProvider)(nil)
+
+	first := &mockProvider{words: []string{"bike"}}
+	blocking := &blockingProvider{started: make(chan struct{})}
+	last := &mockProvider{words: []string{"pool"}}
+	service := testService(t, nil, WithDataSources([]DataSource{
+		*NewDataSource(first, nil, "first.txt", ""),
+		*NewDataSource(blocking, nil, "blocking.txt", ""),
+		*NewDataSource(last, nil, "last.txt", ""),
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-blocking.started
+		cancel()
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- service.LoadDataSourcesContext(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("Expected %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected LoadDataSourcesContext to return once cancelled")
+	}
+
+	if len(service.Errors) != 1 || service.Errors[0] != context.Canceled {
+		t.Errorf("Expected [%v], got %v", context.Canceled, service.Errors)
+	}
+	if !service.Exists("bike") {
+		t.Errorf("Expected the sources loaded before the cancel to be kept")
+	}
+	if last.reads != 0 {
+		t.Errorf("Expected 0 reads after the cancel, got %d", last.reads)
+	}
+
+	// Already cancelled, nothing is read.
+	if err := service.LoadDataSourcesContext(ctx); err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if first.reads != 1 {
+		t.Errorf("Expected 1 read, got %d", first.reads)
+	}
+}
+
+func TestLoadDataSourcesContinuesPastFailures(t *testing.T) {
+	first := &mockProvider{words: []string{"bike"}}
+	failing := &mockProvider{err: errors.New("source unavailable")}
+	last := &mockProvider{words: []string{"pool"}}
+	service := testService(t, nil, WithDataSources([]DataSource{
+		*NewDataSource(first, nil, "first.txt", ""),
+		*NewDataSource(failing, nil, "missing.txt", ""),
+		*NewDataSource(last, nil, "last.txt", ""),
+	}))
+
+	err := service.LoadDataSources()
+	if !errors.Is(err, failing.err) {
+		t.Errorf("Expected the composite error to wrap %v, got %v", failing.err, err)
+	}
+	if len(service.Errors) != 1 || service.Errors[0] != failing.err {
+		t.Errorf("Expected [%v], got %v", failing.err, service.Errors)
+	}
+	assertWords(t, []string{"bike", "pool"}, service.GetContents())
+	if service.LastUpdated == 0 {
+		t.Errorf("Expected LastUpdated to be set")
+	}
+}
+
+func TestCompleteTreeJSON(t *testing.T) {
+	service := testService(t, []string{"bike", "bikes", "bin", "pool"})
+
+	data, err := service.CompleteTreeJSON("bi")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	var root TreeNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	// Flatten the tree back into words.
+	var words []string
+	var walk func(node *TreeNode, word string)
+	walk = func(node *TreeNode, word string) {
+		word += node.Char
+		if node.IsEnd {
+			words = append(words, word)
+		}
+		for _, child := range node.Children {
+			if utf8.RuneCountInString(child.Char) != 1 {
+				t.Errorf("Expected a single character, got %q", child.Char)
+			}
+			walk(child, word)
+		}
+	}
+	walk(&root, "")
+	assertWords(t, []string{"bike", "bikes", "bin"}, words)
+
+	if root.Char != "bi" || root.IsEnd || len(root.Children) != 2 {
+		t.Errorf("Expected the root to be the prefix, got %+v", root)
+	}
+	if root.Children[0].Char != "k" || root.Children[1].Char != "n" || !root.Children[1].IsEnd {
+		t.Errorf("Expected the children to be ordered, got %s", data)
+	}
+
+	data, _ = service.CompleteTreeJSON("car")
+	if string(data) != `{"char":"car","isEnd":false,"children":[]}` {
+		t.Errorf("Expected an empty tree, got %s", data)
+	}
+}
+
+func TestStats(t *testing.T) {
+	for _, tt := range []struct {
+		backend string
+		opts    []ConfigFn
+	}{
+		{"trie", nil},
+		{"tst", []ConfigFn{WithLowMemoryMode}},
+		{"spill", []ConfigFn{WithSpillStore(newTrie(), 2)}},
+		{"custom", []ConfigFn{WithStore(&countingStore{autocompleter: newTrie()})}},
+	} {
+		t.Run(tt.backend, func(t *testing.T) {
+			provider, _ := NewInMemoryProvider(nil)
+			opts := append(tt.opts, WithSnapshotDest(*NewDataSource(provider, nil, "snapshot.json", "")))
+			service := testService(t, []string{"bike", "bike path"}, opts...)
+
+			stats := service.Stats()
+			if stats.WordCount != 2 || stats.NodeCount != 9 || stats.Backend != tt.backend || stats.ErrorCount != 0 {
+				t.Errorf("Expected 2 words, 9 nodes and the %s backend, got %+v", tt.backend, stats)
+			}
+			if stats.LastUpdated != service.LastUpdated {
+				t.Errorf("Expected %d, got %d", service.LastUpdated, stats.LastUpdated)
+			}
+
+			service.Add("pool")
+			if stats := service.Stats(); stats.WordCount != 3 || stats.NodeCount != 13 {
+				t.Errorf("Expected 3 words and 13 nodes, got %+v", stats)
+			}
+			if err := service.CreateSnapshot(); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+
+			service.Clear(false)
+			if stats := service.Stats(); stats.WordCount != 0 || stats.NodeCount != 0 {
+				t.Errorf("Expected an empty store, got %+v", stats)
+			}
+
+			if err := service.RestoreFromSnapshot(); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			if stats := service.Stats(); stats.WordCount != 3 || stats.NodeCount != 13 {
+				t.Errorf("Expected 3 words and 13 nodes, got %+v", stats)
+			}
+
+			service.LoadDataSource(*NewDataSource(&mockProvider{err: errors.New("failed")}, nil, "", ""))
+			if stats := service.Stats(); stats.ErrorCount != 1 {
+				t.Errorf("Expected 1 error, got %+v", stats)
+			}
+		})
+	}
+}
+
+func TestLogging(t *testing.T) {
+	handler := &captureHandler{}
+	provider, _ := NewInMemoryProvider(nil)
+	service := testService(t, []string{"bike"},
+		WithLogger(slog.New(handler)),
+		WithSnapshotDest(*NewDataSource(provider, nil, "snapshot.json", "")),
+	)
+
+	loadErr := errors.New("unreachable")
+	service.LoadDataSource(*NewDataSource(&mockProvider{err: loadErr}, nil, "words.txt", ""))
+	r, ok := handler.find("data source load failed")
+	if !ok {
+		t.Fatalf("Expected the load error to be logged")
+	}
+	if r.Level != slog.LevelError {
+		t.Errorf("Expected %v, got %v", slog.LevelError, r.Level)
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == "error" && attr.Value.Any() != loadErr {
+			t.Errorf("Expected %v, got %v", loadErr, attr.Value)
+		}
+		return true
+	})
+
+	service.Complete("bi")
+	handler.mu.Lock()
+	for _, r := range handler.records[1:] {
+		t.Errorf("Expected Complete not to log, got %q", r.Message)
+	}
+	handler.mu.Unlock()
+
+	service.CreateSnapshot()
+	service.RestoreFromSnapshot()
+	service.Close()
+	for _, msg := range []string{"snapshot created", "snapshot restored", "service closed"} {
+		if _, ok := handler.find(msg); !ok {
+			t.Errorf("Expected %q to be logged", msg)
+		}
+	}
+}
+
+func TestErrServiceClosed(t *testing.T) {
+	service := testService(t, []string{"bike"})
+
+	if results, err := service.CompleteE("bi"); err != nil || len(results) != 1 {
+		t.Errorf("Expected [bike] and nil, got %v and %v", results, err)
+	}
+	if err := service.AddE("pool"); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+	if ok, err := service.ExistsE("pool"); !ok || err != nil {
+		t.Errorf("Expected true and nil, got %v and %v", ok, err)
+	}
+
+	if err := service.Close(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	if _, err := service.CompleteE("bi"); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+	if err := service.AddE("pool"); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+	if _, err := service.ExistsE("pool"); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+	if _, err := service.RemoveE("pool"); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+	if _, err := service.GetContentsE(); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+	if err := service.LoadDataSources(); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+
+	// The plain methods keep failing silently.
+	if results := service.Complete("bi"); len(results) != 0 {
+		t.Errorf("Expected no completions, got %v", results)
+	}
+}
+
+func TestVisualize(t *testing.T) {
+	for _, opts := range [][]ConfigFn{nil, {WithBackend(BackendTST)}, {WithStore(newTrie())}} {
+		service := testService(t, []string{"bike", "beach"}, opts...)
+
+		var buf bytes.Buffer
+		if err := service.Visualize(&buf); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if dot := buf.String(); !strings.HasPrefix(dot, "digraph {") || !strings.HasSuffix(dot, "}\n") {
+			t.Errorf("Expected a dot graph, got %q", dot)
+		}
+
+		service.Close()
+		if err := service.Visualize(&buf); !errors.Is(err, ErrServiceClosed) {
+			t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+		}
+	}
+}
+
+func TestWithKeywords(t *testing.T) {
+	service, err := NewFromConfig(NewServiceConfig(WithKeywords([]string{"bike", "beach"}), WithKeywords([]string{"pool"})))
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"beach", "bike", "pool"}, service.GetContents())
+
+	// Merged with the positional keywords, the configured ones first.
+	service = testService(t, []string{"Bike", "dog park", "pool"}, WithKeywords([]string{"bike", "beach", "beach"}), WithCaseInsensitive)
+	assertWords(t, []string{"beach", "bike", "dog park", "pool"}, service.GetContents())
+	if got := service.Count(); got != 4 {
+		t.Errorf("Expected 4 words, got %d", got)
+	}
+
+	if got := mergeKeywords([]string{"bike", "beach"}, []string{"pool", "bike"}, storeOptions{}); strings.Join(got, ",") != "bike,beach,pool" {
+		t.Errorf("Expected [bike beach pool], got %v", got)
+	}
+	// Without configured keywords, duplicates are inserted as before.
+	if got := mergeKeywords(nil, []string{"bike", "bike"}, storeOptions{}); len(got) != 2 {
+		t.Errorf("Expected the keywords untouched, got %v", got)
+	}
+
+	service = testService(t, []string{"bike"}, WithKeywords([]string{"beach"}), WithSkipInitialInsert)
+	if got := service.Count(); got != 0 {
+		t.Errorf("Expected no words, got %d", got)
+	}
+}
+
+func TestMinPrefixLength(t *testing.T) {
+	service := testService(t, []string{"bike", "bike path", "über", "übung"}, WithMinPrefixLength(2))
+
+	for _, tt := range []struct {
+		prefix   string
+		expected []string
+	}{
+		{"", []string{}},
+		{"b", []string{}},
+		{"bi", []string{"bike", "bike path"}},
+		// Two bytes, but a single rune.
+		{"ü", []string{}},
+		{"üb", []string{"über", "übung"}},
+	} {
+		results := service.Complete(tt.prefix)
+		if results == nil {
+			t.Errorf("Expected a non nil slice for %q", tt.prefix)
+		}
+		assertWords(t, tt.expected, results)
+	}
+
+	if got := len(testService(t, []string{"bike", "pool"}).Complete("")); got != 2 {
+		t.Errorf("Expected every word without a minimum, got %d", got)
+	}
+}
+
+func TestCompletePage(t *testing.T) {
+	words := make([]string, 0, 25)
+	for i := 0; i < 25; i++ {
+		words = append(words, fmt.Sprintf("word %02d", i))
+	}
+	service := testService(t, append(words, "other"))
+
+	// Walking the pages returns every completion once, in order.
+	var paged []string
+	for offset := 0; offset < 30; offset += 10 {
+		results, total := service.CompletePage("word", offset, 10)
+		if total != 25 {
+			t.Errorf("Expected a total of 25, got %d", total)
+		}
+		if expected := min(10, 25-offset); len(results) != expected {
+			t.Errorf("Expected %d results at offset %d, got %d", expected, offset, len(results))
+		}
+		paged = append(paged, results...)
+	}
+	if strings.Join(paged, ",") != strings.Join(service.Complete("word"), ",") {
+		t.Errorf("Expected the pages to match Complete, got %v", paged)
+	}
+
+	for _, tt := range []struct {
+		offset, limit int
+		expected      []string
+	}{
+		{9, 2, []string{"word 09", "word 10"}},
+		{24, 10, []string{"word 24"}},
+		{25, 10, []string{}},
+		{100, 10, []string{}},
+		{0, 0, []string{}},
+		{-5, 1, []string{"word 00"}},
+	} {
+		results, total := service.CompletePage("word", tt.offset, tt.limit)
+		if total != 25 {
+			t.Errorf("Expected a total of 25, got %d", total)
+		}
+		if strings.Join(results, ",") != strings.Join(tt.expected, ",") || results == nil {
+			t.Errorf("Expected %v at offset %d, got %v", tt.expected, tt.offset, results)
+		}
+	}
+
+	service.Close()
+	if results, total := service.CompletePage("word", 0, 10); len(results) != 0 || total != 0 {
+		t.Errorf("Expected no results once closed, got %v and %d", results, total)
+	}
+}
+
+func TestAddBatch(t *testing.T) {
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}, {WithSpillStore(newTrie(), 2)}} {
+		service := testService(t, []string{"beach"}, opts...)
+		service.AddBatch([]string{"bike", "", "bike path", "bicycle repair"})
+
+		if service.Exists("") {
+			t.Errorf("Expected the empty word to not be stored")
+		}
+		if got := service.Count(); got != 4 {
+			t.Errorf("Expected 4 words, got %d", got)
+		}
+		assertWords(t, []string{"bicycle repair", "bike", "bike path"}, service.CompleteSortedBy("bi", func(a, b string) bool { return a < b }))
+	}
+}
+
+func TestAddCounted(t *testing.T) {
+	words := []string{"bike", "beach", "bike", "", "pool", "Bike"}
+
+	for _, tt := range []struct {
+		name string
+		opts []ConfigFn
+	}{
+		{"trie", nil},
+		{"tst", []ConfigFn{WithBackend(BackendTST)}},
+		{"radix", []ConfigFn{WithBackend(BackendRadix)}},
+		{"dawg", []ConfigFn{WithBackend(BackendDAWG)}},
+		{"spill", []ConfigFn{WithSpillStore(newTrie(), 1)}},
+		{"custom", []ConfigFn{WithStore(&countingStore{autocompleter: newTrie()})}},
+	} {
+		service := testService(t, []string{"pool"}, tt.opts...)
+
+		// pool is already stored, the second bike is a repeat and the
+		// empty word is skipped, Bike is a different word.
+		added, skipped := service.AddCounted(words)
+		if added != 3 || skipped != 3 {
+			t.Errorf("%s: Expected 3 added and 3 skipped, got %d and %d", tt.name, added, skipped)
+		}
+		if got := service.Count(); got != 4 {
+			t.Errorf("%s: Expected 4 words, got %d", tt.name, got)
+		}
+
+		if added, skipped := service.AddCounted(words); added != 0 || skipped != len(words) {
+			t.Errorf("%s: Expected every word to be skipped, got %d and %d", tt.name, added, skipped)
+		}
+	}
+
+	service := testService(t, nil, WithCaseInsensitive)
+	if added, skipped := service.AddCounted([]string{"Bike", "bike"}); added != 1 || skipped != 1 {
+		t.Errorf("Expected the folded repeat to be skipped, got %d and %d", added, skipped)
+	}
+}
+
+func TestMaxWordLength(t *testing.T) {
+	src := *NewDataSource(&mockProvider{words: []string{"pool", "swimming pool"}}, nil, "words.json", "")
+	service := testService(t, []string{"beach", "beaches"}, WithMaxWordLength(5), WithDataSources([]DataSource{src}))
+
+	// Below, at and above the limit, "ééééé" is 5 runes but 10 bytes.
+	service.Add("bik")
+	service.Add("bikes")
+	service.Add("ééééé")
+	service.Add("bike path")
+	if err := service.AddE("éééééé"); !errors.Is(err, ErrWordTooLong) {
+		t.Errorf("Expected %v, got %v", ErrWordTooLong, err)
+	}
+	service.AddBatch([]string{"dog", "dog park", "doggo"})
+	if added, skipped := service.AddCounted([]string{"cat", "catalog"}); added != 1 || skipped != 1 {
+		t.Errorf("Expected 1 added and 1 skipped, got %d and %d", added, skipped)
+	}
+	if err := service.LoadDataSources(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	assertWords(t, []string{"beach", "bik", "bikes", "cat", "dog", "doggo", "pool", "ééééé"}, service.GetContents())
+	// beaches, bike path, éééééé, dog park, catalog and swimming pool.
+	if got := service.Stats().RejectedWords; got != 6 {
+		t.Errorf("Expected 6 rejected words, got %d", got)
+	}
+
+	// The loaded words rejected never reach the indexes either.
+	t.Run("indexes", func(t *testing.T) {
+		src := *NewDataSource(&mockProvider{words: []string{"bicycle-repair-shop", "bike"}}, nil, "words.json", "")
+		service := testService(t, nil, WithMaxWordLength(10), WithSubstringIndex, WithDataSources([]DataSource{src}))
+		service.CompleteFold("b")
+		if err := service.LoadDataSources(); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		assertWords(t, []string{"bike"}, service.Complete("bi"))
+		assertWords(t, []string{"bike"}, service.CompleteFold("bi"))
+		assertWords(t, []string{"bike"}, service.CompleteContains("i"))
+	})
+
+	unlimited := testService(t, []string{strings.Repeat("a", 1000)})
+	if unlimited.Count() != 1 || unlimited.RejectedWords() != 0 {
+		t.Errorf("Expected no limit by default")
+	}
+}
+
+func TestBackend(t *testing.T) {
+	for _, tt := range []struct {
+		expected string
+		opts     []ConfigFn
+	}{
+		{"trie", nil},
+		{"trie", []ConfigFn{WithBackend(BackendTrie)}},
+		{"tst", []ConfigFn{WithBackend(BackendTST)}},
+		{"radix", []ConfigFn{WithBackend(BackendRadix)}},
+		{"dawg", []ConfigFn{WithBackend(BackendDAWG)}},
+		// The deprecated booleans are honored when no backend is set.
+		{"tst", []ConfigFn{WithLowMemoryMode}},
+		{"radix", []ConfigFn{WithRadixMode}},
+		{"trie", []ConfigFn{WithLowMemoryMode, WithBackend(BackendTrie)}},
+	} {
+		service := testService(t, []string{"bike"}, tt.opts...)
+		if got := service.Stats().Backend; got != tt.expected {
+			t.Errorf("Expected the %s backend, got %s", tt.expected, got)
+		}
+	}
+
+	for _, opts := range [][]ConfigFn{
+		{WithBackend(Backend(42))},
+		{WithBackend(-1)},
+		{WithLowMemoryMode, WithRadixMode},
+		{WithBackend(BackendTST), WithGraphemeClusters},
+		{WithBackend(BackendDAWG), WithCaseInsensitive},
+	} {
+		if _, err := New(NewServiceConfig(opts...), nil); err == nil {
+			t.Errorf("Expected an error, got %v", err)
+		}
+	}
+
+	if got := Backend(42).String(); got != "Backend(42)" {
+		t.Errorf("Expected %q, got %q", "Backend(42)", got)
+	}
+}
+
+// Run with -race, loads used to write Errors and LastUpdated unsynchronized.
+func TestConcurrentLoadCompleteClose(t *testing.T) {
+	good := *NewDataSource(&mockProvider{words: []string{"bike", "bike path"}}, nil, "good.json", "")
+	bad := *NewDataSource(&mockProvider{err: errors.New("source unavailable")}, nil, "bad.json", "")
+	service := testService(t, []string{"beach"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				service.LoadDataSource(good)
+				service.LoadDataSource(bad)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				service.Complete("b")
+				service.Exists("bike")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				service.GetErrors()
+				service.GetLastUpdated()
+				service.Stats()
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(time.Millisecond)
+		if err := service.Close(); err != nil {
+			t.Errorf("Expected nil, got %v", err)
+		}
+	}()
+	wg.Wait()
+
+	for _, err := range service.GetErrors() {
+		if !errors.Is(err, bad.Provider.(*mockProvider).err) {
+			t.Errorf("Expected only the errors of the failing source, got %v", err)
+		}
+	}
+	if results := service.Complete("b"); len(results) != 0 {
+		t.Errorf("Expected no completions once closed, got %v", results)
+	}
+}
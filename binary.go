@@ -0,0 +1,498 @@
+package autocomplete
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+// The trie and the ternary search tree can persist their structure instead of
+// the flat list of words, so restoring doesn't have to rebuild the tree word by
+// word and the weights, tags and insertion order are kept.
+//
+// Both encode their nodes in pre order, children in order, after a header made
+// of a magic string, the format version and the counters of the store:
+//
+//	trie node := flags:byte [key:uvarint] [wordData] children:uvarint
+//	tst node  := flags:byte key:uvarint [wordData]
+//
+// The key is the rune of the node, or the cluster id in grapheme mode, the
+// root of the trie has none. The flags of a tst node tell which of its links
+// follow. The word data is only written for the nodes ending a word. The store options
+// (case folding, whitespace compaction...) are not part of the encoding, the
+// ones of the store decoding it apply.
+
+var (
+	_ encoding.BinaryMarshaler   = (*trie)(nil)
+	_ encoding.BinaryUnmarshaler = (*trie)(nil)
+	_ encoding.BinaryMarshaler   = (*ternarysearchtree)(nil)
+	_ encoding.BinaryUnmarshaler = (*ternarysearchtree)(nil)
+)
+
+const binaryVersion = 1
+
+const (
+	trieMagic = "actr"
+	tstMagic  = "acts"
+)
+
+// Node flags.
+const (
+	flagEnd byte = 1 << iota
+	flagDisplay
+	flagTags
+	// Only used by the ternary search tree, set for the links that are set.
+	flagLeft
+	flagMid
+	flagRight
+)
+
+var errTruncated = errors.New("unexpected end of data")
+
+type binaryWriter struct {
+	buf []byte
+}
+
+func (w *binaryWriter) uvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *binaryWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// header writes the magic string and the format version.
+func (w *binaryWriter) header(magic string) {
+	w.buf = append(w.buf, magic...)
+	w.uvarint(binaryVersion)
+}
+
+// node writes the flags of a node, its key when it has one, and its word data
+// when it ends a word.
+func (w *binaryWriter) node(flags byte, key rune, hasKey bool, isEnd bool, data wordData) {
+	if isEnd {
+		flags |= flagEnd
+		if data.display != "" {
+			flags |= flagDisplay
+		}
+		if len(data.tags) > 0 {
+			flags |= flagTags
+		}
+	}
+	w.buf = append(w.buf, flags)
+	if hasKey {
+		w.uvarint(uint64(key))
+	}
+	if !isEnd {
+		return
+	}
+
+	w.uvarint(data.seq)
+	w.buf = binary.AppendVarint(w.buf, int64(data.weight))
+	if flags&flagDisplay != 0 {
+		w.string(data.display)
+	}
+	if flags&flagTags != 0 {
+		w.uvarint(uint64(len(data.tags)))
+		for _, tag := range data.tags {
+			w.string(tag)
+		}
+	}
+}
+
+// binaryReader reads what binaryWriter writes. The first error is kept and
+// every read after it returns zero values, so it only has to be checked once
+// in a while.
+type binaryReader struct {
+	buf []byte
+	err error
+}
+
+func (r *binaryReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) == 0 {
+		r.err = errTruncated
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+// count reads a number of items, each taking at least one byte, so a corrupt
+// count can't make the decoder allocate more than the data could hold.
+func (r *binaryReader) count() int {
+	n := r.uvarint()
+	if r.err == nil && n > uint64(len(r.buf)) {
+		r.err = fmt.Errorf("count %d exceeds the remaining %d bytes", n, len(r.buf))
+		return 0
+	}
+	return int(n)
+}
+
+func (r *binaryReader) string() string {
+	n := r.count()
+	if r.err != nil {
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+// key reads a node key, which can't be past maxKey.
+func (r *binaryReader) key(maxKey rune) rune {
+	k := r.uvarint()
+	if r.err == nil && k > uint64(maxKey) {
+		r.err = fmt.Errorf("invalid key %d", k)
+		return 0
+	}
+	return rune(k)
+}
+
+// done returns the first error, or an error if some data was left unread.
+func (r *binaryReader) done() error {
+	if r.err == nil && len(r.buf) > 0 {
+		return fmt.Errorf("%d bytes left unread", len(r.buf))
+	}
+	return r.err
+}
+
+// header checks the magic string and the format version.
+func (r *binaryReader) header(magic string) {
+	if len(r.buf) < len(magic) || string(r.buf[:len(magic)]) != magic {
+		r.err = errors.New("invalid magic string")
+		return
+	}
+	r.buf = r.buf[len(magic):]
+	if v := r.uvarint(); r.err == nil && v != binaryVersion {
+		r.err = fmt.Errorf("unsupported version %d", v)
+	}
+}
+
+// node reads the flags of a node, its key when it has one, and its word data
+// when it ends a word.
+func (r *binaryReader) node(hasKey bool, maxKey rune) (flags byte, key rune, data wordData) {
+	flags = r.byte()
+	if hasKey {
+		key = r.key(maxKey)
+	}
+	if flags&flagEnd == 0 {
+		return flags, key, data
+	}
+
+	data.seq = r.uvarint()
+	data.weight = int(r.varint())
+	if flags&flagDisplay != 0 {
+		data.display = r.string()
+	}
+	if flags&flagTags != 0 {
+		n := r.count()
+		if r.err == nil {
+			data.tags = make([]string, n)
+			for i := range data.tags {
+				data.tags[i] = r.string()
+			}
+		}
+	}
+	return flags, key, data
+}
+
+// MarshalBinary encodes the nodes of the trie, along with the cluster table in
+// grapheme mode.
+func (t *trie) MarshalBinary() ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	w := &binaryWriter{}
+	w.header(trieMagic)
+	w.uvarint(t.seq)
+	w.uvarint(uint64(t.count))
+
+	var clusters []string
+	if t.clusters != nil {
+		clusters = t.clusters.clusters
+	}
+	w.uvarint(uint64(len(clusters)))
+	for _, cluster := range clusters {
+		w.string(cluster)
+	}
+
+	root := t.Root
+	if root == nil {
+		root = &trieNode{}
+	}
+
+	// Written with an explicit stack, like visit(). The root has no key.
+	type frame struct {
+		node *trieNode
+		key  rune
+		root bool
+	}
+	stack := []frame{{node: root, root: true}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		w.node(0, f.key, !f.root, f.node.isEnd, f.node.wordData)
+		w.uvarint(uint64(len(f.node.children)))
+		// Pushed in reverse, so the smallest key is written first.
+		for i := len(f.node.children) - 1; i >= 0; i-- {
+			edge := f.node.children[i]
+			stack = append(stack, frame{node: edge.node, key: edge.key})
+		}
+	}
+
+	return w.buf, nil
+}
+
+// UnmarshalBinary replaces the contents of the trie with the encoded ones. A
+// trie using grapheme clusters can only be decoded in grapheme mode.
+func (t *trie) UnmarshalBinary(data []byte) error {
+	r := &binaryReader{buf: data}
+	r.header(trieMagic)
+	seq := r.uvarint()
+	count := r.count()
+
+	n := r.count()
+	clusters := newClusterTable()
+	for i := 0; i < n && r.err == nil; i++ {
+		cluster := r.string()
+		clusters.ids[cluster] = unicode.MaxRune + 1 + rune(i)
+		clusters.clusters = append(clusters.clusters, cluster)
+	}
+	maxKey := rune(unicode.MaxRune + n)
+
+	// The root is never a word, its flags only matter to the other nodes.
+	root := &trieNode{}
+	r.node(false, maxKey)
+	root.children = make([]trieEdge, r.count())
+
+	// Decoded with an explicit stack of the edges the next nodes read go
+	// into, pushed in reverse so the first child is read first.
+	var stack []*trieEdge
+	push := func(node *trieNode) {
+		for i := len(node.children) - 1; i >= 0; i-- {
+			stack = append(stack, &node.children[i])
+		}
+	}
+	push(root)
+	for len(stack) > 0 && r.err == nil {
+		edge := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		flags, key, data := r.node(true, maxKey)
+		edge.key = key
+		edge.node = &trieNode{isEnd: flags&flagEnd != 0, wordData: data}
+		if children := r.count(); children > 0 {
+			edge.node.children = make([]trieEdge, children)
+			push(edge.node)
+		}
+	}
+	if err := r.done(); err != nil {
+		return fmt.Errorf("trie: unmarshal: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.clusters == nil && n > 0 {
+		return errors.New("trie: unmarshal: grapheme clusters can only be decoded in grapheme mode")
+	}
+	if t.clusters != nil {
+		t.clusters = clusters
+	}
+	t.Root = root
+	t.seq = seq
+	t.count = count
+	return nil
+}
+
+// MarshalBinary encodes the nodes of the tree, along with which of their links
+// are set.
+func (t *ternarysearchtree) MarshalBinary() ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	w := &binaryWriter{}
+	w.header(tstMagic)
+	w.uvarint(t.seq)
+	w.uvarint(uint64(t.count))
+
+	if t.Root == nil {
+		w.buf = append(w.buf, 0)
+		return w.buf, nil
+	}
+	w.buf = append(w.buf, 1)
+
+	// Written with an explicit stack, like visit().
+	stack := []*tstNode{t.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		var flags byte
+		if node.Left != nil {
+			flags |= flagLeft
+		}
+		if node.Mid != nil {
+			flags |= flagMid
+		}
+		if node.Right != nil {
+			flags |= flagRight
+		}
+		w.node(flags, node.Char, true, node.IsEnd, node.wordData)
+
+		// Pushed in reverse, so the left link is written first.
+		for _, link := range []*tstNode{node.Right, node.Mid, node.Left} {
+			if link != nil {
+				stack = append(stack, link)
+			}
+		}
+	}
+
+	return w.buf, nil
+}
+
+// UnmarshalBinary replaces the contents of the tree with the encoded ones.
+func (t *ternarysearchtree) UnmarshalBinary(data []byte) error {
+	r := &binaryReader{buf: data}
+	r.header(tstMagic)
+	seq := r.uvarint()
+	count := r.count()
+	hasRoot := r.byte() == 1
+
+	// Decoded with an explicit stack, holding the links the next node read
+	// goes into.
+	var root *tstNode
+	var stack []**tstNode
+	if hasRoot {
+		stack = append(stack, &root)
+	}
+	for len(stack) > 0 && r.err == nil {
+		dst := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		flags, key, data := r.node(true, unicode.MaxRune)
+		node := &tstNode{Char: key, IsEnd: flags&flagEnd != 0, wordData: data}
+		*dst = node
+
+		if flags&flagRight != 0 {
+			stack = append(stack, &node.Right)
+		}
+		if flags&flagMid != 0 {
+			stack = append(stack, &node.Mid)
+		}
+		if flags&flagLeft != 0 {
+			stack = append(stack, &node.Left)
+		}
+	}
+	if err := r.done(); err != nil {
+		return fmt.Errorf("tst: unmarshal: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Root = root
+	t.seq = seq
+	t.count = count
+	return nil
+}
+
+// SnapshotBinary writes the structure of the store to w, see
+// RestoreBinary(). Unlike CreateSnapshot() nothing goes through a formatter,
+// which makes it much faster to restore large stores, and the weights, tags
+// and insertion order of the words are kept.
+//
+// Only the trie and the ternary search tree support it.
+func (a *AutocompleteService) SnapshotBinary(w io.Writer) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: snapshotbinary: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	m, ok := a.store.(encoding.BinaryMarshaler)
+	if !ok {
+		return fmt.Errorf("autocompleteservice: snapshotbinary: the %s backend doesn't support binary snapshots", backendName(a.store))
+	}
+
+	data, err := m.MarshalBinary()
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	if err != nil {
+		err = fmt.Errorf("autocompleteservice: snapshotbinary: %w", err)
+		a.recordError(err)
+	}
+	a.logSnapshot("create", "", err)
+	return err
+}
+
+// RestoreBinary replaces the contents of the store with a snapshot written by
+// SnapshotBinary(). The snapshot has to come from the same backend, and the
+// store options of the service (e.g. WithCaseInsensitive()) should match the
+// ones of the service that wrote it.
+func (a *AutocompleteService) RestoreBinary(r io.Reader) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: restorebinary: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	u, ok := a.store.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("autocompleteservice: restorebinary: the %s backend doesn't support binary snapshots", backendName(a.store))
+	}
+
+	data, err := io.ReadAll(r)
+	if err == nil {
+		err = u.UnmarshalBinary(data)
+		// Even a failed unmarshal may have replaced some of the contents.
+		a.cache.invalidate()
+	}
+	a.logSnapshot("restore", "", err)
+	if err != nil {
+		err = fmt.Errorf("autocompleteservice: restorebinary: %w", err)
+		a.recordError(err)
+		return err
+	}
+
+	// Both are rebuilt from scratch, the restored words were never inserted
+	// through the service.
+	a.folds.reset()
+	a.substrings.reset()
+	a.trends.reset()
+	a.markUpdated()
+	return nil
+}
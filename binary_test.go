@@ -0,0 +1,172 @@
+package autocomplete
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"testing"
+)
+
+// assertSameEntries checks that both stores hold the same words with the same
+// bookkeeping.
+func assertSameEntries(t *testing.T, expected, got entryStore) {
+	t.Helper()
+	e, g := expected.entries(""), got.entries("")
+	if fmt.Sprint(e) != fmt.Sprint(g) {
+		t.Errorf("Expected %v, got %v", e, g)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	words := []string{"bike", "bike path", "Bicycle Repair", "beach", "résumé", "🇫🇷 france", "👍🏽 ok", "pool"}
+
+	for _, tt := range []struct {
+		name string
+		new  func() autocompleter
+	}{
+		{"trie", func() autocompleter { return newTrie() }},
+		{"trie case insensitive", func() autocompleter {
+			t := newTrie()
+			t.foldCase = true
+			return t
+		}},
+		{"grapheme trie", func() autocompleter { return newGraphemeTrie() }},
+		{"tst", func() autocompleter { return newTernarySearchTree("") }},
+		{"tst case insensitive", func() autocompleter {
+			t := newTernarySearchTree("")
+			t.foldCase = true
+			return t
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.new()
+			store.InsertBatch(words)
+			store.Delete("pool")
+			store.(weightStore).addWeight("beach", 3)
+			store.(tagStore).addTags("bike", []string{"outdoor", "sport"})
+
+			data, err := store.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+
+			restored := tt.new()
+			restored.Insert("stale")
+			if err := restored.(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+
+			assertSameEntries(t, store.(entryStore), restored.(entryStore))
+			if restored.Count() != store.Count() || restored.NodeCount() != store.NodeCount() {
+				t.Errorf("Expected %d words and %d nodes, got %d and %d", store.Count(), store.NodeCount(), restored.Count(), restored.NodeCount())
+			}
+			if err := restored.(verifier).verify(); err != nil {
+				t.Error(err)
+			}
+
+			// An identical tree encodes to the same bytes.
+			again, _ := restored.(encoding.BinaryMarshaler).MarshalBinary()
+			if !bytes.Equal(data, again) {
+				t.Errorf("Expected the restored tree to encode the same")
+			}
+
+			// Inserting keeps going from the restored sequence.
+			restored.Insert("bike")
+			if e := restored.(entryStore).entries("bike"); e[0].seq <= uint64(len(words)) {
+				t.Errorf("Expected a new sequence number, got %d", e[0].seq)
+			}
+		})
+	}
+}
+
+func TestBinaryEmpty(t *testing.T) {
+	for _, store := range []autocompleter{newTrie(), newTernarySearchTree("")} {
+		data, _ := store.(encoding.BinaryMarshaler).MarshalBinary()
+		if err := store.(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+			t.Errorf("Expected nil, got %v", err)
+		}
+		if store.Count() != 0 || store.NodeCount() != 0 {
+			t.Errorf("Expected an empty store, got %v", store.ListContents())
+		}
+	}
+}
+
+func TestBinaryInvalid(t *testing.T) {
+	trie := newTrie()
+	trie.InsertBatch([]string{"bike", "beach"})
+	data, _ := trie.MarshalBinary()
+
+	graphemes := newGraphemeTrie()
+	graphemes.Insert("🇫🇷")
+	clusters, _ := graphemes.MarshalBinary()
+
+	for name, tt := range map[string]struct {
+		store encoding.BinaryUnmarshaler
+		data  []byte
+	}{
+		"empty":              {newTrie(), nil},
+		"wrong magic":        {newTernarySearchTree(""), data},
+		"truncated":          {newTrie(), data[:len(data)-3]},
+		"trailing data":      {newTrie(), append(append([]byte{}, data...), 0)},
+		"clusters in a trie": {newTrie(), clusters},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := tt.store.UnmarshalBinary(tt.data); err == nil {
+				t.Errorf("Expected an error, got %v", err)
+			}
+		})
+	}
+
+	// A failed decode leaves the store untouched.
+	if err := trie.UnmarshalBinary(data[:5]); err == nil {
+		t.Errorf("Expected an error, got %v", err)
+	}
+	assertWords(t, []string{"beach", "bike"}, trie.ListContents())
+}
+
+func TestSnapshotBinary(t *testing.T) {
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}} {
+		service := testService(t, []string{"bike", "bike path", "beach"}, opts...)
+		service.AddWeighted("beach", 5)
+
+		var buf bytes.Buffer
+		if err := service.SnapshotBinary(&buf); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		restored := testService(t, []string{"stale"}, opts...)
+		if err := restored.RestoreBinary(&buf); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		assertWords(t, []string{"beach", "bike", "bike path"}, restored.Complete("b"))
+		if restored.Exists("stale") {
+			t.Errorf("Expected the contents to be replaced")
+		}
+		if got := restored.CompleteFold("BI"); len(got) != 2 {
+			t.Errorf("Expected the fold index to be rebuilt, got %v", got)
+		}
+	}
+
+	service := testService(t, nil, WithRadixMode)
+	if err := service.SnapshotBinary(&bytes.Buffer{}); err == nil {
+		t.Errorf("Expected an unsupported backend error, got %v", err)
+	}
+	if err := service.RestoreBinary(&bytes.Buffer{}); err == nil {
+		t.Errorf("Expected an unsupported backend error, got %v", err)
+	}
+}
+
+func BenchmarkRestoreBinary(b *testing.B) {
+	trie := newTrie()
+	trie.InsertBatch(benchmarkWords(100000))
+	data, _ := trie.MarshalBinary()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		restored := newTrie()
+		if err := restored.UnmarshalBinary(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package autocomplete
+
+import (
+	"sync"
+	"time"
+)
+
+// sourceBreaker is a circuit breaker for a single data source. Once a source
+// fails Config.BreakerFailures times in a row, it trips and the source is
+// skipped by LoadDataSources() until Config.BreakerCooldown has passed.
+//
+// After the cooldown the source gets a single attempt, if it fails again the
+// breaker trips right away, otherwise it's reset.
+//
+// Concurrent loads share the breaker, mu guards its state.
+type sourceBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	tripped   bool
+}
+
+// breaker returns the circuit breaker for the data source at index i of
+// Config.DataSources, or nil if the circuit breaker is disabled.
+func (a *AutocompleteService) breaker(i int) *sourceBreaker {
+	if a.breakers == nil {
+		return nil
+	}
+
+	a.breakersMu.Lock()
+	defer a.breakersMu.Unlock()
+	b, ok := a.breakers[i]
+	if !ok {
+		b = &sourceBreaker{}
+		a.breakers[i] = b
+	}
+	return b
+}
+
+// allow reports whether the source should be loaded.
+func (b *sourceBreaker) allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !now.Before(b.openUntil)
+}
+
+// failure records a failed load and returns the number of failures in a row,
+// and whether the breaker just tripped.
+func (b *sourceBreaker) failure(now time.Time, threshold int, cooldown time.Duration) (int, bool) {
+	if b == nil {
+		return 0, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures < threshold {
+		return b.failures, false
+	}
+
+	b.openUntil = now.Add(cooldown)
+	b.tripped = true
+	return b.failures, true
+}
+
+// success records a successful load and reports whether the breaker was reset.
+func (b *sourceBreaker) success() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasTripped := b.tripped
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.tripped = false
+	return wasTripped
+}
@@ -0,0 +1,111 @@
+package autocomplete
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSourceCircuitBreaker(t *testing.T) {
+	failing := &mockProvider{err: errors.New("source unavailable")}
+	sources := []DataSource{*NewDataSource(failing, nil, "keywords.json", "")}
+
+	service := testService(t, nil, WithDataSources(sources), WithSourceCircuitBreaker(3, time.Minute))
+
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if err := service.LoadDataSources(); err == nil {
+			t.Errorf("Expected non-nil, got %v", err)
+		}
+	}
+	if failing.reads != 3 {
+		t.Errorf("Expected 3 reads, got %d", failing.reads)
+	}
+
+	// Tripped, the source is skipped.
+	for i := 0; i < 5; i++ {
+		if err := service.LoadDataSources(); err != nil {
+			t.Errorf("Expected nil, got %v", err)
+		}
+	}
+	if failing.reads != 3 {
+		t.Errorf("Expected the source to be skipped after tripping, got %d reads", failing.reads)
+	}
+	if len(service.Errors) != 3 {
+		t.Errorf("Expected 3 errors, got %d", len(service.Errors))
+	}
+
+	// After the cooldown the source gets a single attempt and trips again.
+	now = now.Add(time.Minute)
+	service.LoadDataSources()
+	service.LoadDataSources()
+	if failing.reads != 4 {
+		t.Errorf("Expected 4 reads, got %d", failing.reads)
+	}
+
+	// Once it recovers the breaker is reset.
+	now = now.Add(time.Minute)
+	failing.err = nil
+	failing.words = []string{"bike"}
+	if err := service.LoadDataSources(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+	if !service.Exists("bike") {
+		t.Errorf("Expected %q to exist", "bike")
+	}
+	if service.breakers[0].failures != 0 {
+		t.Errorf("Expected the breaker to be reset, got %d failures", service.breakers[0].failures)
+	}
+}
+
+// Run with -race.
+func TestSourceCircuitBreakerConcurrentLoads(t *testing.T) {
+	failing := &mockProvider{err: errors.New("source unavailable")}
+	sources := []DataSource{
+		*NewDataSource(&mockProvider{words: []string{"bike"}}, nil, "words.json", ""),
+		*NewDataSource(failing, nil, "keywords.json", ""),
+	}
+	service := testService(t, nil, WithDataSources(sources), WithSourceCircuitBreaker(1000, time.Minute))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				service.LoadDataSources()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every failure was counted.
+	if got := service.breaker(1).failures; got != 200 {
+		t.Errorf("Expected 200 failures, got %d", got)
+	}
+}
+
+func TestSourceCircuitBreakerReloads(t *testing.T) {
+	failing := &mockProvider{err: errors.New("source unavailable")}
+	sources := []DataSource{*NewDataSource(failing, nil, "keywords.json", "")}
+	service := testService(t, nil, WithDataSources(sources), WithSourceCircuitBreaker(2, time.Minute))
+
+	// The reloads made by the file watcher count towards the breaker.
+	for i := 0; i < 2; i++ {
+		if err := service.reloadSource(0); !errors.Is(err, failing.err) {
+			t.Errorf("Expected %v, got %v", failing.err, err)
+		}
+	}
+	if err := service.reloadSource(0); err != nil {
+		t.Errorf("Expected the tripped source to be skipped, got %v", err)
+	}
+	if err := service.LoadDataSources(); err != nil {
+		t.Errorf("Expected the tripped source to be skipped, got %v", err)
+	}
+	if failing.reads != 2 {
+		t.Errorf("Expected 2 reads, got %d", failing.reads)
+	}
+}
@@ -0,0 +1,106 @@
+package autocomplete
+
+import (
+	"container/list"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// suggestionCache is a LRU cache of the results of Complete() keyed by prefix,
+// see WithSuggestionCache(). A nil cache is disabled, all its methods are
+// no-ops.
+//
+// Every write to the store bumps the generation after it is done, and only the
+// results computed at the current generation are returned. A result computed
+// while a write was in flight is tagged with the generation read before
+// computing it, so it is never returned once the write is done.
+type suggestionCache struct {
+	gen atomic.Uint64
+
+	size int
+	// Most recently used first.
+	order *list.List
+	items map[string]*list.Element
+	mu    sync.Mutex
+}
+
+type cachedSuggestions struct {
+	prefix  string
+	gen     uint64
+	results []string
+}
+
+func newSuggestionCache(size int) *suggestionCache {
+	if size <= 0 {
+		return nil
+	}
+	return &suggestionCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// generation returns the generation to tag the results about to be computed
+// with.
+func (c *suggestionCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	return c.gen.Load()
+}
+
+// invalidate drops every cached result, it must be called once the write is
+// done.
+func (c *suggestionCache) invalidate() {
+	if c != nil {
+		c.gen.Add(1)
+	}
+}
+
+// get returns a copy of the results cached for prefix, if still current.
+func (c *suggestionCache) get(prefix string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[prefix]
+	if !ok {
+		return nil, false
+	}
+	item := elem.Value.(*cachedSuggestions)
+	if item.gen != c.gen.Load() {
+		c.order.Remove(elem)
+		delete(c.items, prefix)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	// The caller owns the results, e.g. it may sort them.
+	return slices.Clone(item.results), true
+}
+
+// put caches a copy of the results computed for prefix at generation gen,
+// evicting the least recently used results when full.
+func (c *suggestionCache) put(prefix string, gen uint64, results []string) {
+	if c == nil || gen != c.gen.Load() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item := &cachedSuggestions{prefix: prefix, gen: gen, results: slices.Clone(results)}
+	if elem, ok := c.items[prefix]; ok {
+		elem.Value = item
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[prefix] = c.order.PushFront(item)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cachedSuggestions).prefix)
+	}
+}
@@ -0,0 +1,125 @@
+package autocomplete
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSuggestionCacheInvalidation(t *testing.T) {
+	service := testService(t, []string{"bike", "bike path", "beach"}, WithSuggestionCache(8))
+
+	assertWords(t, []string{"bike", "bike path"}, service.Complete("bi"))
+	if _, ok := service.cache.get("bi"); !ok {
+		t.Fatalf("Expected the completions of bi to be cached")
+	}
+
+	service.Add("bicycle repair")
+	assertWords(t, []string{"bicycle repair", "bike", "bike path"}, service.Complete("bi"))
+
+	service.Remove("bike path")
+	assertWords(t, []string{"bicycle repair", "bike"}, service.Complete("bi"))
+
+	service.AddWeighted("bike", 5)
+	assertWords(t, []string{"bike", "bicycle repair"}, service.Complete("bi"))
+	service.ResetWeights()
+	assertWords(t, []string{"bicycle repair", "bike"}, service.Complete("bi"))
+
+	service.AddBatch([]string{"bird"})
+	assertWords(t, []string{"bicycle repair", "bike", "bird"}, service.Complete("bi"))
+
+	err := service.LoadDataSource(*NewDataSource(&mockProvider{words: []string{"bison"}}, nil, "words.json", ""))
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"bicycle repair", "bike", "bird", "bison"}, service.Complete("bi"))
+
+	service.Clear(false)
+	if results := service.Complete("bi"); len(results) != 0 {
+		t.Errorf("Expected no results, got %v", results)
+	}
+
+	// The cached results belong to the cache, not to the caller.
+	service.Add("bike")
+	service.Complete("bi")[0] = "changed"
+	assertWords(t, []string{"bike"}, service.Complete("bi"))
+}
+
+func TestSuggestionCacheEviction(t *testing.T) {
+	cache := newSuggestionCache(2)
+	cache.put("a", 0, []string{"a"})
+	cache.put("b", 0, []string{"b"})
+	cache.get("a")
+	cache.put("c", 0, []string{"c"})
+
+	if _, ok := cache.get("b"); ok {
+		t.Errorf("Expected the least recently used prefix to be evicted")
+	}
+	for _, prefix := range []string{"a", "c"} {
+		if _, ok := cache.get(prefix); !ok {
+			t.Errorf("Expected %q to be cached", prefix)
+		}
+	}
+
+	// Results computed before a write are never cached.
+	gen := cache.generation()
+	cache.invalidate()
+	cache.put("d", gen, []string{"d"})
+	if _, ok := cache.get("d"); ok {
+		t.Errorf("Expected results of a past generation to be dropped")
+	}
+
+	if newSuggestionCache(0) != nil {
+		t.Errorf("Expected a size of 0 to disable the cache")
+	}
+}
+
+// Run with -race.
+func TestSuggestionCacheConcurrentWrites(t *testing.T) {
+	service := testService(t, nil, WithSuggestionCache(4))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				service.Complete("w")
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		service.Add(fmt.Sprintf("word %03d", i))
+	}
+	wg.Wait()
+
+	if got := len(service.Complete("w")); got != 100 {
+		t.Errorf("Expected 100 completions after the writes, got %d", got)
+	}
+}
+
+func BenchmarkCompleteCached(b *testing.B) {
+	words := benchmarkWords(10000)
+	prefixes := []string{"a", "ap", "app", "b", "be"}
+
+	for _, tt := range []struct {
+		name string
+		opts []ConfigFn
+	}{
+		{"uncached", nil},
+		{"cached", []ConfigFn{WithSuggestionCache(16)}},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			service, err := New(NewServiceConfig(tt.opts...), words)
+			if err != nil {
+				b.Fatalf("Expected nil, got %v", err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				service.Complete(prefixes[i%len(prefixes)])
+			}
+		})
+	}
+}
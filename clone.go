@@ -0,0 +1,167 @@
+package autocomplete
+
+// cloner is implemented by the stores that can deep copy themselves.
+type cloner interface {
+	clone() autocompleter
+}
+
+// cloneStore deep copies store. Stores that can't clone themselves are copied
+// word by word into a new store of the type selected by the config, which
+// loses any bookkeeping they keep.
+func cloneStore(store autocompleter, config *ServiceConfig) autocompleter {
+	if c, ok := store.(cloner); ok {
+		return c.clone()
+	}
+
+	copied := newStore(config)
+	for _, word := range store.ListContents() {
+		copied.Insert(word)
+	}
+	return copied
+}
+
+// Clone forks the service, the clone starts with the same words (and their
+// weights) but mutating it never affects the original, nor the other way
+// around. This is meant for short lived experiments: add some words, query,
+// then discard the clone.
+//
+// Every node is copied, so cloning costs as much memory as the original. The
+// clone doesn't get the data sources or the snapshot destination, so it can be
+// closed without closing the providers of the original. Clone returns nil once
+// the service is closed.
+func (a *AutocompleteService) Clone() *AutocompleteService {
+	if !a.acquire() {
+		return nil
+	}
+	defer a.release()
+
+	config := *a.Config
+	config.Store = nil
+	config.DataSources = nil
+	config.SnapshotDest = nil
+	config.SnapshotsEnabled = false
+
+	clone := &AutocompleteService{
+		Config:      &config,
+		store:       cloneStore(a.store, &config),
+		Errors:      make([]error, 0),
+		LastUpdated: a.GetLastUpdated(),
+		now:         a.now,
+		rng:         newRand(config.Seed),
+		cache:       newSuggestionCache(config.SuggestionCacheSize),
+
+		newTicker: a.newTicker,
+	}
+
+	a.nsMu.RLock()
+	defer a.nsMu.RUnlock()
+	if len(a.namespaces) > 0 {
+		clone.namespaces = make(map[string]autocompleter, len(a.namespaces))
+		for ns, store := range a.namespaces {
+			clone.namespaces[ns] = cloneStore(store, &config)
+		}
+	}
+
+	return clone
+}
+
+// clone copies both tiers, a secondary that can't clone itself is copied
+// into an in-memory trie.
+func (s *spillStore) clone() autocompleter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	config := &ServiceConfig{}
+	return &spillStore{
+		primary:   cloneStore(s.primary, config),
+		secondary: cloneStore(s.secondary, config),
+		threshold: s.threshold,
+		size:      s.size,
+	}
+}
+
+func (t *trie) clone() autocompleter {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	c := &trie{
+		Root:         &trieNode{},
+		seq:          t.seq,
+		count:        t.count,
+		storeOptions: t.storeOptions,
+	}
+	if t.clusters != nil {
+		c.clusters = t.clusters.clone()
+	}
+
+	// Copied with an explicit stack, like visit().
+	type pair struct{ src, dst *trieNode }
+	stack := []pair{{t.Root, c.Root}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		p.dst.isEnd = p.src.isEnd
+		p.dst.wordData = p.src.wordData
+		if len(p.src.children) == 0 {
+			continue
+		}
+
+		p.dst.children = make([]trieEdge, len(p.src.children))
+		for i, edge := range p.src.children {
+			child := &trieNode{}
+			p.dst.children[i] = trieEdge{key: edge.key, node: child}
+			stack = append(stack, pair{edge.node, child})
+		}
+	}
+
+	return c
+}
+
+func (t *ternarysearchtree) clone() autocompleter {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	c := &ternarysearchtree{
+		seq:          t.seq,
+		count:        t.count,
+		storeOptions: t.storeOptions,
+	}
+
+	// Copied with an explicit stack, like visit(). dst is the link of the
+	// copied parent the copy of src goes into.
+	type pair struct {
+		src *tstNode
+		dst **tstNode
+	}
+	stack := []pair{{t.Root, &c.Root}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if p.src == nil {
+			continue
+		}
+
+		node := &tstNode{Char: p.src.Char, IsEnd: p.src.IsEnd, wordData: p.src.wordData}
+		*p.dst = node
+		stack = append(stack,
+			pair{p.src.Left, &node.Left},
+			pair{p.src.Mid, &node.Mid},
+			pair{p.src.Right, &node.Right},
+		)
+	}
+
+	return c
+}
+
+func (c *clusterTable) clone() *clusterTable {
+	copied := &clusterTable{
+		ids:      make(map[string]rune, len(c.ids)),
+		clusters: append([]string(nil), c.clusters...),
+	}
+	for cluster, id := range c.ids {
+		copied.ids[cluster] = id
+	}
+	return copied
+}
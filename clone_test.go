@@ -0,0 +1,66 @@
+package autocomplete
+
+import (
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "beach"}
+
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}, {WithRadixMode}, {WithGraphemeClusters}} {
+		service := testService(t, words, opts...)
+		service.AddWeighted("beach", 2)
+		service.AddNS("user", "bird")
+
+		clone := service.Clone()
+		if clone == nil {
+			t.Fatalf("Expected a clone, got nil")
+		}
+		assertWords(t, service.Complete(""), clone.Complete(""))
+
+		// Mutate the clone.
+		clone.Add("bin")
+		clone.Remove("bike")
+		clone.Bump("bike path")
+		clone.AddNS("user", "birch")
+		clone.ResetWeights()
+
+		assertWords(t, []string{"beach", "bicycle repair", "bike", "bike path"}, service.Complete(""))
+		assertWords(t, []string{"bird"}, service.CompleteNS("user", "bi"))
+		if scores := service.CompleteWithScores("beach"); len(scores) != 1 || scores[0].Score != 2 {
+			t.Errorf("Expected the original weight to be kept, got %v", scores)
+		}
+
+		assertWords(t, []string{"bicycle repair", "bike path", "bin"}, clone.Complete("bi"))
+		assertWords(t, []string{"birch", "bird"}, clone.CompleteNS("user", "bi"))
+
+		// Mutating the original doesn't affect the clone either.
+		service.Add("bird")
+		if clone.Exists("bird") {
+			t.Errorf("Expected %q to not be in the clone", "bird")
+		}
+
+		// Closing the clone leaves the original open.
+		if err := clone.Close(); err != nil {
+			t.Errorf("Expected nil, got %v", err)
+		}
+		if !service.Exists("bike") {
+			t.Errorf("Expected the original to be untouched by closing the clone")
+		}
+
+		service.Close()
+		if service.Clone() != nil {
+			t.Errorf("Expected nil when cloning a closed service")
+		}
+	}
+}
+
+func TestCloneSpillStore(t *testing.T) {
+	service := testService(t, []string{"bike", "bike path", "beach"}, WithSpillStore(newTrie(), 2))
+
+	clone := service.Clone()
+	clone.Add("bin")
+
+	assertWords(t, []string{"beach", "bike", "bike path"}, service.Complete(""))
+	assertWords(t, []string{"beach", "bike", "bike path", "bin"}, clone.Complete(""))
+}
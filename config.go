@@ -1,5 +1,14 @@
 package autocomplete
 
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
 // ServiceConfig contains all of the configurable options for initializing a
 // new autocomplete service.
 //
@@ -7,16 +16,182 @@ package autocomplete
 type ServiceConfig struct {
 	ServiceName string
 	// Leave 0 for unlimited.
-	MaxResults       int
+	MaxResults int
+	// MinPrefixLength is the number of runes a prefix needs for Complete() to
+	// return completions, see WithMinPrefixLength(). Leave 0 to complete any
+	// prefix, the empty one included.
+	MinPrefixLength int
+	// MaxWordLength is the number of runes above which words are skipped
+	// instead of inserted, see WithMaxWordLength(). Leave 0 for unlimited.
+	MaxWordLength int
+	// SnapshotsEnabled creates a snapshot to SnapshotDest every
+	// SnapshotInterval seconds, in a goroutine stopped by Close().
 	SnapshotsEnabled bool
 	SnapshotInterval int
+	// SnapshotRetention is the number of timestamped snapshots kept for a
+	// local file SnapshotDest, see WithSnapshotRetention(). Leave 0 to
+	// overwrite a single file.
+	SnapshotRetention int
 
-	AutomaticUpdates       bool
+	// AutomaticUpdates reloads the LocalFileProvider data sources when their
+	// file changes, see WithAutomaticUpdates().
+	AutomaticUpdates bool
+	// UpdateDebounce is how long the file of a data source has to go without
+	// changes before it is reloaded, 250ms by default.
+	UpdateDebounce         time.Duration
 	LoadDataSourcesOnStart bool
-	LowMemoryMode          bool
+
+	// Backend is the data structure storing the words, see WithBackend().
+	// Leave it unset to pick it from LowMemoryMode and RadixMode.
+	Backend Backend
+	// Deprecated: Use Backend, LowMemoryMode is BackendTST.
+	LowMemoryMode bool
+	// Deprecated: Use Backend, RadixMode is BackendRadix.
+	RadixMode bool
+	// GraphemeClusters stores words by grapheme cluster instead of by rune.
+	// Only supported by the trie backend.
+	GraphemeClusters bool
 
 	SnapshotDest *DataSource
 	DataSources  []DataSource
+
+	// Store is used as the in-memory store instead of creating a new one.
+	// See WithStore().
+	Store autocompleter
+	// Keywords are inserted by New(), along with the keywords passed to it.
+	// See WithKeywords().
+	Keywords []string
+	// SkipInitialInsert skips inserting the keywords passed to New(). This is
+	// useful when Store was already populated.
+	SkipInitialInsert bool
+
+	// SpillStore receives every word inserted once the in-memory store
+	// holds SpillThreshold words. Leave nil to keep everything in memory.
+	SpillStore     autocompleter
+	SpillThreshold int
+
+	// LoadDedupe skips the words already stored when loading data sources
+	// instead of inserting them again.
+	LoadDedupe bool
+
+	// RecencyTieBreak ranks the most recently inserted word first when two
+	// completions would otherwise rank the same.
+	RecencyTieBreak bool
+
+	// CoverageRanking makes CompleteRanked() rank the completions the prefix
+	// covers the most of first, among the ones of the same weight.
+	CoverageRanking bool
+
+	// CaseInsensitive matches words regardless of case, while completions
+	// keep the casing the word was first inserted with. Only supported by the
+	// stores created by the service.
+	CaseInsensitive bool
+
+	// CompactWhitespace collapses every run of whitespace within a word into
+	// a single space. Only supported by the stores created by the service.
+	CompactWhitespace bool
+
+	// Stemming merges the completions sharing the same stem, e.g. "cat" and
+	// "cats", into a single canonical completion. See WithStemming().
+	Stemming bool
+
+	// SubstringIndex indexes every suffix of the stored words for
+	// CompleteContains(), see WithSubstringIndex().
+	SubstringIndex bool
+
+	// TrackHits counts every insert of a word as a hit, adding one to its
+	// weight. Only supported by the stores created by the service.
+	TrackHits bool
+
+	// BaseWeight is the weight ResetWeights() gives back to every word.
+	BaseWeight int
+
+	// TrendingRetention is how long inserts are remembered for
+	// CompleteTrending(). Leave 0 to disable tracking them.
+	TrendingRetention time.Duration
+
+	// BreakerFailures is the number of consecutive failures after which a data
+	// source is skipped for BreakerCooldown. Leave 0 to disable.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+
+	// SlowQueryThreshold logs every Complete() call that takes longer than
+	// the threshold. Leave 0 to disable.
+	SlowQueryThreshold time.Duration
+
+	// SuggestionCacheSize is the number of prefixes whose Complete() results
+	// are cached, see WithSuggestionCache(). Leave 0 to disable the cache.
+	SuggestionCacheSize int
+
+	// Seed seeds the random number generator used by randomized operations
+	// like CompleteRandom(). Leave 0 to seed from the current time.
+	Seed int64
+
+	// Logger defaults to discarding everything.
+	Logger *slog.Logger
+
+	// ProgressInterval is how many inserts happen between calls to the
+	// progress callback of LoadDataSourceWithProgress.
+	ProgressInterval int
+}
+
+// Backend selects the data structure the service stores the words in.
+type Backend int
+
+const (
+	// BackendDefault is the trie, unless LowMemoryMode or RadixMode is set.
+	BackendDefault Backend = iota
+	// BackendTrie is the fastest, at the cost of a node per character.
+	BackendTrie
+	// BackendTST is a ternary search tree, which takes less memory than the
+	// trie but is slower.
+	BackendTST
+	// BackendRadix is a radix tree, which collapses the runs of characters
+	// without branching into a single node.
+	BackendRadix
+	// BackendDAWG is a directed acyclic word graph, which also shares the
+	// common suffixes. It takes the least memory for large dictionaries but is
+	// rebuilt on the first read following a write, see DAWG.
+	BackendDAWG
+)
+
+func (b Backend) String() string {
+	switch b {
+	case BackendDefault:
+		return "default"
+	case BackendTrie:
+		return "trie"
+	case BackendTST:
+		return "tst"
+	case BackendRadix:
+		return "radix"
+	case BackendDAWG:
+		return "dawg"
+	default:
+		return "Backend(" + strconv.Itoa(int(b)) + ")"
+	}
+}
+
+// backend returns the backend selected by the config, resolving
+// BackendDefault from the deprecated booleans.
+func (c *ServiceConfig) backend() (Backend, error) {
+	switch c.Backend {
+	case BackendDefault:
+		if c.LowMemoryMode && c.RadixMode {
+			return 0, errors.New("low memory mode and radix mode can't be combined")
+		}
+		if c.LowMemoryMode {
+			return BackendTST, nil
+		}
+		if c.RadixMode {
+			return BackendRadix, nil
+		}
+		return BackendTrie, nil
+	case BackendTrie, BackendTST, BackendRadix, BackendDAWG:
+		return c.Backend, nil
+	default:
+		return 0, fmt.Errorf("unknown backend %s", c.Backend)
+	}
 }
 
 /* Config Functions */
@@ -38,22 +213,197 @@ func WithMaxResults(max int) ConfigFn {
 	}
 }
 
+// WithSnapshotsEnabled creates a snapshot every SnapshotInterval seconds in
+// the background, from New() until Close(). See WithSnapshotInterval().
 func WithSnapshotsEnabled(c *ServiceConfig) {
 	c.SnapshotsEnabled = true
 }
 
+// WithAutomaticUpdates watches the files of the LocalFileProvider data sources
+// in the background, from New() until Close(), and loads a source again with
+// LoadDataSource() whenever its file changes. Reloads are debounced so a burst
+// of writes only reloads once, see WithUpdateDebounce().
+//
+// Reloading only inserts the words of the file, the words removed from it are
+// kept. Data sources using other providers are not watched, AutomaticUpdates
+// is a no-op for them.
 func WithAutomaticUpdates(c *ServiceConfig) {
 	c.AutomaticUpdates = true
 }
 
+// WithSnapshotRetention writes every snapshot of a local file SnapshotDest to
+// a new timestamped file next to it, e.g. snapshot-20240101T120000.json for
+// snapshot.json, and only keeps the n most recent ones, so a bad state can be
+// rolled back. RestoreFromSnapshot() restores the newest one.
+func WithSnapshotRetention(n int) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.SnapshotRetention = n
+	}
+}
+
+// WithUpdateDebounce sets how long the file of a data source has to go
+// without changes before it is reloaded, see WithAutomaticUpdates().
+func WithUpdateDebounce(d time.Duration) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.UpdateDebounce = d
+	}
+}
+
+// WithMinPrefixLength makes Complete() return no completions for the
+// prefixes shorter than n runes, so an empty search box doesn't list the whole
+// dictionary.
+func WithMinPrefixLength(n int) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.MinPrefixLength = n
+	}
+}
+
+// WithMaxWordLength skips the words longer than n runes, whether added
+// through the service or loaded from a data source, so a buggy or malicious
+// client can't balloon the depth and the memory of the store. The skipped
+// words are counted in Stats().RejectedWords.
+func WithMaxWordLength(n int) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.MaxWordLength = n
+	}
+}
+
+// WithSuggestionCache caches the results of Complete() for the size most
+// recently used prefixes, which pays off when the same short prefixes are
+// completed over and over, e.g. search as you type. Every write through the
+// service drops the cached results, so they are never stale. Writes made to a
+// store shared with WithStore() without going through the service are not
+// seen by the cache.
+func WithSuggestionCache(size int) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.SuggestionCacheSize = size
+	}
+}
+
 func WithLoadDataSourcesOnStart(c *ServiceConfig) {
 	c.LoadDataSourcesOnStart = true
 }
 
+// Deprecated: Use WithBackend(BackendTST).
 func WithLowMemoryMode(c *ServiceConfig) {
 	c.LowMemoryMode = true
 }
 
+// WithRadixMode stores words in a radix tree instead of a trie. Runs of
+// characters without branching share a single node, so long words with few
+// common prefixes take a fraction of the memory of the trie.
+//
+// Deprecated: Use WithBackend(BackendRadix).
+func WithRadixMode(c *ServiceConfig) {
+	c.RadixMode = true
+}
+
+// WithBackend selects the data structure storing the words, it takes
+// precedence over the deprecated WithLowMemoryMode and WithRadixMode.
+func WithBackend(b Backend) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.Backend = b
+	}
+}
+
+// WithKeywords adds words to the keywords inserted by New(), so the whole
+// service can be set up through the config, see NewFromConfig().
+func WithKeywords(words []string) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.Keywords = append(c.Keywords, words...)
+	}
+}
+
+// WithSkipInitialInsert skips inserting the keywords passed to New(), use it
+// along with WithStore() when the store has already been populated.
+func WithSkipInitialInsert(c *ServiceConfig) {
+	c.SkipInitialInsert = true
+}
+
+// WithLoadDedupe skips the words already stored when loading data sources or
+// restoring snapshots, so reloading a source doesn't insert its words again.
+// See AutocompleteService.DedupedOnLoad() for the number of words skipped.
+func WithLoadDedupe(c *ServiceConfig) {
+	c.LoadDedupe = true
+}
+
+// WithRecencyTieBreak makes CompleteRanked() break ties by insertion recency,
+// newest first, instead of lexically.
+func WithRecencyTieBreak(c *ServiceConfig) {
+	c.RecencyTieBreak = true
+}
+
+// WithCoverageRanking makes CompleteRanked() favor the completions closest to
+// the prefix, scoring them by len(prefix)/len(word): "bike" ranks above
+// "bicycle repair" for "bi". Weights still rank first, the score only breaks
+// their ties.
+func WithCoverageRanking(c *ServiceConfig) {
+	c.CoverageRanking = true
+}
+
+// WithCaseInsensitive makes "BIK" complete "bike". Words differing only by
+// case are stored once, keeping the casing they were first inserted with.
+func WithCaseInsensitive(c *ServiceConfig) {
+	c.CaseInsensitive = true
+}
+
+// WithWhitespaceCompaction collapses every run of whitespace into a single
+// space, so "dog  park" is stored as "dog park". Prefixes are compacted the
+// same way when completing.
+func WithWhitespaceCompaction(c *ServiceConfig) {
+	c.CompactWhitespace = true
+}
+
+// WithStemming makes Complete() return a single completion for the variants
+// of a word, e.g. "runs" and "running" are completed as "run" when stored, as
+// the shortest variant otherwise. Words are stored as is, the stems are only
+// used to merge the completions. The stemmer only knows about English.
+func WithStemming(c *ServiceConfig) {
+	c.Stemming = true
+}
+
+// WithSubstringIndex builds an index of every suffix of the stored words, so
+// CompleteContains() looks the words up instead of scanning them all.
+//
+// This costs a lot more memory than the store: a word of n characters is
+// indexed under its n suffixes, so the index grows with the square of the
+// word lengths. It's only worth it for short words queried often.
+func WithSubstringIndex(c *ServiceConfig) {
+	c.SubstringIndex = true
+}
+
+// WithHitTracking adds one to the weight of a word every time it is inserted,
+// so the words added or loaded the most are completed first.
+func WithHitTracking(c *ServiceConfig) {
+	c.TrackHits = true
+}
+
+// WithBaseWeight sets the weight ResetWeights() resets every word to,
+// instead of zero.
+func WithBaseWeight(base int) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.BaseWeight = base
+	}
+}
+
+// WithTrending records the time of every insert made through the service,
+// keeping them for retention, so CompleteTrending() can rank the completions
+// by their recent popularity. Every insert costs memory until it expires.
+func WithTrending(retention time.Duration) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.TrendingRetention = retention
+	}
+}
+
+// WithGraphemeClusters stores words by grapheme cluster (user perceived
+// character) instead of by rune, so emoji made of several runes like flags
+// or skin toned emoji are kept whole.
+func WithGraphemeClusters(c *ServiceConfig) {
+	c.GraphemeClusters = true
+}
+
+// WithSnapshotInterval sets the number of seconds between two automatic
+// snapshots. Snapshots also have to be enabled with WithSnapshotsEnabled.
 func WithSnapshotInterval(interval int) ConfigFn {
 	return func(c *ServiceConfig) {
 		c.SnapshotInterval = interval
@@ -72,6 +422,68 @@ func WithDataSources(sources []DataSource) ConfigFn {
 	}
 }
 
+// WithProgressInterval sets how many inserts happen between calls to the
+// progress callback of LoadDataSourceWithProgress.
+func WithProgressInterval(n int) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.ProgressInterval = n
+	}
+}
+
+// WithStore makes the service use an existing store instead of creating a
+// new one, which allows reusing a store between services. Backend is
+// ignored when a store is provided.
+func WithStore(store autocompleter) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.Store = store
+	}
+}
+
+// WithSpillStore keeps the first threshold words in the in-memory store and
+// sends every word inserted after that to secondary, usually a disk backed
+// autocompleter. Completions are merged from both stores.
+func WithSpillStore(secondary autocompleter, threshold int) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.SpillStore = secondary
+		c.SpillThreshold = threshold
+	}
+}
+
+// WithSourceCircuitBreaker skips a data source for cooldown once it has failed
+// to load failures times in a row.
+func WithSourceCircuitBreaker(failures int, cooldown time.Duration) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.BreakerFailures = failures
+		c.BreakerCooldown = cooldown
+	}
+}
+
+// WithSeed makes every randomized operation reproducible, services with
+// the same seed and contents make the same random picks.
+func WithSeed(seed int64) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.Seed = seed
+	}
+}
+
+// WithSlowQueryThreshold logs every Complete() call taking longer than d,
+// along with the prefix length and the number of results.
+func WithSlowQueryThreshold(d time.Duration) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.SlowQueryThreshold = d
+	}
+}
+
+// WithLogger sets the logger the service reports to. Data source loads,
+// snapshots and Close() are logged, failures at the error level along with
+// the error that was added to Errors. Completions only log slow queries, see
+// WithSlowQueryThreshold().
+func WithLogger(l *slog.Logger) ConfigFn {
+	return func(c *ServiceConfig) {
+		c.Logger = l
+	}
+}
+
 /* End Config Functions */
 
 // NewServiceConfig creates a new ServiceConfig instance with
@@ -111,8 +523,14 @@ func defaultConfig() *ServiceConfig {
 		AutomaticUpdates:       false,
 		LoadDataSourcesOnStart: false,
 		LowMemoryMode:          false,
+		ProgressInterval:       1000,
+		Logger:                 discardLogger(),
 
 		SnapshotDest: snapshotDest,
 	}
 
 }
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
@@ -0,0 +1,117 @@
+package autocomplete
+
+import (
+	"context"
+	"fmt"
+)
+
+// contextCheckInterval is the number of nodes visited between two checks of
+// the context, see CompleteContext(). Checking on every node would cost more
+// than the walk itself for the small subtrees.
+const contextCheckInterval = 256
+
+// contextStore is implemented by the stores that can give up collecting the
+// entries for a prefix once a context is done.
+type contextStore interface {
+	// entriesContext behaves like entries, but returns ctx.Err() as soon as
+	// ctx is done.
+	entriesContext(ctx context.Context, prefix string) ([]entry, error)
+}
+
+// storeEntriesContext returns the entries for prefix, see storeEntries(). The
+// stores that can't be interrupted are only checked before the walk.
+func storeEntriesContext(ctx context.Context, store autocompleter, prefix string) ([]entry, error) {
+	if cs, ok := store.(contextStore); ok {
+		return cs.entriesContext(ctx, prefix)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return storeEntries(store, prefix), nil
+}
+
+// checkContext returns a walk check, see trie.walkChecked(), which stops the
+// walk and sets err once ctx is done. ctx is checked on the first node visited
+// and then every contextCheckInterval nodes, so a subtree with few words but
+// long chains of nodes between them is still interrupted.
+func checkContext(ctx context.Context, err *error) func() bool {
+	visited := 0
+	return func() bool {
+		if visited%contextCheckInterval == 0 {
+			if *err = ctx.Err(); *err != nil {
+				return false
+			}
+		}
+		visited++
+		return true
+	}
+}
+
+// collectEntries returns a walk callback appending to results.
+func collectEntries[N any](results *[]entry, data func(N) wordData) func(word string, node N) bool {
+	return func(word string, node N) bool {
+		*results = append(*results, entry{word: word, wordData: data(node)})
+		return true
+	}
+}
+
+func (t *trie) entriesContext(ctx context.Context, prefix string) ([]entry, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	prefix = t.normalize(prefix)
+
+	var results []entry
+	curr := t.prefixNode(prefix)
+	if curr == nil {
+		return results, ctx.Err()
+	}
+
+	var err error
+	t.walkChecked(curr, prefix, checkContext(ctx, &err), collectEntries(&results, func(node *trieNode) wordData { return node.wordData }))
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (t *ternarysearchtree) entriesContext(ctx context.Context, prefix string) ([]entry, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	prefix = t.normalize(prefix)
+
+	var results []entry
+	var err error
+	check := checkContext(ctx, &err)
+	collect := collectEntries(&results, func(node *tstNode) wordData { return node.wordData })
+
+	if prefix == "" {
+		t.walkChecked(t.Root, "", check, collect)
+	} else if node := t.getPrefixNode(t.Root, t.runes(prefix), 0); node == nil {
+		return results, ctx.Err()
+	} else if check() {
+		if node.IsEnd {
+			collect(node.text(prefix), node)
+		}
+		t.walkChecked(node.Mid, prefix, check, collect)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// CompleteContext behaves like Complete, but gives up once ctx is done and
+// returns ctx.Err(), e.g. when the user typed another character before the
+// completions of a short prefix matching a huge subtree were collected. The
+// trie and the ternary search tree backends check ctx while walking the
+// subtree, the other ones only before.
+func (a *AutocompleteService) CompleteContext(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !a.acquire() {
+		return nil, fmt.Errorf("autocompleteservice: completecontext: %w", ErrServiceClosed)
+	}
+	defer a.release()
+	return a.completeQueryContext(ctx, prefix)
+}
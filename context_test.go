@@ -0,0 +1,133 @@
+package autocomplete
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// cancelAfter is a context done after its Err method was called n times, so
+// tests can cancel at a given point of a walk.
+type cancelAfter struct {
+	context.Context
+	n, calls int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.calls++; c.calls > c.n {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestCompleteContext(t *testing.T) {
+	words := benchmarkWords(20000)
+
+	for _, tt := range []struct {
+		name string
+		opts []ConfigFn
+	}{
+		{"trie", nil},
+		{"tst", []ConfigFn{WithBackend(BackendTST)}},
+		{"radix", []ConfigFn{WithBackend(BackendRadix)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(t, words, append(tt.opts, WithSuggestionCache(4))...)
+
+			results, err := service.CompleteContext(context.Background(), "")
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			assertWords(t, service.Complete(""), results)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := service.CompleteContext(ctx, "b"); !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected %v, got %v", context.Canceled, err)
+			}
+			// Nothing of the cancelled query is cached.
+			assertWords(t, service.Complete("b"), storeWords(service, "b"))
+		})
+	}
+
+	service := testService(t, nil)
+	service.Close()
+	if _, err := service.CompleteContext(context.Background(), "b"); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+}
+
+// storeWords returns the completions for prefix straight from the store.
+func storeWords(service *AutocompleteService, prefix string) []string {
+	return service.completions(storeEntries(service.store, prefix))
+}
+
+func TestCompleteContextCancelMidWalk(t *testing.T) {
+	words := benchmarkWords(20000)
+
+	for _, tt := range []struct {
+		name  string
+		store contextStore
+		nodes func(store contextStore) int
+	}{
+		{"trie", newTrie(), func(store contextStore) int {
+			nodes := 0
+			trie := store.(*trie)
+			trie.walkNodes(trie.Root, "", func(*trieNode, string) error {
+				nodes++
+				return nil
+			})
+			return nodes
+		}},
+		{"tst", newTernarySearchTree(""), func(store contextStore) int {
+			return store.(*ternarysearchtree).NodeCount()
+		}},
+	} {
+		tt.store.(autocompleter).InsertBatch(words)
+
+		// Done on the third check, after two intervals of nodes.
+		ctx := &cancelAfter{Context: context.Background(), n: 2}
+		results, err := tt.store.entriesContext(ctx, "")
+		if !errors.Is(err, context.Canceled) || results != nil {
+			t.Errorf("%s: Expected %v and no results, got %v and %d results", tt.name, context.Canceled, err, len(results))
+		}
+		if ctx.calls != 3 {
+			t.Errorf("%s: Expected the walk to stop on the third check, got %d checks", tt.name, ctx.calls)
+		}
+
+		ctx = &cancelAfter{Context: context.Background(), n: len(words)}
+		results, err = tt.store.entriesContext(ctx, "")
+		if err != nil || len(results) != len(words) {
+			t.Errorf("%s: Expected %d results, got %d and %v", tt.name, len(words), len(results), err)
+		}
+		// Checked every contextCheckInterval nodes only.
+		if want := (tt.nodes(tt.store)-1)/contextCheckInterval + 1; ctx.calls != want {
+			t.Errorf("%s: Expected %d checks, got %d", tt.name, want, ctx.calls)
+		}
+	}
+}
+
+func TestCompleteContextCancelLongWords(t *testing.T) {
+	// Few words, but thousands of nodes to walk between them.
+	words := []string{strings.Repeat("a", 2000), strings.Repeat("b", 2000)}
+
+	for _, tt := range []struct {
+		name  string
+		store contextStore
+	}{
+		{"trie", newTrie()},
+		{"tst", newTernarySearchTree("")},
+	} {
+		tt.store.(autocompleter).InsertBatch(words)
+
+		ctx := &cancelAfter{Context: context.Background(), n: 1}
+		results, err := tt.store.entriesContext(ctx, "")
+		if !errors.Is(err, context.Canceled) || results != nil {
+			t.Errorf("%s: Expected %v and no results, got %v and %d results", tt.name, context.Canceled, err, len(results))
+		}
+		if ctx.calls != 2 {
+			t.Errorf("%s: Expected the walk to stop on the second check, got %d checks", tt.name, ctx.calls)
+		}
+	}
+}
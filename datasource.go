@@ -1,17 +1,29 @@
 package autocomplete
 
 import (
+	"archive/tar"
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/go-github/v53/github"
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/oauth2/google"
 )
 
@@ -22,6 +34,12 @@ import (
 // - LocalFileProvider - Allows you to read and write data from a local file.
 // - GoogleStorageBucketProvider - Allows you to read and write data from a Google Cloud Storage bucket.
 // - GithubProvider - Allows you to read and write data from a Github repository.
+// - TarProvider - Allows you to read data from the files of a tar archive.
+// - HTTPProvider - Allows you to read and write data from a HTTP(S) server.
+// - InMemoryProvider - Allows you to read and write data from a byte slice, handy for tests.
+// - RedisProvider - Allows you to read and write data from a Redis key, shared between services.
+// - SQLProvider - Allows you to read and write data from the rows of a SQL database.
+// - S3Provider - Allows you to read and write data from an AWS S3 bucket.
 
 // DataProvider is an interface that allows a DataSource of some kind, to be used
 // to update the data inside of our AutoCompleterService store or export the data from the
@@ -32,6 +50,51 @@ type DataProvider interface {
 	Close() error
 }
 
+// ContextDataProvider is a DataProvider whose reads and writes can be cancelled
+// through a context, e.g. to put a timeout on a slow remote source. The service
+// uses the context aware methods whenever a provider implements them, see
+// AutocompleteService.LoadDataSourcesContext().
+//
+// The included providers implement it, their ReadData and DumpData methods use
+// context.Background().
+type ContextDataProvider interface {
+	DataProvider
+	ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error
+	DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error
+}
+
+// ErrNilFormatter is returned by the included providers when they are given a
+// nil formatter. The service never passes one, a data source without a
+// formatter is read and written with DefaultFormat.
+var ErrNilFormatter = errors.New("datasource: nil formatter")
+
+// readData reads src into store, through ReadDataContext() when the provider
+// supports it. Other providers can't be interrupted once started.
+func readData(ctx context.Context, src DataSource, store PublicProviderStore) error {
+	if p, ok := src.Provider.(ContextDataProvider); ok {
+		return p.ReadDataContext(ctx, src.Filepath, store, src.formatter())
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return src.Provider.ReadData(src.Filepath, store, src.formatter())
+}
+
+// insertKeywords inserts the keywords into store, stopping early with the
+// context error once ctx is done.
+func insertKeywords(ctx context.Context, store PublicProviderStore, keywords []string) error {
+	for i, keyword := range keywords {
+		// Checking on every insert would slow down large loads.
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		store.Insert(keyword)
+	}
+	return ctx.Err()
+}
+
 // By implementing this interface the user can mock their store when testing their custom
 // providers. This allows us to keep the autocomplete interface private. While at the time
 // this also satisfies the interface of our AutoCompleterService store which is what will
@@ -74,6 +137,32 @@ func NewDataSource(provider DataProvider, fmtr Formatter, filepath string, url s
 	}
 }
 
+// ErrNilProvider is returned by DataSource.Validate() for a data source
+// without a provider.
+var ErrNilProvider = errors.New("datasource: nil provider")
+
+// Validate reports whether the data source can be used: it needs a provider,
+// and a file path when the provider is a LocalFileProvider. A nil Formatter is
+// valid, the data source is read and written with DefaultFormat.
+func (d DataSource) Validate() error {
+	if d.Provider == nil {
+		return ErrNilProvider
+	}
+	if _, ok := d.Provider.(*LocalFileProvider); ok && d.Filepath == "" {
+		return errors.New("datasource localfileprovider: empty file path")
+	}
+	return nil
+}
+
+// formatter returns the formatter of the data source, DefaultFormat when none
+// is set.
+func (d DataSource) formatter() Formatter {
+	if d.Formatter == nil {
+		return DefaultFormat{}
+	}
+	return d.Formatter
+}
+
 // The Github provider allows a user to read and write data directly from a Github repository.
 // Because we are using the Github client for golang this requires that you have a valid access
 // token for the repositories that you're using as providers. UNLESS you are using the SourceOnly
@@ -160,6 +249,9 @@ func (g *GithubProvider) Close() error {
 }
 
 func (g *GithubProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -194,6 +286,9 @@ func (g *GithubProvider) ReadData(fileName string, store PublicProviderStore, fm
 }
 
 func (g *GithubProvider) DumpData(msg, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -312,14 +407,20 @@ func (g *GoogleStorageBucketProvider) Close() error {
 }
 
 func (g *GoogleStorageBucketProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return g.ReadDataContext(context.Background(), fileName, store, fmtr)
+}
+
+// ReadDataContext reads the object, DefaultTimeout still applies on top of ctx.
+func (g *GoogleStorageBucketProvider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	if g.client == nil {
 		return errors.New("datasource googlestoragebucketprovider: cannot read from google storage without a valid client.")
 	}
 	// Creates a local scope for cancellation..
-	// TODO: In the future consider passing contexts and return contexts instead.
-	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, g.DefaultTimeout)
 
 	defer cancel()
@@ -339,21 +440,24 @@ func (g *GoogleStorageBucketProvider) ReadData(fileName string, store PublicProv
 		return err
 	}
 
-	for _, keyword := range keywords {
-		store.Insert(keyword)
-	}
-
-	return nil
+	return insertKeywords(ctx, store, keywords)
 }
 
 func (g *GoogleStorageBucketProvider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return g.DumpDataContext(context.Background(), fileName, store, fmtr)
+}
+
+// DumpDataContext writes the object, DefaultTimeout still applies on top of ctx.
+func (g *GoogleStorageBucketProvider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	if g.client == nil {
 		return errors.New("datasource googlestoragebucketprovider: cannot read from google storage without a valid client.")
 	}
 
-	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, g.DefaultTimeout)
 	defer cancel()
 
@@ -395,51 +499,88 @@ func NewLocalFileProvider(fileName string) (*LocalFileProvider, error) {
 }
 
 func (l *LocalFileProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return l.ReadDataContext(context.Background(), fileName, store, fmtr)
+}
+
+// ReadDataContext reads Filename, fileName is only used to pick the format.
+//
+// The whole file is formatted at once, formatting it in chunks would split
+// keywords (and JSON documents) across chunks. A StreamingFormatter is given
+// the open file instead, so the file is never held in memory.
+func (l *LocalFileProvider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	var err error
-	l.File, err = os.OpenFile(l.Filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-	if err != nil {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	defer l.Close()
+	if sf, ok := fmtr.(StreamingFormatter); ok {
+		return l.readStream(ctx, fileName, store, sf)
+	}
 
-	chunk := make([]byte, 1<<10)
+	// Read through a separate handle, l.File is only used by writes.
+	byts, err := os.ReadFile(l.Filename)
+	if err != nil {
+		return err
+	}
 
-	for {
-		n, err := l.Read(chunk)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
+	keywords, err := fmtr.FormatRead(byts, fileName)
+	if err != nil {
+		return err
+	}
 
-		keywords, err := fmtr.FormatRead(chunk[:n], fileName)
-		if err != nil {
-			return err
-		}
+	return insertKeywords(ctx, store, keywords)
+}
 
-		for _, keyword := range keywords {
-			store.Insert(keyword)
-		}
+// readStream streams Filename through sf, the caller must hold the read lock.
+func (l *LocalFileProvider) readStream(ctx context.Context, fileName string, store PublicProviderStore, sf StreamingFormatter) error {
+	f, err := os.Open(l.Filename)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	defer f.Close()
+
+	n := 0
+	return sf.FormatReadStream(f, fileName, func(keyword string) error {
+		// Same as insertKeywords(), checking on every insert is too slow.
+		if n%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		n++
+		store.Insert(keyword)
+		return nil
+	})
 }
 
 func (l *LocalFileProvider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return l.DumpDataContext(context.Background(), fileName, store, fmtr)
+}
+
+// DumpDataContext writes Filename, fileName is only used to pick the format.
+func (l *LocalFileProvider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	var err error
 	l.File, err = os.OpenFile(l.Filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
 	if err != nil {
 		return err
 	}
-	defer l.Close()
+	// We're already holding the lock, so we can't go through Close().
+	defer l.closeFile()
 
 	contents := store.ListContents()
 
@@ -452,6 +593,17 @@ func (l *LocalFileProvider) DumpData(fileName string, store PublicProviderStore,
 	return err
 }
 
+// closeFile closes the file opened by the current operation, the caller
+// must hold the lock.
+func (l *LocalFileProvider) closeFile() error {
+	if l.File == nil {
+		return nil
+	}
+	err := l.File.Close()
+	l.File = nil
+	return err
+}
+
 // My thought here is if the AutocompleteService.Close() is called while a write
 // or read operation is currently in progress. We can go ahead and shut it down.
 func (l *LocalFileProvider) Close() error {
@@ -465,3 +617,723 @@ func (l *LocalFileProvider) Close() error {
 
 	return l.File.Close()
 }
+
+// TarProvider reads the keywords of every file in a tar archive, optionally
+// gzip compressed. Every file is run through the formatter based on its own
+// name, so an archive can mix JSON, txt, etc. files.
+//
+// The provider is read only, DumpData always returns an error.
+type TarProvider struct {
+	Path string
+	// Formatter is used for every file of the archive instead of the
+	// formatter of the DataSource when set.
+	Formatter Formatter
+}
+
+func NewTarProvider(path string, formatter Formatter) (*TarProvider, error) {
+	return &TarProvider{Path: path, Formatter: formatter}, nil
+}
+
+func (t *TarProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return t.ReadDataContext(context.Background(), fileName, store, fmtr)
+}
+
+// ReadDataContext reads the archive at Path, fileName is not used as every file
+// of the archive has its own name.
+func (t *TarProvider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if t.Formatter != nil {
+		fmtr = t.Formatter
+	}
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+
+	f, err := os.Open(t.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Peek at the gzip magic number to support both .tar and .tar.gz.
+	rdr := bufio.NewReader(f)
+	var src io.Reader = rdr
+	if magic, _ := rdr.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(rdr)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	tr := tar.NewReader(src)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		byts, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+
+		keywords, err := fmtr.FormatRead(byts, hdr.Name)
+		if err != nil {
+			return fmt.Errorf("datasource tarprovider: %s: %w", hdr.Name, err)
+		}
+
+		if err := insertKeywords(ctx, store, keywords); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *TarProvider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return t.DumpDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (t *TarProvider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+	return errors.New("datasource tarprovider: tar archives are read only.")
+}
+
+// Close is a no-op, the archive is only open while reading.
+func (t *TarProvider) Close() error {
+	return nil
+}
+
+// DirectoryProvider reads the keywords of every file in a directory, in
+// lexical order. Every file is run through the formatter based on its own
+// name, so a directory can mix JSON, txt, etc. files.
+//
+// The provider is read only, DumpData always returns an error.
+type DirectoryProvider struct {
+	Path string
+	// Pattern selects the files to read by name, e.g. "*.json", see
+	// filepath.Match. Every file is read when empty.
+	Pattern string
+	// Recursive also reads the files of the subdirectories, which are
+	// skipped otherwise.
+	Recursive bool
+}
+
+func NewDirectoryProvider(path, pattern string, recursive bool) (*DirectoryProvider, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("datasource directoryprovider: %w", err)
+	}
+	return &DirectoryProvider{Path: path, Pattern: pattern, Recursive: recursive}, nil
+}
+
+func (d *DirectoryProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return d.ReadDataContext(context.Background(), fileName, store, fmtr)
+}
+
+// ReadDataContext reads the files of the directory at Path, fileName is not
+// used as every file has its own name. The first file failing to read stops
+// the load, the files read before it are kept in the store.
+func (d *DirectoryProvider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+
+	return filepath.WalkDir(d.Path, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if name != d.Path && !d.Recursive {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if d.Pattern != "" {
+			if ok, _ := filepath.Match(d.Pattern, entry.Name()); !ok {
+				return nil
+			}
+		}
+
+		// Read through a LocalFileProvider for the StreamingFormatter support.
+		file := &LocalFileProvider{Filename: name}
+		if err := file.ReadDataContext(ctx, name, store, fmtr); err != nil {
+			return fmt.Errorf("datasource directoryprovider: %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+func (d *DirectoryProvider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return d.DumpDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (d *DirectoryProvider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+	return errors.New("datasource directoryprovider: directories are read only.")
+}
+
+// Close is a no-op, the files are only open while reading.
+func (d *DirectoryProvider) Close() error {
+	return nil
+}
+
+// HTTPProvider reads keywords from a web server with a GET, the file path of
+// the data source being the URL. The file name used to pick the format is the
+// last element of the URL path, e.g. keywords.json for
+// https://example.com/keywords.json?v=2.
+//
+// Writes are disabled unless DumpMethod is set, in which case DumpData sends the
+// formatted keywords to the URL as the body of a PUT or POST request. Any non
+// 2xx response is returned as an error.
+type HTTPProvider struct {
+	// Client defaults to a client using Timeout.
+	Client *http.Client
+	// Timeout is the time limit of every request, 30 seconds by default.
+	Timeout time.Duration
+	// DumpMethod is http.MethodPut or http.MethodPost, leave empty to make the
+	// provider read only.
+	DumpMethod string
+	// UserAgent defaults to "autocomplete".
+	UserAgent string
+	// Header is added to every request, e.g. for authorization.
+	Header http.Header
+}
+
+// Pass 0 for timeout if you wish to use a default timeout.
+func NewHTTPProvider(timeout time.Duration) (*HTTPProvider, error) {
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	return &HTTPProvider{
+		Client:    &http.Client{Timeout: timeout},
+		Timeout:   timeout,
+		UserAgent: SERVICE_NAME,
+	}, nil
+}
+
+func (h *HTTPProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return h.ReadDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (h *HTTPProvider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+	formatName, err := urlFileName(fileName)
+	if err != nil {
+		return err
+	}
+
+	byts, err := h.do(ctx, http.MethodGet, fileName, nil)
+	if err != nil {
+		return err
+	}
+
+	keywords, err := fmtr.FormatRead(byts, formatName)
+	if err != nil {
+		return err
+	}
+
+	return insertKeywords(ctx, store, keywords)
+}
+
+func (h *HTTPProvider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return h.DumpDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (h *HTTPProvider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+	if h.DumpMethod == "" {
+		return errors.New("datasource httpprovider: no dump method set, the provider is read only.")
+	}
+
+	formatName, err := urlFileName(fileName)
+	if err != nil {
+		return err
+	}
+
+	content, err := fmtr.FormatWrite(store.ListContents(), formatName)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.do(ctx, h.DumpMethod, fileName, content)
+	return err
+}
+
+// do sends a request to rawURL and returns the response body.
+func (h *HTTPProvider) do(ctx context.Context, method, rawURL string, body []byte) ([]byte, error) {
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: h.Timeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("datasource httpprovider: %w", err)
+	}
+	for key, values := range h.Header {
+		req.Header[key] = values
+	}
+	userAgent := h.UserAgent
+	if userAgent == "" {
+		userAgent = SERVICE_NAME
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", http.DetectContentType(body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("datasource httpprovider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	byts, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("datasource httpprovider: %s %s: %w", method, rawURL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("datasource httpprovider: %s %s: unexpected status %s", method, rawURL, resp.Status)
+	}
+	return byts, nil
+}
+
+// urlFileName returns the last element of the path of rawURL.
+func urlFileName(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("datasource httpprovider: %w", err)
+	}
+	return path.Base(u.Path), nil
+}
+
+// Close is a no-op, connections are handled by the client.
+func (h *HTTPProvider) Close() error {
+	return nil
+}
+
+// InMemoryProvider reads and writes a byte slice instead of a file, both going
+// through the formatter like any other provider. This is handy to round trip
+// snapshots and exports in tests without touching the filesystem.
+//
+// The file name is only used to pick the format.
+type InMemoryProvider struct {
+	// Data holds the last dumped data, use Bytes() while the provider is in use.
+	Data []byte
+
+	mu sync.RWMutex
+}
+
+// NewInMemoryProvider creates a provider reading data, which may be nil.
+func NewInMemoryProvider(data []byte) (*InMemoryProvider, error) {
+	return &InMemoryProvider{Data: data}, nil
+}
+
+// Bytes returns a copy of the data held by the provider.
+func (m *InMemoryProvider) Bytes() []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]byte(nil), m.Data...)
+}
+
+func (m *InMemoryProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return m.ReadDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (m *InMemoryProvider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keywords, err := fmtr.FormatRead(m.Data, fileName)
+	if err != nil {
+		return err
+	}
+
+	return insertKeywords(ctx, store, keywords)
+}
+
+func (m *InMemoryProvider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return m.DumpDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (m *InMemoryProvider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	content, err := fmtr.FormatWrite(store.ListContents(), fileName)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Data = content
+	return nil
+}
+
+// Close is a no-op, the data is kept so it can still be inspected.
+func (m *InMemoryProvider) Close() error {
+	return nil
+}
+
+// RedisProvider reads and writes the keywords stored at a Redis key, so
+// several service instances can share the same keyword source.
+//
+// The key either holds a string, read and written through the formatter like a
+// file, or a set whose members are the keywords. The file name is only used to
+// pick the format of a string key.
+type RedisProvider struct {
+	Client *redis.Client
+	Key    string
+
+	closed bool
+	mu     sync.Mutex
+}
+
+// NewRedisProvider creates a provider for key on the Redis server at addr, e.g.
+// "localhost:6379". Connecting is deferred to the first read or write, which
+// returns the connection errors.
+func NewRedisProvider(addr, key string) (*RedisProvider, error) {
+	if key == "" {
+		return nil, errors.New("datasource redisprovider: empty key")
+	}
+	return &RedisProvider{Client: redis.NewClient(&redis.Options{Addr: addr}), Key: key}, nil
+}
+
+func (r *RedisProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return r.ReadDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (r *RedisProvider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+
+	typ, err := r.Client.Type(ctx, r.Key).Result()
+	if err != nil {
+		return fmt.Errorf("datasource redisprovider: %w", err)
+	}
+
+	var keywords []string
+	switch typ {
+	case "string":
+		byts, err := r.Client.Get(ctx, r.Key).Bytes()
+		if err != nil {
+			return fmt.Errorf("datasource redisprovider: %w", err)
+		}
+		keywords, err = fmtr.FormatRead(byts, fileName)
+		if err != nil {
+			return err
+		}
+	case "set":
+		keywords, err = r.Client.SMembers(ctx, r.Key).Result()
+		if err != nil {
+			return fmt.Errorf("datasource redisprovider: %w", err)
+		}
+	case "none":
+		return fmt.Errorf("datasource redisprovider: key %q does not exist", r.Key)
+	default:
+		return fmt.Errorf("datasource redisprovider: key %q holds an unsupported %s", r.Key, typ)
+	}
+
+	return insertKeywords(ctx, store, keywords)
+}
+
+func (r *RedisProvider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return r.DumpDataContext(context.Background(), fileName, store, fmtr)
+}
+
+// DumpDataContext replaces the members of the key when it holds a set, and
+// sets it to the formatted keywords otherwise.
+func (r *RedisProvider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+
+	typ, err := r.Client.Type(ctx, r.Key).Result()
+	if err != nil {
+		return fmt.Errorf("datasource redisprovider: %w", err)
+	}
+
+	keywords := store.ListContents()
+	if typ == "set" {
+		members := make([]interface{}, len(keywords))
+		for i, keyword := range keywords {
+			members[i] = keyword
+		}
+		_, err = r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, r.Key)
+			if len(members) > 0 {
+				pipe.SAdd(ctx, r.Key, members...)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("datasource redisprovider: %w", err)
+		}
+		return nil
+	}
+
+	content, err := fmtr.FormatWrite(keywords, fileName)
+	if err != nil {
+		return err
+	}
+	if err := r.Client.Set(ctx, r.Key, content, 0).Err(); err != nil {
+		return fmt.Errorf("datasource redisprovider: %w", err)
+	}
+	return nil
+}
+
+// Close closes the client connections, it is safe to call more than once.
+func (r *RedisProvider) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed || r.Client == nil {
+		return nil
+	}
+	if err := r.Client.Close(); err != nil {
+		return err
+	}
+	r.closed = true
+	return nil
+}
+
+// ErrNilDB is returned by SQLProvider when it has no database to query.
+var ErrNilDB = errors.New("datasource sqlprovider: nil *sql.DB")
+
+// SQLProvider reads and writes keywords from the rows of a database/sql
+// database, e.g. a Postgres table.
+//
+// The data is already structured into rows, so the file name and the formatter
+// passed to ReadData and DumpData are ignored, a nil formatter is fine.
+type SQLProvider struct {
+	DB *sql.DB
+	// Query selects the keywords, only the first column of every row is read
+	// and NULL values are skipped.
+	Query string
+	// UpsertStatement is executed once for every keyword by DumpData, with the
+	// keyword as its only argument, e.g.
+	//
+	//	INSERT INTO keywords (word) VALUES ($1) ON CONFLICT DO NOTHING
+	//
+	// Leave empty to make the provider read only.
+	UpsertStatement string
+}
+
+// NewSQLProvider creates a provider loading the keywords selected by query. The
+// database is owned by the caller, Close() doesn't close it.
+func NewSQLProvider(db *sql.DB, query string) (*SQLProvider, error) {
+	if db == nil {
+		return nil, ErrNilDB
+	}
+	return &SQLProvider{DB: db, Query: query}, nil
+}
+
+func (s *SQLProvider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return s.ReadDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (s *SQLProvider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if s.DB == nil {
+		return ErrNilDB
+	}
+
+	rows, err := s.DB.QueryContext(ctx, s.Query)
+	if err != nil {
+		return fmt.Errorf("datasource sqlprovider: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("datasource sqlprovider: %w", err)
+	}
+	if len(columns) == 0 {
+		return errors.New("datasource sqlprovider: the query returned no columns")
+	}
+
+	// Only the first column is kept, the others are scanned into dest and
+	// discarded.
+	var keyword sql.NullString
+	dest := make([]any, len(columns))
+	dest[0] = &keyword
+	for i := 1; i < len(dest); i++ {
+		dest[i] = new(sql.RawBytes)
+	}
+
+	var keywords []string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("datasource sqlprovider: %w", err)
+		}
+		if keyword.Valid {
+			keywords = append(keywords, keyword.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("datasource sqlprovider: %w", err)
+	}
+
+	return insertKeywords(ctx, store, keywords)
+}
+
+func (s *SQLProvider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return s.DumpDataContext(context.Background(), fileName, store, fmtr)
+}
+
+// DumpDataContext upserts every keyword within a single transaction, which is
+// rolled back if any of them fails.
+func (s *SQLProvider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if s.DB == nil {
+		return ErrNilDB
+	}
+	if s.UpsertStatement == "" {
+		return errors.New("datasource sqlprovider: no upsert statement set, the provider is read only.")
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("datasource sqlprovider: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, s.UpsertStatement)
+	if err != nil {
+		return fmt.Errorf("datasource sqlprovider: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, keyword := range store.ListContents() {
+		if _, err := stmt.ExecContext(ctx, keyword); err != nil {
+			return fmt.Errorf("datasource sqlprovider: %q: %w", keyword, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("datasource sqlprovider: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op, the database belongs to the caller.
+func (s *SQLProvider) Close() error {
+	return nil
+}
+
+// S3API is the part of *s3.Client used by S3Provider, so it can be replaced by
+// a stub in tests.
+type S3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// S3Provider reads and writes the objects of an AWS S3 bucket, the file name
+// being the object key. The region and the credentials are the ones the client
+// was created with.
+type S3Provider struct {
+	Bucket string
+	Client S3API
+}
+
+// NewS3Provider creates a provider for bucket, client is usually created with
+// s3.NewFromConfig().
+func NewS3Provider(bucket string, client S3API) (*S3Provider, error) {
+	if bucket == "" {
+		return nil, errors.New("datasource s3provider: empty bucket name")
+	}
+	if client == nil {
+		return nil, errors.New("datasource s3provider: nil client")
+	}
+	return &S3Provider{Bucket: bucket, Client: client}, nil
+}
+
+func (p *S3Provider) ReadData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return p.ReadDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (p *S3Provider) ReadDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+
+	out, err := p.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(p.Bucket),
+		Key:    aws.String(fileName),
+	})
+	if err != nil {
+		return fmt.Errorf("datasource s3provider: %w", err)
+	}
+	defer out.Body.Close()
+
+	byts, err := io.ReadAll(out.Body)
+	if err != nil {
+		return fmt.Errorf("datasource s3provider: %s: %w", fileName, err)
+	}
+
+	keywords, err := fmtr.FormatRead(byts, fileName)
+	if err != nil {
+		return err
+	}
+
+	return insertKeywords(ctx, store, keywords)
+}
+
+func (p *S3Provider) DumpData(fileName string, store PublicProviderStore, fmtr Formatter) error {
+	return p.DumpDataContext(context.Background(), fileName, store, fmtr)
+}
+
+func (p *S3Provider) DumpDataContext(ctx context.Context, fileName string, store PublicProviderStore, fmtr Formatter) error {
+	if fmtr == nil {
+		return ErrNilFormatter
+	}
+
+	content, err := fmtr.FormatWrite(store.ListContents(), fileName)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(p.Bucket),
+		Key:           aws.String(fileName),
+		Body:          bytes.NewReader(content),
+		ContentLength: aws.Int64(int64(len(content))),
+	})
+	if err != nil {
+		return fmt.Errorf("datasource s3provider: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op, the client has no connections to close.
+func (p *S3Provider) Close() error {
+	return nil
+}
@@ -0,0 +1,562 @@
+package autocomplete
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// testTarFile writes an archive with the given files, gzipped when compress is set.
+func testTarFile(t *testing.T, files map[string]string, compress bool) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keywords.tar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	if compress {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	if err := tw.WriteHeader(&tar.Header{Name: "keywords/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	for name, contents := range files {
+		hdr := &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(contents))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if _, err := tw.Write([]byte(contents)); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+	}
+	return path
+}
+
+func TestTarProvider(t *testing.T) {
+	var _ DataProvider = (*TarProvider)(nil)
+
+	files := map[string]string{
+		"keywords/places.json": `["beach","pool"]`,
+		"keywords/bikes.txt":   "bike\nbike path\n",
+	}
+
+	for _, compress := range []bool{false, true} {
+		provider, err := NewTarProvider(testTarFile(t, files, compress), DefaultFormat{})
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		store := newTrie()
+		if err := provider.ReadData("", store, nil); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		contents := store.ListContents()
+		sort.Strings(contents)
+		assertWords(t, []string{"beach", "bike", "bike path", "pool"}, contents)
+
+		if err := provider.DumpData("", store, nil); err == nil {
+			t.Errorf("Expected non-nil, got %v", err)
+		}
+	}
+
+	t.Run("unsupported entry", func(t *testing.T) {
+		path := testTarFile(t, map[string]string{"README": "not keywords"}, false)
+		provider, _ := NewTarProvider(path, nil)
+		if err := provider.ReadData("", newTrie(), DefaultFormat{}); err == nil {
+			t.Errorf("Expected non-nil, got %v", err)
+		}
+	})
+}
+
+func TestDirectoryProvider(t *testing.T) {
+	var _ ContextDataProvider = (*DirectoryProvider)(nil)
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"places.json":        `["beach","pool"]`,
+		"bikes.txt":          "bike\nbike path\n",
+		"parks.csv":          "dog park,skate park",
+		"nested/trails.json": `["bike trail"]`,
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name      string
+		pattern   string
+		recursive bool
+		want      []string
+	}{
+		{"every file", "", false, []string{"beach", "bike", "bike path", "dog park", "pool", "skate park"}},
+		{"pattern", "*.json", false, []string{"beach", "pool"}},
+		{"recursive", "*.json", true, []string{"beach", "bike trail", "pool"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewDirectoryProvider(dir, tt.pattern, tt.recursive)
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+
+			store := newTrie()
+			if err := provider.ReadData(dir, store, DefaultFormat{}); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			assertWords(t, tt.want, store.ListContents())
+
+			if err := provider.DumpData(dir, store, DefaultFormat{}); err == nil {
+				t.Errorf("Expected non-nil, got %v", err)
+			}
+		})
+	}
+
+	t.Run("unsupported file", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not keywords"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		provider, _ := NewDirectoryProvider(dir, "", false)
+		if err := provider.ReadData(dir, newTrie(), DefaultFormat{}); err == nil || !strings.Contains(err.Error(), "README") {
+			t.Errorf("Expected an error naming README, got %v", err)
+		}
+	})
+
+	if _, err := NewDirectoryProvider(dir, "[", false); err == nil {
+		t.Errorf("Expected an error for a malformed pattern")
+	}
+}
+
+func TestLocalFileProvider(t *testing.T) {
+	var _ ContextDataProvider = (*LocalFileProvider)(nil)
+
+	path := filepath.Join(t.TempDir(), "keywords.json")
+	provider, _ := NewLocalFileProvider(path)
+
+	words := []string{"beach", "bike", "bike path", "pool"}
+	src := newTrie()
+	for _, word := range words {
+		src.Insert(word)
+	}
+	if err := provider.DumpData(path, src, DefaultFormat{}); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	// Reading twice makes sure reads leave the file intact.
+	for i := 0; i < 2; i++ {
+		store := newTrie()
+		if err := provider.ReadData(path, store, DefaultFormat{}); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		assertWords(t, words, store.ListContents())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store := newTrie()
+	if err := provider.ReadDataContext(ctx, path, store, DefaultFormat{}); err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	if store.Count() != 0 {
+		t.Errorf("Expected 0 words, got %d", store.Count())
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	var _ ContextDataProvider = (*HTTPProvider)(nil)
+
+	var mu sync.Mutex
+	var uploaded []byte
+	var userAgent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		userAgent = r.UserAgent()
+		switch {
+		case r.URL.Path != "/keywords.json":
+			http.NotFound(w, r)
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`["beach","bike","pool"]`))
+		case r.Method == http.MethodPut:
+			uploaded, _ = io.ReadAll(r.Body)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer srv.Close()
+
+	provider, _ := NewHTTPProvider(0)
+	service := testService(t, nil)
+	src := NewDataSource(provider, DefaultFormat{}, srv.URL+"/keywords.json?v=2", "")
+	if err := service.LoadDataSource(*src); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"beach", "bike", "pool"}, service.GetContents())
+	if userAgent != SERVICE_NAME {
+		t.Errorf("Expected %q, got %q", SERVICE_NAME, userAgent)
+	}
+
+	t.Run("dump", func(t *testing.T) {
+		if err := service.ExportToDataSource(*src); err == nil {
+			t.Errorf("Expected a read only error, got %v", err)
+		}
+
+		provider.DumpMethod = http.MethodPut
+		defer func() { provider.DumpMethod = "" }()
+		if err := service.ExportToDataSource(*src); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if string(uploaded) != `["beach","bike","pool"]` {
+			t.Errorf("Expected the keywords, got %s", uploaded)
+		}
+	})
+
+	t.Run("non 2xx", func(t *testing.T) {
+		errs := len(service.Errors)
+		missing := NewDataSource(provider, DefaultFormat{}, srv.URL+"/missing.json", "")
+		if err := service.LoadDataSource(*missing); err == nil {
+			t.Errorf("Expected non-nil, got %v", err)
+		}
+		if len(service.Errors) != errs+1 {
+			t.Errorf("Expected the error to be added to the service")
+		}
+	})
+}
+
+func TestNilFormatter(t *testing.T) {
+	provider, _ := NewInMemoryProvider([]byte(`["beach","bike"]`))
+
+	// Built without NewDataSource, so no formatter is filled in.
+	src := DataSource{Provider: provider, Filepath: "keywords.json"}
+	service := testService(t, nil)
+	if err := service.LoadDataSource(src); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"beach", "bike"}, service.GetContents())
+
+	if err := service.ExportToDataSource(src); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	// Providers called directly report it instead of panicking.
+	if err := provider.ReadData("keywords.json", newTrie(), nil); !errors.Is(err, ErrNilFormatter) {
+		t.Errorf("Expected %v, got %v", ErrNilFormatter, err)
+	}
+	if err := provider.DumpData("keywords.json", newTrie(), nil); !errors.Is(err, ErrNilFormatter) {
+		t.Errorf("Expected %v, got %v", ErrNilFormatter, err)
+	}
+}
+
+func TestDataSourceValidate(t *testing.T) {
+	local, _ := NewLocalFileProvider(filepath.Join(t.TempDir(), "keywords.json"))
+
+	if err := (DataSource{Filepath: "keywords.json"}).Validate(); !errors.Is(err, ErrNilProvider) {
+		t.Errorf("Expected %v, got %v", ErrNilProvider, err)
+	}
+	if err := (DataSource{Provider: local}).Validate(); err == nil {
+		t.Errorf("Expected an error for an empty file path")
+	}
+	// Read and written with DefaultFormat.
+	if err := (DataSource{Provider: local, Filepath: local.Filename}).Validate(); err != nil {
+		t.Errorf("Expected a nil formatter to be valid, got %v", err)
+	}
+	// Only local files need a path.
+	if err := (DataSource{Provider: &mockProvider{}}).Validate(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		opts     []ConfigFn
+		expected string
+	}{
+		{"nil provider", []ConfigFn{WithDataSources([]DataSource{
+			*NewDataSource(&mockProvider{}, nil, "first.json", ""),
+			{Formatter: DefaultFormat{}, Filepath: "second.json"},
+		})}, `data source 1 ("second.json")`},
+		{"empty path", []ConfigFn{WithDataSources([]DataSource{{Provider: local}})}, `data source 0 ("")`},
+		{"snapshot destination", []ConfigFn{WithSnapshotDest(DataSource{Provider: local})}, "snapshot destination"},
+	} {
+		_, err := New(NewServiceConfig(tt.opts...), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: Expected an error naming %s, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestLocalFileProviderStreaming(t *testing.T) {
+	var _ StreamingFormatter = (*LineFormat)(nil)
+
+	// Longer than the default bufio.Scanner buffer, and no trailing newline.
+	long := strings.Repeat("a", bufio.MaxScanTokenSize*2)
+	path := filepath.Join(t.TempDir(), "keywords.list")
+	if err := os.WriteFile(path, []byte("beach\r\nbike\n\npool\n"+long), 0644); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	provider, _ := NewLocalFileProvider(path)
+
+	store := newTrie()
+	if err := provider.ReadData(path, store, LineFormat{}); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{long, "beach", "bike", "pool"}, store.ListContents())
+
+	if err := provider.ReadData(path, newTrie(), LineFormat{MaxLineLength: 1024}); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("Expected %v, got %v", bufio.ErrTooLong, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := provider.ReadDataContext(ctx, path, newTrie(), LineFormat{}); err != context.Canceled {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestRedisProvider(t *testing.T) {
+	var _ ContextDataProvider = (*RedisProvider)(nil)
+
+	mr := miniredis.RunT(t)
+	mr.Set("keywords", `["beach","bike","pool"]`)
+
+	provider, err := NewRedisProvider(mr.Addr(), "keywords")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	defer provider.Close()
+
+	service := testService(t, nil)
+	src := NewDataSource(provider, DefaultFormat{}, "keywords.json", "")
+	if err := service.LoadDataSource(*src); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"beach", "bike", "pool"}, service.GetContents())
+
+	t.Run("dump", func(t *testing.T) {
+		service.Add("bicycle")
+		if err := service.ExportToDataSource(*src); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		got, _ := mr.Get("keywords")
+		if got != `["beach","bicycle","bike","pool"]` {
+			t.Errorf("Expected the keywords, got %s", got)
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		mr.SAdd("set", "dog", "dog park")
+		setProvider, _ := NewRedisProvider(mr.Addr(), "set")
+		defer setProvider.Close()
+
+		service := testService(t, nil)
+		src := NewDataSource(setProvider, DefaultFormat{}, "keywords.json", "")
+		if err := service.LoadDataSource(*src); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		assertWords(t, []string{"dog", "dog park"}, service.GetContents())
+
+		service.Remove("dog park")
+		service.Add("cat")
+		if err := service.ExportToDataSource(*src); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		members, _ := mr.Members("set")
+		assertWords(t, []string{"cat", "dog"}, members)
+	})
+
+	t.Run("connection failure", func(t *testing.T) {
+		down := miniredis.RunT(t)
+		addr := down.Addr()
+		down.Close()
+
+		downProvider, _ := NewRedisProvider(addr, "keywords")
+		defer downProvider.Close()
+
+		errs := len(service.Errors)
+		if err := service.LoadDataSource(*NewDataSource(downProvider, DefaultFormat{}, "keywords.json", "")); err == nil {
+			t.Errorf("Expected non-nil, got %v", err)
+		}
+		if len(service.Errors) != errs+1 {
+			t.Errorf("Expected the error to be added to the service")
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		p, _ := NewRedisProvider(mr.Addr(), "keywords")
+		if err := p.Close(); err != nil {
+			t.Errorf("Expected nil, got %v", err)
+		}
+		if err := p.Close(); err != nil {
+			t.Errorf("Expected closing twice to be a no-op, got %v", err)
+		}
+	})
+}
+
+func TestSQLProvider(t *testing.T) {
+	var _ ContextDataProvider = (*SQLProvider)(nil)
+
+	if _, err := NewSQLProvider(nil, "SELECT word FROM keywords"); !errors.Is(err, ErrNilDB) {
+		t.Errorf("Expected %v, got %v", ErrNilDB, err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	defer db.Close()
+
+	provider, err := NewSQLProvider(db, "SELECT word, weight FROM keywords")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	mock.ExpectQuery("SELECT word, weight FROM keywords").WillReturnRows(
+		sqlmock.NewRows([]string{"word", "weight"}).
+			AddRow("beach", 1).
+			AddRow(nil, 2).
+			AddRow("bike", 3),
+	)
+
+	// The formatter is ignored.
+	service := testService(t, nil)
+	src := DataSource{Provider: provider, Filepath: "keywords"}
+	if err := service.LoadDataSource(src); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"beach", "bike"}, service.GetContents())
+
+	t.Run("dump", func(t *testing.T) {
+		if err := service.ExportToDataSource(src); err == nil {
+			t.Errorf("Expected a read only error, got %v", err)
+		}
+
+		provider.UpsertStatement = "INSERT INTO keywords (word) VALUES ($1) ON CONFLICT DO NOTHING"
+		defer func() { provider.UpsertStatement = "" }()
+
+		mock.ExpectBegin()
+		prep := mock.ExpectPrepare(`INSERT INTO keywords \(word\) VALUES \(\$1\) ON CONFLICT DO NOTHING`)
+		prep.ExpectExec().WithArgs("beach").WillReturnResult(sqlmock.NewResult(1, 1))
+		prep.ExpectExec().WithArgs("bike").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		if err := service.ExportToDataSource(src); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT word, weight FROM keywords").WillReturnError(errors.New("connection refused"))
+
+		errs := len(service.Errors)
+		if err := service.LoadDataSource(src); err == nil {
+			t.Errorf("Expected non-nil, got %v", err)
+		}
+		if len(service.Errors) != errs+1 {
+			t.Errorf("Expected the error to be added to the service")
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// stubS3 keeps the objects of a single bucket in memory.
+type stubS3 struct {
+	bucket  string
+	objects map[string][]byte
+}
+
+func (s *stubS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if *params.Bucket != s.bucket {
+		return nil, errors.New("NoSuchBucket")
+	}
+	obj, ok := s.objects[*params.Key]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(obj))}, nil
+}
+
+func (s *stubS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if *params.Bucket != s.bucket {
+		return nil, errors.New("NoSuchBucket")
+	}
+	obj, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	s.objects[*params.Key] = obj
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3Provider(t *testing.T) {
+	var _ ContextDataProvider = (*S3Provider)(nil)
+	var _ S3API = (*s3.Client)(nil)
+
+	client := &stubS3{bucket: "keywords", objects: map[string][]byte{
+		"lists/keywords.txt": []byte("beach\nbike\npool\n"),
+	}}
+	provider, err := NewS3Provider("keywords", client)
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	service := testService(t, nil)
+	src := NewDataSource(provider, DefaultFormat{}, "lists/keywords.txt", "")
+	if err := service.LoadDataSource(*src); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"beach", "bike", "pool"}, service.GetContents())
+
+	t.Run("dump", func(t *testing.T) {
+		dest := NewDataSource(provider, DefaultFormat{}, "snapshots/snapshot.json", "")
+		if err := service.ExportToDataSource(*dest); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if got := string(client.objects["snapshots/snapshot.json"]); got != `["beach","bike","pool"]` {
+			t.Errorf("Expected the keywords, got %s", got)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		errs := len(service.Errors)
+		if err := service.LoadDataSource(*NewDataSource(provider, DefaultFormat{}, "missing.json", "")); err == nil {
+			t.Errorf("Expected non-nil, got %v", err)
+		}
+		if len(service.Errors) != errs+1 {
+			t.Errorf("Expected the error to be added to the service")
+		}
+	})
+}
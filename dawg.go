@@ -0,0 +1,468 @@
+package autocomplete
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"unicode/utf8"
+)
+
+var _ autocompleter = (*DAWG)(nil)
+
+// dawgNode is a state of the graph. Unlike the nodes of a trie it can have
+// several parents, every word sharing a suffix goes through the same nodes.
+type dawgNode struct {
+	// Kept sorted by key, so walking the children visits the words in
+	// lexical order.
+	children []dawgEdge
+	isEnd    bool
+
+	// id is unique, it identifies the node in the signatures of its parents.
+	id uint64
+	// words is the number of words in the subgraph of the node, itself
+	// included, so counting the completions doesn't walk them.
+	words int
+}
+
+type dawgEdge struct {
+	key  rune
+	node *dawgNode
+}
+
+func (n *dawgNode) child(r rune) *dawgNode {
+	i, ok := slices.BinarySearchFunc(n.children, r, func(e dawgEdge, r rune) int {
+		return int(e.key - r)
+	})
+	if !ok {
+		return nil
+	}
+	return n.children[i].node
+}
+
+// signature identifies the right language of the node: two nodes with the
+// same signature complete the same suffixes, so one can replace the other.
+func (n *dawgNode) signature() string {
+	buf := make([]byte, 0, 1+len(n.children)*4)
+	if n.isEnd {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	for _, edge := range n.children {
+		buf = binary.AppendUvarint(buf, uint64(edge.key))
+		buf = binary.AppendUvarint(buf, edge.node.id)
+	}
+	return string(buf)
+}
+
+// DAWG is a directed acyclic word graph, a read optimized store for mostly
+// static dictionaries. On top of the prefixes shared by a trie it shares the
+// common suffixes, so "nation", "ration" and "station" share the nodes of
+// "ation", taking a fraction of the memory of a trie for large dictionaries.
+//
+// The graph is minimal, which makes it immutable in place: inserts and deletes
+// are buffered and the graph is rebuilt from scratch on the next read. Batch
+// the writes, each read following a write costs a rebuild.
+//
+// Since the nodes are shared, the bookkeeping of the other stores (weights,
+// insertion order, tags) is not kept and case insensitive mode is not
+// supported.
+type DAWG struct {
+	root *dawgNode
+
+	// Words inserted and deleted since the last build, keyed by their keys.
+	inserted map[string]struct{}
+	deleted  map[string]struct{}
+
+	// nodes is the number of nodes of the graph, the root excluded.
+	nodes int
+
+	// applied on every insert and lookup, see normalize.go.
+	storeOptions
+
+	mu sync.RWMutex
+}
+
+// BuildDAWG builds a minimal DAWG holding words, which don't have to be
+// sorted nor unique.
+func BuildDAWG(words []string) *DAWG {
+	d := newDAWG()
+	d.build(words)
+	return d
+}
+
+func newDAWG() *DAWG {
+	return &DAWG{
+		root:     &dawgNode{},
+		inserted: make(map[string]struct{}),
+		deleted:  make(map[string]struct{}),
+	}
+}
+
+// build replaces the graph with the minimal graph of words, with the
+// incremental algorithm of Daciuk et al. for sorted input: every word is added
+// as a branch after the longest prefix it shares with the previous one, and the
+// branch of the previous word past that prefix is minimized, since no other
+// word can go through it anymore. Minimizing replaces every node by an
+// equivalent node already in the register, or registers it.
+func (d *DAWG) build(words []string) {
+	keys := make([]string, 0, len(words))
+	for _, word := range words {
+		if key := d.key(word); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	type unchecked struct {
+		parent *dawgNode
+		child  *dawgNode
+	}
+
+	var nextID uint64
+	newNode := func() *dawgNode {
+		nextID++
+		return &dawgNode{id: nextID}
+	}
+
+	root := newNode()
+	register := make(map[string]*dawgNode)
+	var path []unchecked
+
+	// minimize replaces or registers the nodes of the path past depth, the
+	// deepest first as the signature of a node depends on its children.
+	minimize := func(depth int) {
+		for i := len(path) - 1; i >= depth; i-- {
+			p := path[i]
+			p.child.words = countWords(p.child)
+			sig := p.child.signature()
+			if existing, ok := register[sig]; ok {
+				p.parent.children[len(p.parent.children)-1].node = existing
+			} else {
+				register[sig] = p.child
+			}
+		}
+		path = path[:depth]
+	}
+
+	var previous []rune
+	for _, key := range keys {
+		word := []rune(key)
+
+		common := 0
+		for common < len(word) && common < len(previous) && word[common] == previous[common] {
+			common++
+		}
+		minimize(common)
+
+		node := root
+		if len(path) > 0 {
+			node = path[len(path)-1].child
+		}
+		for _, r := range word[common:] {
+			child := newNode()
+			// The keys are sorted, so every new child comes last.
+			node.children = append(node.children, dawgEdge{key: r, node: child})
+			path = append(path, unchecked{parent: node, child: child})
+			node = child
+		}
+		node.isEnd = true
+		previous = word
+	}
+	minimize(0)
+	root.words = countWords(root)
+
+	d.root = root
+	d.nodes = len(register)
+	clear(d.inserted)
+	clear(d.deleted)
+}
+
+// countWords counts the words of the subgraph of node, whose children are
+// already counted.
+func countWords(node *dawgNode) int {
+	words := 0
+	if node.isEnd {
+		words++
+	}
+	for _, edge := range node.children {
+		words += edge.node.words
+	}
+	return words
+}
+
+// dirty reports whether some writes are waiting for a rebuild.
+func (d *DAWG) dirty() bool {
+	return len(d.inserted) > 0 || len(d.deleted) > 0
+}
+
+// rebuild applies the buffered writes, the caller must hold the write lock.
+func (d *DAWG) rebuild() {
+	words := make([]string, 0, d.root.words+len(d.inserted))
+	d.visit(d.root, "", func(word string) {
+		if _, ok := d.deleted[word]; !ok {
+			words = append(words, word)
+		}
+	})
+	for key := range d.inserted {
+		words = append(words, key)
+	}
+	d.build(words)
+}
+
+// rlock read locks the graph, rebuilding it first if writes are waiting.
+// Release it with d.mu.RUnlock().
+func (d *DAWG) rlock() {
+	for {
+		d.mu.RLock()
+		if !d.dirty() {
+			return
+		}
+		d.mu.RUnlock()
+
+		d.mu.Lock()
+		if d.dirty() {
+			d.rebuild()
+		}
+		d.mu.Unlock()
+	}
+}
+
+// find returns the node at the end of key, or nil.
+func (d *DAWG) find(key string) *dawgNode {
+	curr := d.root
+	for _, r := range key {
+		if curr = curr.child(r); curr == nil {
+			return nil
+		}
+	}
+	return curr
+}
+
+// stored reports whether key is stored, buffered writes included. The caller
+// must hold the lock.
+func (d *DAWG) stored(key string) bool {
+	if _, ok := d.inserted[key]; ok {
+		return true
+	}
+	if _, ok := d.deleted[key]; ok {
+		return false
+	}
+	node := d.find(key)
+	return node != nil && node.isEnd
+}
+
+// Insert buffers word, the graph is rebuilt on the next read.
+func (d *DAWG) Insert(word string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.insert(word)
+}
+
+// InsertReport buffers word and reports whether it is newly added, false when
+// it is already stored or is empty.
+func (d *DAWG) InsertReport(word string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.insert(word)
+}
+
+// InsertBatch buffers every word, the graph is rebuilt once on the next read.
+func (d *DAWG) InsertBatch(words []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, word := range words {
+		d.insert(word)
+	}
+}
+
+// insert buffers word and reports whether it is newly added, the caller must
+// hold the lock.
+func (d *DAWG) insert(word string) bool {
+	key := d.key(word)
+	if key == "" || d.stored(key) {
+		return false
+	}
+
+	if _, ok := d.deleted[key]; ok {
+		delete(d.deleted, key)
+		return true
+	}
+	d.inserted[key] = struct{}{}
+	return true
+}
+
+// Delete buffers the removal of word, the graph is rebuilt on the next read.
+func (d *DAWG) Delete(word string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := d.key(word)
+	if key == "" || !d.stored(key) {
+		return false
+	}
+
+	if _, ok := d.inserted[key]; ok {
+		delete(d.inserted, key)
+		return true
+	}
+	d.deleted[key] = struct{}{}
+	return true
+}
+
+func (d *DAWG) Contains(word string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.stored(d.key(word))
+}
+
+func (d *DAWG) Autocomplete(prefix string) []string {
+	d.rlock()
+	defer d.mu.RUnlock()
+	prefix = d.key(prefix)
+
+	var results []string
+
+	curr := d.find(prefix)
+	if curr == nil {
+		return results
+	}
+
+	d.visit(curr, prefix, func(word string) {
+		results = append(results, word)
+	})
+
+	return results
+}
+
+// visit calls fn with every word in the subgraph of node, in lexical order. It
+// walks with an explicit stack like the trie, a node shared by several words
+// is visited once per word.
+func (d *DAWG) visit(node *dawgNode, prefix string, fn func(word string)) {
+	if node.isEnd {
+		fn(prefix)
+	}
+
+	type frame struct {
+		node *dawgNode
+		key  rune
+		// depth is the length of the word up to the parent of node.
+		depth int
+	}
+
+	word := []byte(prefix)
+	var stack []frame
+	push := func(parent *dawgNode, depth int) {
+		// Pushed in reverse, so the smallest key is popped first.
+		for i := len(parent.children) - 1; i >= 0; i-- {
+			edge := parent.children[i]
+			stack = append(stack, frame{node: edge.node, key: edge.key, depth: depth})
+		}
+	}
+
+	push(node, len(word))
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		word = utf8.AppendRune(word[:f.depth], f.key)
+		if f.node.isEnd {
+			fn(string(word))
+		}
+		push(f.node, len(word))
+	}
+}
+
+func (d *DAWG) ListContents() []string {
+	return d.Autocomplete("")
+}
+
+func (d *DAWG) Count() int {
+	d.rlock()
+	defer d.mu.RUnlock()
+	return d.root.words
+}
+
+// PrefixCount only walks the prefix, every node knows how many words it leads
+// to.
+func (d *DAWG) PrefixCount(prefix string) int {
+	d.rlock()
+	defer d.mu.RUnlock()
+
+	curr := d.find(d.key(prefix))
+	if curr == nil {
+		return 0
+	}
+	return curr.words
+}
+
+// NodeCount counts the shared nodes once, the root excluded.
+func (d *DAWG) NodeCount() int {
+	d.rlock()
+	defer d.mu.RUnlock()
+	return d.nodes
+}
+
+func (d *DAWG) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.root = &dawgNode{}
+	d.nodes = 0
+	clear(d.inserted)
+	clear(d.deleted)
+}
+
+// Visualize writes the graph in the dot format. The characters are on the
+// edges rather than on the nodes, as a node can be reached through several.
+func (d *DAWG) Visualize(w io.Writer) error {
+	d.rlock()
+	defer d.mu.RUnlock()
+	if d.root == nil {
+		return errors.New("dawg visualizer: root is nil")
+	}
+
+	nodeAttrs := `[color=lightblue fillcolor=lightblue fontcolor=black shape=circle style=filled label=""]`
+	// write header
+	if _, err := fmt.Fprintln(w, "digraph {"); err != nil {
+		return err
+	}
+
+	// write node attributes
+	if _, err := fmt.Fprintf(w, "\tnode %s\n", nodeAttrs); err != nil {
+		return err
+	}
+
+	// Every node is written once, however many parents it has.
+	seen := map[*dawgNode]bool{d.root: true}
+	stack := []*dawgNode{d.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node.isEnd {
+			if _, err := fmt.Fprintf(w, "\t%d [shape=doublecircle]\n", node.id); err != nil {
+				return err
+			}
+		}
+		for _, edge := range node.children {
+			if _, err := fmt.Fprintf(w, "\t%d -> %d [label=%q]\n", node.id, edge.node.id, string(edge.key)); err != nil {
+				return err
+			}
+			if !seen[edge.node] {
+				seen[edge.node] = true
+				stack = append(stack, edge.node)
+			}
+		}
+	}
+
+	// write closing bracket
+	if _, err := fmt.Fprintln(w, "}"); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package autocomplete
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDAWG(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "pool", "beach", "waterfront", "dog park", "resteraunts", "bike"}
+	dawg := BuildDAWG(words)
+
+	if got := dawg.Count(); got != len(words)-1 {
+		t.Errorf("Expected %d words, got %d", len(words)-1, got)
+	}
+	assertWords(t, []string{"bicycle repair", "bike", "bike path"}, dawg.Autocomplete("bi"))
+	assertWords(t, []string{"bike path"}, dawg.Autocomplete("bike p"))
+	if results := dawg.Autocomplete("bikes"); len(results) != 0 {
+		t.Errorf("Expected no results, got %v", results)
+	}
+
+	contents := dawg.ListContents()
+	if !sort.StringsAreSorted(contents) {
+		t.Errorf("Expected the contents in lexical order, got %v", contents)
+	}
+
+	for _, word := range words {
+		if !dawg.Contains(word) {
+			t.Errorf("Expected %q to be stored", word)
+		}
+	}
+	for _, word := range []string{"", "b", "bik", "bikes"} {
+		if dawg.Contains(word) {
+			t.Errorf("Expected %q to not be stored", word)
+		}
+	}
+
+	if got := dawg.PrefixCount("bi"); got != 3 {
+		t.Errorf("Expected 3 words, got %d", got)
+	}
+
+	// Test visualizer
+	dotFile, err := os.Create("dawg.dot")
+	if err != nil {
+		t.Errorf("Error creating dot file: %v", err)
+	}
+	defer dotFile.Close()
+
+	if err := dawg.Visualize(dotFile); err != nil {
+		t.Errorf("Error visualizing dawg: %v", err)
+	}
+
+	os.Remove("dawg.dot")
+
+	dawg.Clear()
+	if dawg.Count() != 0 || len(dawg.ListContents()) != 0 || dawg.NodeCount() != 0 {
+		t.Errorf("Expected an empty graph, got %v", dawg.ListContents())
+	}
+}
+
+func TestDAWGSharesSuffixes(t *testing.T) {
+	words := []string{"nation", "station", "ration"}
+	dawg := BuildDAWG(words)
+	trie := newTrie()
+	trie.InsertBatch(words)
+
+	// "n", "r" and "st" all lead to the nodes of "ation", "s" to its own
+	// node: 5 + 1 + 1.
+	if got := dawg.NodeCount(); got != 7 {
+		t.Errorf("Expected 7 nodes, got %d", got)
+	}
+	if dawg.NodeCount() >= trie.NodeCount() {
+		t.Errorf("Expected less nodes than the trie, got %d and %d", dawg.NodeCount(), trie.NodeCount())
+	}
+	assertWords(t, []string{"nation", "ration", "station"}, dawg.ListContents())
+	assertWords(t, []string{"station"}, dawg.Autocomplete("s"))
+}
+
+func TestDAWGWrites(t *testing.T) {
+	dawg := BuildDAWG([]string{"nation", "ration"})
+
+	dawg.InsertBatch([]string{"station", "nations"})
+	if !dawg.Contains("station") {
+		t.Errorf("Expected station to be stored before the rebuild")
+	}
+	assertWords(t, []string{"nation", "nations", "ration", "station"}, dawg.ListContents())
+
+	if !dawg.Delete("nation") || dawg.Delete("nation") || dawg.Delete("missing") {
+		t.Errorf("Expected nation to be deleted once")
+	}
+	// Deleting a buffered insert, then inserting a buffered delete.
+	dawg.Insert("creation")
+	if !dawg.Delete("creation") {
+		t.Errorf("Expected creation to be deleted")
+	}
+	dawg.Insert("nation")
+	dawg.Delete("ration")
+	assertWords(t, []string{"nation", "nations", "station"}, dawg.ListContents())
+	if got := dawg.PrefixCount("nation"); got != 2 {
+		t.Errorf("Expected 2 words, got %d", got)
+	}
+
+	// The rebuilt graph is as small as a graph built from scratch.
+	if got, expected := dawg.NodeCount(), BuildDAWG(dawg.ListContents()).NodeCount(); got != expected {
+		t.Errorf("Expected %d nodes, got %d", expected, got)
+	}
+}
+
+func TestDAWGMatchesTrie(t *testing.T) {
+	words := dictionaryWords()
+	dawg := BuildDAWG(words)
+	trie := newTrie()
+	trie.InsertBatch(words)
+
+	if dawg.Count() != trie.Count() {
+		t.Fatalf("Expected %d words, got %d", trie.Count(), dawg.Count())
+	}
+	for _, prefix := range []string{"", "a", "st", "tion", "zz"} {
+		expected := trie.Autocomplete(prefix)
+		sort.Strings(expected)
+		if got := dawg.Autocomplete(prefix); strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %d completions of %q, got %d", len(expected), prefix, len(got))
+		}
+		if got := dawg.PrefixCount(prefix); got != trie.PrefixCount(prefix) {
+			t.Errorf("Expected %d words under %q, got %d", trie.PrefixCount(prefix), prefix, got)
+		}
+	}
+	t.Logf("%d words: trie %d nodes, dawg %d nodes", len(words), trie.NodeCount(), dawg.NodeCount())
+	if dawg.NodeCount() >= trie.NodeCount() {
+		t.Errorf("Expected less nodes than the trie, got %d and %d", dawg.NodeCount(), trie.NodeCount())
+	}
+}
+
+func TestDAWGBackend(t *testing.T) {
+	service := testService(t, []string{"nation", "station"}, WithBackend(BackendDAWG), WithWhitespaceCompaction)
+	assertWords(t, []string{"nation"}, service.Complete("n"))
+
+	service.Add("ration  camp")
+	if exists, _ := service.Lookup("ration camp"); !exists {
+		t.Errorf("Expected the compacted word to be stored")
+	}
+	service.Remove("station")
+	assertWords(t, []string{"nation", "ration camp"}, service.Complete(""))
+}
+
+func BenchmarkBuildDAWG(b *testing.B) {
+	words := benchmarkWords(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		BuildDAWG(words)
+	}
+}
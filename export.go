@@ -0,0 +1,110 @@
+package autocomplete
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// RecordFormat reads and writes length prefixed records: every keyword is
+// written as its length in bytes, a 4 byte big endian integer, followed by the
+// keyword itself. Unlike the text formats any keyword can be stored, and the
+// offset of every record can be computed ahead, see ExportSharded().
+//
+//	TYPE: type RecordFormat struct{}
+type RecordFormat struct{}
+
+// recordHeader is the size of the length prefix of every record.
+const recordHeader = 4
+
+func (r RecordFormat) FormatRead(data []byte, fileName string) ([]string, error) {
+	var results []string
+	for len(data) > 0 {
+		if len(data) < recordHeader {
+			return nil, fmt.Errorf("recordformat: truncated length after %d records", len(results))
+		}
+		n := binary.BigEndian.Uint32(data)
+		data = data[recordHeader:]
+		if uint64(n) > uint64(len(data)) {
+			return nil, fmt.Errorf("recordformat: truncated record after %d records", len(results))
+		}
+		results = append(results, string(data[:n]))
+		data = data[n:]
+	}
+	return results, nil
+}
+
+func (r RecordFormat) FormatWrite(keywords []string, fileName string) ([]byte, error) {
+	size := 0
+	for _, keyword := range keywords {
+		size += recordHeader + len(keyword)
+	}
+	return appendRecords(make([]byte, 0, size), keywords), nil
+}
+
+func appendRecords(buf []byte, keywords []string) []byte {
+	for _, keyword := range keywords {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(keyword)))
+		buf = append(buf, keyword...)
+	}
+	return buf
+}
+
+// ExportSharded writes every word to w in the RecordFormat, splitting the
+// sorted words into shards ranges that are encoded and written concurrently,
+// each at its own offset. The result is the same as writing them in a single
+// pass, and can be read back with RecordFormat. It returns the number of bytes
+// written.
+//
+// A failing shard doesn't stop the others, the errors of all the failed ones
+// are joined.
+func (a *AutocompleteService) ExportSharded(w io.WriterAt, shards int) (int64, error) {
+	if !a.acquire() {
+		return 0, fmt.Errorf("autocompleteservice: exportsharded: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	if shards <= 0 {
+		return 0, fmt.Errorf("autocompleteservice: exportsharded: shards must be positive, got %d", shards)
+	}
+
+	words := a.store.ListContents()
+	shards = max(1, min(shards, len(words)))
+
+	// bounds[i] is the index of the first word of shard i, offsets[i] where
+	// it's written.
+	bounds := make([]int, shards+1)
+	offsets := make([]int64, shards+1)
+	for i := 0; i <= shards; i++ {
+		bounds[i] = i * len(words) / shards
+	}
+	for i := 0; i < shards; i++ {
+		offsets[i+1] = offsets[i]
+		for _, word := range words[bounds[i]:bounds[i+1]] {
+			offsets[i+1] += int64(recordHeader + len(word))
+		}
+	}
+
+	errs := make([]error, shards)
+	var wg sync.WaitGroup
+	for i := 0; i < shards; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			buf := make([]byte, 0, offsets[i+1]-offsets[i])
+			buf = appendRecords(buf, words[bounds[i]:bounds[i+1]])
+			if _, err := w.WriteAt(buf, offsets[i]); err != nil {
+				errs[i] = fmt.Errorf("autocompleteservice: exportsharded: shard %d: %w", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		a.recordError(err)
+		return 0, err
+	}
+	return offsets[shards], nil
+}
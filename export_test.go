@@ -0,0 +1,69 @@
+package autocomplete
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportSharded(t *testing.T) {
+	words := []string{"beach", "bicycle repair", "bike", "bike path", "dog park", "pool", "waterfront", "café", "line\nbreak", ""}
+	service := testService(t, words)
+	for i := 0; i < 100; i++ {
+		service.Add(strings.Repeat("z", i+1))
+	}
+
+	expected, _ := RecordFormat{}.FormatWrite(service.GetContents(), "")
+
+	for _, shards := range []int{1, 3, 8, 1000} {
+		f, err := os.Create(filepath.Join(t.TempDir(), "export.records"))
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		n, err := service.ExportSharded(f, shards)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if n != int64(len(expected)) {
+			t.Errorf("Expected %d bytes, got %d", len(expected), n)
+		}
+
+		byts, _ := os.ReadFile(f.Name())
+		if !bytes.Equal(expected, byts) {
+			t.Errorf("Expected the shards to match a single pass export")
+		}
+
+		read, err := RecordFormat{}.FormatRead(byts, f.Name())
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		assertWords(t, service.GetContents(), read)
+	}
+
+	if _, err := service.ExportSharded(&failingWriterAt{}, 0); err == nil {
+		t.Errorf("Expected non-nil, got %v", err)
+	}
+	if _, err := service.ExportSharded(&failingWriterAt{}, 4); !errors.Is(err, errWriteAt) {
+		t.Errorf("Expected %v, got %v", errWriteAt, err)
+	}
+}
+
+func TestRecordFormatTruncated(t *testing.T) {
+	byts, _ := RecordFormat{}.FormatWrite([]string{"bike", "pool"}, "")
+	for _, n := range []int{len(byts) - 1, len(byts) - 6} {
+		if _, err := (RecordFormat{}).FormatRead(byts[:n], ""); err == nil {
+			t.Errorf("Expected non-nil for %d bytes, got %v", n, err)
+		}
+	}
+}
+
+var errWriteAt = errors.New("write failed")
+
+type failingWriterAt struct{}
+
+func (f *failingWriterAt) WriteAt(p []byte, off int64) (int, error) { return 0, errWriteAt }
@@ -0,0 +1,179 @@
+package autocomplete
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldIndex maps the folded form of the stored words, see fold(), back to the
+// words themselves. It costs as much memory as a second store, so it's only
+// built on the first CompleteFold() call, from the current contents, and kept
+// up to date from then on.
+type foldIndex struct {
+	// built is only set while holding mu, it lets the updates skip taking the
+	// lock until the index is needed.
+	built atomic.Bool
+
+	mu   sync.Mutex
+	opts storeOptions
+	// keys holds the folded words, words the store keys folding to each of
+	// them, mapped to the word they were inserted as.
+	keys  *trie
+	words map[string]map[string]string
+}
+
+// fold lower cases s and strips its diacritics, so "Crème Brûlée" folds to
+// "creme brulee".
+func fold(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return norm.NFC.String(b.String())
+}
+
+// build indexes the contents of store, unless the index is already built. The
+// caller must hold the lock.
+func (f *foldIndex) build(store autocompleter, opts storeOptions) {
+	if f.built.Load() {
+		return
+	}
+
+	f.opts = opts
+	f.keys = newTrie()
+	f.words = make(map[string]map[string]string)
+	for _, word := range store.ListContents() {
+		f.add(word)
+	}
+	f.built.Store(true)
+}
+
+// add indexes word under its store key, keeping the form it was first
+// inserted as like the store does. The caller must hold the lock.
+func (f *foldIndex) add(word string) {
+	key := fold(f.opts.normalize(word))
+	words, ok := f.words[key]
+	if !ok {
+		words = make(map[string]string, 1)
+		f.words[key] = words
+		f.keys.Insert(key)
+	}
+	if storeKey := f.opts.key(word); words[storeKey] == "" {
+		words[storeKey] = f.opts.normalize(word)
+	}
+}
+
+// insert indexes word when the index is built.
+func (f *foldIndex) insert(word string) {
+	if word == "" || !f.built.Load() {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Checked again, the index may have been reset in between.
+	if f.built.Load() {
+		f.add(word)
+	}
+}
+
+// remove drops word from the index when the index is built.
+func (f *foldIndex) remove(word string) {
+	if !f.built.Load() {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Checked again, the index may have been reset in between.
+	if !f.built.Load() {
+		return
+	}
+
+	key := fold(f.opts.normalize(word))
+	words, ok := f.words[key]
+	if !ok {
+		return
+	}
+	delete(words, f.opts.key(word))
+	if len(words) == 0 {
+		delete(f.words, key)
+		f.keys.Delete(key)
+	}
+}
+
+// reset drops the index, it's built again on the next query.
+func (f *foldIndex) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = nil
+	f.words = nil
+	f.built.Store(false)
+}
+
+// size returns the number of folded words indexed, 0 until the index is built.
+func (f *foldIndex) size() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.words)
+}
+
+// complete returns the words of store whose folded form starts with the
+// folded prefix, sorted lexically. The index is built first if needed, under
+// the same lock so a reset can't drop it in between.
+func (f *foldIndex) complete(store autocompleter, opts storeOptions, prefix string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.build(store, opts)
+
+	results := []string{}
+	for _, key := range f.keys.Autocomplete(fold(prefix)) {
+		for _, word := range f.words[key] {
+			results = append(results, word)
+		}
+	}
+	sort.Strings(results)
+	return results
+}
+
+// CompleteFold returns the words starting with prefix ignoring case and
+// diacritics, so "cre" completes "Crème brûlée". Results are sorted lexically.
+//
+// The index this needs is only built on the first call, which scans the whole
+// store once. It's then kept up to date by every insert and removal made
+// through the service, and dropped by Clear().
+func (a *AutocompleteService) CompleteFold(prefix string) []string {
+	if !a.acquire() {
+		return []string{}
+	}
+	defer a.release()
+	a.queries.record(prefix)
+
+	return a.folds.complete(a.store, a.Config.storeOptions(), prefix)
+}
+
+// indexStore keeps the secondary indexes up to date with the words inserted by
+// the data providers.
+type indexStore struct {
+	PublicProviderStore
+
+	folds      *foldIndex
+	substrings *substringIndex
+	cache      *suggestionCache
+}
+
+func (s *indexStore) Insert(word string) {
+	s.PublicProviderStore.Insert(word)
+	s.cache.invalidate()
+	s.folds.insert(word)
+	s.substrings.insert(word)
+}
@@ -0,0 +1,104 @@
+package autocomplete
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCompleteFold(t *testing.T) {
+	service := testService(t, []string{"Crème brûlée", "crepe", "Café", "bike"})
+
+	if size := service.folds.size(); size != 0 {
+		t.Fatalf("Expected an empty index before the first query, got %d", size)
+	}
+
+	assertWords(t, []string{"Crème brûlée", "crepe"}, service.CompleteFold("CRE"))
+	assertWords(t, []string{"Café"}, service.CompleteFold("cafe"))
+	assertWords(t, []string{"Café"}, service.CompleteFold("CAFÉ"))
+	if size := service.folds.size(); size != 4 {
+		t.Errorf("Expected 4, got %d", size)
+	}
+
+	// Kept up to date once built.
+	service.Add("Crêpe")
+	provider, _ := NewInMemoryProvider([]byte(`["crémeux"]`))
+	if err := service.LoadDataSource(*NewDataSource(provider, nil, "keywords.json", "")); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	service.Remove("crepe")
+	assertWords(t, []string{"Crème brûlée", "Crêpe", "crémeux"}, service.CompleteFold("cre"))
+
+	service.Clear(false)
+	if size := service.folds.size(); size != 0 {
+		t.Errorf("Expected Clear to drop the index, got %d", size)
+	}
+	service.Add("crêpe")
+	assertWords(t, []string{"crêpe"}, service.CompleteFold("crep"))
+}
+
+func TestCompleteFoldNormalized(t *testing.T) {
+	service := testService(t, nil, WithWhitespaceCompaction)
+	service.CompleteFold("d")
+
+	// Indexed as stored, so removing the compacted form drops it.
+	service.Add("dog  park")
+	assertWords(t, []string{"dog park"}, service.CompleteFold("dog"))
+	service.Remove("dog park")
+	assertWords(t, []string{}, service.CompleteFold("dog"))
+}
+
+func TestCompleteFoldCaseInsensitive(t *testing.T) {
+	service := testService(t, nil, WithCaseInsensitive)
+	service.CompleteFold("b")
+
+	service.Add("Bike")
+	service.Add("bike")
+	assertWords(t, []string{"Bike"}, service.CompleteFold("bi"))
+
+	// Removed under any casing, like from the store.
+	service.Remove("bike")
+	assertWords(t, []string{}, service.Complete("bi"))
+	assertWords(t, []string{}, service.CompleteFold("bi"))
+
+	service.Add("BIKE")
+	assertWords(t, service.Complete("bi"), service.CompleteFold("bi"))
+}
+
+// Run with -race, resets used to drop the index between the built check and
+// the lock of the updates.
+func TestFoldIndexConcurrentReset(t *testing.T) {
+	store := newTrie()
+	store.InsertBatch([]string{"crepe", "café"})
+	var f foldIndex
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				f.insert("crème")
+				f.complete(store, storeOptions{}, "cr")
+				f.remove("crème")
+			}
+		}()
+	}
+	for j := 0; j < 500; j++ {
+		f.reset()
+	}
+	wg.Wait()
+}
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"Crème Brûlée": "creme brulee",
+		"ÅNGSTRÖM":     "angstrom",
+		"bike":         "bike",
+		"":             "",
+	}
+	for in, expected := range tests {
+		if got := fold(in); got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	}
+}
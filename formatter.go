@@ -1,10 +1,16 @@
 package autocomplete
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"io"
+	"path"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -20,7 +26,7 @@ import (
 //
 // NOTE: Though it is not required to satisfy the interface, it is the standard
 // to create a type alias if you're not using a user defined struct. For example:
-// `type DefaultFormat []string`
+// `type MyFormat []string`
 //
 // Implementing the Formatter interface only requires one method.
 // Format. It takes the file data and returns a slice of strings
@@ -30,10 +36,28 @@ type Formatter interface {
 	FormatWrite(keywords []string, fileName string) ([]byte, error)
 }
 
+// StreamingFormatter is implemented by the formatters able to read keywords
+// one at a time, so very large files never have to be held in memory. Emit is
+// called with every keyword read, reading stops at the first error it returns.
+//
+// Implementing it is optional, the providers supporting it (currently
+// LocalFileProvider) fall back to FormatRead for every other formatter.
+type StreamingFormatter interface {
+	Formatter
+	FormatReadStream(r io.Reader, fileName string, emit func(keyword string) error) error
+}
+
 // DefaultFormat requires that your file decode into a slice of strings.
 // Basically a non-nested JSON array of strings.
 //
-// TYPE: type DefaultFormat []string
+//	TYPE: type DefaultFormat struct {
+//		Indent bool
+//		Prefix string
+//	}
+//
+// JSON is written compact by default, set Indent to write it indented for
+// human editable snapshots. Prefix is prepended to every indented line, keep
+// it whitespace if the output needs to be read back.
 //
 // Example: keywords.json
 //
@@ -58,13 +82,23 @@ type Formatter interface {
 //   - keyword1
 //   - keyword2
 //   - keyword3
-type DefaultFormat []string
+//
+// Example: keywords.xml
+//
+//	<keywords>
+//	  <keyword>keyword1</keyword>
+//	  <keyword>keyword2</keyword>
+//	</keywords>
+type DefaultFormat struct {
+	Indent bool
+	Prefix string
+}
 
 func (f DefaultFormat) FormatRead(data []byte, fileName string) ([]string, error) {
 	fType := detectFileType(fileName)
 	switch fType {
 	case "json":
-		var obj DefaultFormat
+		var obj []string
 		if err := json.Unmarshal(data, &obj); err != nil {
 			return nil, err
 		}
@@ -77,23 +111,23 @@ func (f DefaultFormat) FormatRead(data []byte, fileName string) ([]string, error
 		reader := csv.NewReader(bytes.NewReader(data))
 		return reader.Read()
 	case "yaml":
-		var obj DefaultFormat
+		var obj []string
 		if err := yaml.Unmarshal(data, &obj); err != nil {
 			return nil, err
 		}
+		return obj, nil
+	case "xml":
+		return unmarshalXML(data)
 	default:
 		return nil, errors.New("Invalid file type")
 	}
-	// Technically this should be covered by the default block.. But the compiler
-	// isn't picking up on that.
-	return nil, errors.New("Unhandled error")
 
 }
 func (f DefaultFormat) FormatWrite(keywords []string, fileName string) ([]byte, error) {
 	fType := detectFileType(fileName)
 	switch fType {
 	case "json":
-		return json.Marshal(keywords)
+		return marshalJSON(keywords, f.Indent, f.Prefix)
 	case "txt":
 		return []byte(strings.Join(keywords, "\n")), nil
 	case "csv":
@@ -106,6 +140,8 @@ func (f DefaultFormat) FormatWrite(keywords []string, fileName string) ([]byte,
 		return buf.Bytes(), nil
 	case "yaml":
 		return yaml.Marshal(keywords)
+	case "xml":
+		return marshalXML(keywords, f.Indent, f.Prefix)
 	default:
 		return nil, errors.New("Invalid file type")
 	}
@@ -147,8 +183,21 @@ func (f DefaultFormat) FormatWrite(keywords []string, fileName string) ([]byte,
 //	keyword1
 //	keyword2
 //	keyword3
+//
+// Example: keywords.xml
+//
+//	<keywords>
+//	  <keyword>keyword1</keyword>
+//	  <keyword>keyword2</keyword>
+//	</keywords>
+//
+// JSON is written compact by default, set Indent to write it indented for
+// human editable snapshots. Prefix is prepended to every indented line.
 type KeywordObjectListFormat struct {
 	Keywords []string `json:"keywords" yaml:"keywords"`
+
+	Indent bool   `json:"-" yaml:"-"`
+	Prefix string `json:"-" yaml:"-"`
 }
 
 func (k KeywordObjectListFormat) FormatRead(data []byte, fileName string) ([]string, error) {
@@ -178,12 +227,12 @@ func (k KeywordObjectListFormat) FormatRead(data []byte, fileName string) ([]str
 		if err != nil {
 			return nil, err
 		}
+		return obj.Keywords, nil
+	case "xml":
+		return unmarshalXML(data)
 	default:
 		return nil, errors.New("Invalid file type")
 	}
-	// Technically this should be covered by the default block.. But the compiler
-	// isn't picking up on that.
-	return nil, errors.New("Unhandled error")
 }
 
 func (k KeywordObjectListFormat) FormatWrite(keywords []string, fileName string) ([]byte, error) {
@@ -192,7 +241,7 @@ func (k KeywordObjectListFormat) FormatWrite(keywords []string, fileName string)
 	switch fType {
 	case "json":
 		obj := KeywordObjectListFormat{Keywords: keywords}
-		return json.Marshal(obj)
+		return marshalJSON(obj, k.Indent, k.Prefix)
 	case "txt":
 		var buffer bytes.Buffer
 		buffer.WriteString("keywords\n")
@@ -210,18 +259,429 @@ func (k KeywordObjectListFormat) FormatWrite(keywords []string, fileName string)
 	case "yaml":
 		obj := KeywordObjectListFormat{Keywords: keywords}
 		return yaml.Marshal(obj)
+	case "xml":
+		return marshalXML(keywords, k.Indent, k.Prefix)
 	default:
 		return nil, errors.New("Invalid file type")
 	}
 }
 
+// MarkdownListFormat reads the items of the bullet (- or *) and ordered
+// (1. or 1)) lists of a Markdown file, nested items included. Headings,
+// paragraphs and fenced code blocks are ignored. Keywords are written back as
+// a bullet list.
+//
+//	TYPE: type MarkdownListFormat struct{}
+//
+// Example: keywords.md
+//
+//	# Places
+//
+//	Some places around town.
+//
+//	- keyword1
+//	- keyword2
+//	  1. keyword3
+type MarkdownListFormat struct{}
+
+func (m MarkdownListFormat) FormatRead(data []byte, fileName string) ([]string, error) {
+	if fType := detectFileType(fileName); fType != "md" && fType != "markdown" {
+		return nil, errors.New("Invalid file type")
+	}
+
+	var results []string
+	var fenced bool
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		if item, ok := markdownListItem(line); ok {
+			results = append(results, item)
+		}
+	}
+	return results, nil
+}
+
+func (m MarkdownListFormat) FormatWrite(keywords []string, fileName string) ([]byte, error) {
+	if fType := detectFileType(fileName); fType != "md" && fType != "markdown" {
+		return nil, errors.New("Invalid file type")
+	}
+
+	var buffer bytes.Buffer
+	for _, keyword := range keywords {
+		buffer.WriteString("- ")
+		buffer.WriteString(keyword)
+		buffer.WriteString("\n")
+	}
+	return buffer.Bytes(), nil
+}
+
+// markdownListItem returns the text of a trimmed list item line, ok is false
+// when the line isn't a list item.
+func markdownListItem(line string) (item string, ok bool) {
+	if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") {
+		item = line[2:]
+	} else {
+		// Ordered item, one or more digits followed by . or ).
+		digits := 0
+		for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+			digits++
+		}
+		rest := line[digits:]
+		if digits == 0 || !(strings.HasPrefix(rest, ". ") || strings.HasPrefix(rest, ") ")) {
+			return "", false
+		}
+		item = rest[2:]
+	}
+
+	item = strings.TrimSpace(item)
+	return item, item != ""
+}
+
+// DelimitedFormat reads delimiter separated values, e.g. TSV with '\t', whatever
+// the file extension. Every field of every record is a keyword, records may
+// have any number of fields. When HasHeader is set the first record is a header
+// and is skipped. Keywords are written one per record, after a "keywords"
+// header when HasHeader is set.
+//
+//	TYPE: type DelimitedFormat struct {
+//		Delimiter rune
+//		HasHeader bool
+//	}
+//
+// Delimiter defaults to ','.
+//
+// Example: keywords.tsv with HasHeader
+//
+//	keywords
+//	keyword1	keyword2
+//	keyword3
+type DelimitedFormat struct {
+	Delimiter rune
+	HasHeader bool
+}
+
+func (d DelimitedFormat) comma() rune {
+	if d.Delimiter == 0 {
+		return ','
+	}
+	return d.Delimiter
+}
+
+func (d DelimitedFormat) FormatRead(data []byte, fileName string) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = d.comma()
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if d.HasHeader && len(records) > 0 {
+		records = records[1:]
+	}
+
+	var results []string
+	for _, record := range records {
+		for _, field := range record {
+			if field != "" {
+				results = append(results, field)
+			}
+		}
+	}
+	return results, nil
+}
+
+func (d DelimitedFormat) FormatWrite(keywords []string, fileName string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = d.comma()
+	if d.HasHeader {
+		writer.Write([]string{"keywords"})
+	}
+	for _, keyword := range keywords {
+		writer.Write([]string{keyword})
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// LineFormat reads one keyword per line whatever the file extension, and
+// streams them when the provider supports it, see StreamingFormatter. Empty
+// lines are skipped, both \n and \r\n line endings are supported and the last
+// line doesn't need a trailing newline.
+//
+//	TYPE: type LineFormat struct {
+//		MaxLineLength int
+//	}
+//
+// MaxLineLength is the length in bytes of the longest line accepted, 64 MiB
+// when left 0. Longer lines fail the read with bufio.ErrTooLong.
+//
+// Example: keywords.txt
+//
+//	keyword1
+//	keyword2
+//	keyword3
+type LineFormat struct {
+	MaxLineLength int
+}
+
+func (f LineFormat) FormatRead(data []byte, fileName string) ([]string, error) {
+	var results []string
+	err := f.FormatReadStream(bytes.NewReader(data), fileName, func(keyword string) error {
+		results = append(results, keyword)
+		return nil
+	})
+	return results, err
+}
+
+func (f LineFormat) FormatReadStream(r io.Reader, fileName string, emit func(keyword string) error) error {
+	maxLength := f.MaxLineLength
+	if maxLength <= 0 {
+		maxLength = 64 << 20
+	}
+
+	scanner := bufio.NewScanner(r)
+	// Starts small, the buffer only grows as long lines are met.
+	scanner.Buffer(make([]byte, 0, min(4096, maxLength)), maxLength)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := emit(scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (f LineFormat) FormatWrite(keywords []string, fileName string) ([]byte, error) {
+	var buffer bytes.Buffer
+	for _, keyword := range keywords {
+		buffer.WriteString(keyword)
+		buffer.WriteString("\n")
+	}
+	return buffer.Bytes(), nil
+}
+
+// JSONLinesFormat reads and writes JSON Lines (NDJSON), one JSON string per
+// line, whatever the file extension. Lines that aren't quoted are read as bare
+// keywords, blank lines are skipped. Reads are streamed when the provider
+// supports it, see StreamingFormatter.
+//
+//	TYPE: type JSONLinesFormat struct{}
+//
+// Example: keywords.jsonl
+//
+//	"keyword1"
+//	"keyword with \"quotes\""
+//	keyword3
+type JSONLinesFormat struct{}
+
+func (j JSONLinesFormat) FormatRead(data []byte, fileName string) ([]string, error) {
+	var results []string
+	err := j.FormatReadStream(bytes.NewReader(data), fileName, func(keyword string) error {
+		results = append(results, keyword)
+		return nil
+	})
+	return results, err
+}
+
+func (j JSONLinesFormat) FormatReadStream(r io.Reader, fileName string, emit func(keyword string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		keyword := text
+		switch text[0] {
+		case '"', '[', '{':
+			// JSON, which has to be a string.
+			if err := json.Unmarshal([]byte(text), &keyword); err != nil {
+				return fmt.Errorf("jsonlinesformat: %s: line %d: %w", fileName, line, err)
+			}
+		}
+		if err := emit(keyword); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (j JSONLinesFormat) FormatWrite(keywords []string, fileName string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Keep <, > and & readable, there is no HTML involved.
+	enc.SetEscapeHTML(false)
+	for _, keyword := range keywords {
+		// Encode ends every value with a newline.
+		if err := enc.Encode(keyword); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// GzipFormatter compresses what Inner writes, and decompresses what it reads,
+// so snapshots can be stored compressed. The type passed to Inner is the file
+// name without its .gz suffix, e.g. keywords.json for keywords.json.gz.
+//
+//	TYPE: type GzipFormatter struct {
+//		Inner Formatter
+//	}
+//
+// Inner defaults to DefaultFormat.
+type GzipFormatter struct {
+	Inner Formatter
+}
+
+func (g GzipFormatter) inner() Formatter {
+	if g.Inner == nil {
+		return DefaultFormat{}
+	}
+	return g.Inner
+}
+
+func (g GzipFormatter) FormatRead(data []byte, fileName string) ([]string, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gzipformatter: %s: %w", fileName, err)
+	}
+	defer gz.Close()
+
+	byts, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("gzipformatter: %s: %w", fileName, err)
+	}
+	return g.inner().FormatRead(byts, g.innerName(fileName))
+}
+
+// innerName returns the file name the inner formatter is given, without its
+// .gz extension.
+func (g GzipFormatter) innerName(fileName string) string {
+	base := fileBase(fileName)
+	if outer, _ := detectFileTypes(base); outer == "gz" {
+		return base[:len(base)-len(".gz")]
+	}
+	return base
+}
+
+func (g GzipFormatter) FormatWrite(keywords []string, fileName string) ([]byte, error) {
+	byts, err := g.inner().FormatWrite(keywords, g.innerName(fileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(byts); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// marshalJSON marshals v compact, or indented with two spaces when indent is set.
+func marshalJSON(v any, indent bool, prefix string) ([]byte, error) {
+	if indent {
+		return json.MarshalIndent(v, prefix, "  ")
+	}
+	return json.Marshal(v)
+}
+
+// xmlKeywords is the XML document read and written by DefaultFormat and
+// KeywordObjectListFormat.
+//
+//	<keywords>
+//	  <keyword>keyword1</keyword>
+//	  <keyword>keyword2</keyword>
+//	</keywords>
+type xmlKeywords struct {
+	XMLName  xml.Name `xml:"keywords"`
+	Keywords []string `xml:"keyword"`
+}
+
+// marshalXML marshals keywords compact, or indented with two spaces when
+// indent is set, after the standard XML header.
+func marshalXML(keywords []string, indent bool, prefix string) ([]byte, error) {
+	obj := xmlKeywords{Keywords: keywords}
+
+	var byts []byte
+	var err error
+	if indent {
+		byts, err = xml.MarshalIndent(obj, prefix, "  ")
+	} else {
+		byts, err = xml.Marshal(obj)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), byts...), nil
+}
+
+// unmarshalXML reads the keyword elements of the root element, whatever its
+// name. Attributes and other elements are ignored, and an empty document has
+// no keywords.
+func unmarshalXML(data []byte) ([]string, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []string{}, nil
+	}
+
+	var obj struct {
+		Keywords []string `xml:"keyword"`
+	}
+	if err := xml.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	if obj.Keywords == nil {
+		return []string{}, nil
+	}
+	return obj.Keywords, nil
+}
+
 // There might be a better way of doing this in the future. I have tried with the bytes
 // using http.DetectContentType(data) and not as much help as it should be. Will have to
 // research later to see if there is another way of detecting file type.
+//
+// detectFileType returns the lower cased extension of fileName, which may be a
+// URL, e.g. "json" for https://host/Keywords.JSON?v=2.
 func detectFileType(fileName string) string {
-	parts := strings.Split(fileName, ".")
+	outer, _ := detectFileTypes(fileName)
+	return outer
+}
+
+// detectFileTypes behaves like detectFileType, but also returns the extension
+// before it for compound extensions, e.g. "gz" and "json" for data.json.gz. So
+// wrappers like GzipFormatter can dispatch on the inner type.
+func detectFileTypes(fileName string) (outer, inner string) {
+	parts := strings.Split(fileBase(fileName), ".")
+	// The first part is the name, e.g. "" for ".json" is still a name.
 	if len(parts) < 2 {
-		return ""
+		return "", ""
+	}
+	outer = strings.ToLower(parts[len(parts)-1])
+	if len(parts) > 2 {
+		inner = strings.ToLower(parts[len(parts)-2])
+	}
+	return outer, inner
+}
+
+// fileBase returns the last element of fileName, without any query string or
+// fragment when it's a URL.
+func fileBase(fileName string) string {
+	if i := strings.IndexAny(fileName, "?#"); i >= 0 {
+		fileName = fileName[:i]
 	}
-	return parts[len(parts)-1]
+	return path.Base(fileName)
 }
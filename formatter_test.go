@@ -2,6 +2,8 @@ package autocomplete
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -18,18 +20,25 @@ func TestDefaultFormatter(t *testing.T) {
 	}
 
 	// Passing JSON
-	byts, cleanup := testJsonFile(t, "test.json")
-	keywords, err = fmtr.FormatRead(byts, "test.json")
-	if err != nil {
+	provider, _ := NewInMemoryProvider([]byte(`["keyword1", "keyword2", "keyword3"]`))
+	store := newTrie()
+	if err := provider.ReadData("test.json", store, fmtr); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+	if store.Count() != 3 {
+		t.Errorf("Expected 3, got %v", store.Count())
+	}
+
+	// Written back through the formatter.
+	if err := provider.DumpData("test.json", store, fmtr); err != nil {
 		t.Errorf("Expected nil, got %v", err)
 	}
-	if len(keywords) != 3 {
-		t.Errorf("Expected 3, got %v", len(keywords))
+	if string(provider.Bytes()) != `["keyword1","keyword2","keyword3"]` {
+		t.Errorf("Expected the keywords, got %s", provider.Bytes())
 	}
-	cleanup()
 
 	// Passing TXT
-	byts, cleanup = testTxtFile(t, "test.txt")
+	byts = testTxtFile(t, "test.txt")
 	keywords, err = fmtr.FormatRead(byts, "test.txt")
 	if err != nil {
 		t.Errorf("Expected nil, got %v", err)
@@ -41,21 +50,350 @@ func TestDefaultFormatter(t *testing.T) {
 }
 
 func TestKeywordListFormatter(t *testing.T) {
+	var _ Formatter = (*KeywordObjectListFormat)(nil)
+	fmtr := KeywordObjectListFormat{}
+
+	data := []byte("keywords:\n  - keyword1\n  - keyword2\n  - keyword3\n")
+	keywords, err := fmtr.FormatRead(data, "keywords.yaml")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"keyword1", "keyword2", "keyword3"}, keywords)
 
+	byts, err := fmtr.FormatWrite(keywords, "keywords.yaml")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	read, err := fmtr.FormatRead(byts, "keywords.yaml")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, keywords, read)
+}
+
+func TestDefaultFormatterYAML(t *testing.T) {
+	fmtr := DefaultFormat{}
+
+	data := []byte("- keyword1\n- keyword2\n- keyword with spaces\n")
+	keywords, err := fmtr.FormatRead(data, "keywords.yaml")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"keyword1", "keyword2", "keyword with spaces"}, keywords)
+
+	byts, err := fmtr.FormatWrite(keywords, "keywords.yaml")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	read, err := fmtr.FormatRead(byts, "keywords.yaml")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, keywords, read)
+
+	// A mapping isn't a list.
+	if _, err := fmtr.FormatRead([]byte("keywords:\n  - keyword1\n"), "keywords.yaml"); err == nil {
+		t.Errorf("Expected non-nil, got %v", err)
+	}
+}
+
+func TestFormatWriteIndent(t *testing.T) {
+	keywords := []string{"keyword1", "keyword2"}
+
+	tests := []struct {
+		name     string
+		fmtr     Formatter
+		expected string
+	}{
+		{"default compact", DefaultFormat{}, `["keyword1","keyword2"]`},
+		{"default indented", DefaultFormat{Indent: true}, "[\n  \"keyword1\",\n  \"keyword2\"\n]"},
+		{"default prefixed", DefaultFormat{Indent: true, Prefix: "\t"}, "[\n\t  \"keyword1\",\n\t  \"keyword2\"\n\t]"},
+		{"keyword list compact", KeywordObjectListFormat{}, `{"keywords":["keyword1","keyword2"]}`},
+		{"keyword list indented", KeywordObjectListFormat{Indent: true}, "{\n  \"keywords\": [\n    \"keyword1\",\n    \"keyword2\"\n  ]\n}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			byts, err := tt.fmtr.FormatWrite(keywords, "keywords.json")
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			if string(byts) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, string(byts))
+			}
+
+			read, err := tt.fmtr.FormatRead(byts, "keywords.json")
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			if len(read) != len(keywords) {
+				t.Errorf("Expected %v, got %v", keywords, read)
+			}
+		})
+	}
+}
+
+func TestMarkdownListFormat(t *testing.T) {
+	var _ Formatter = (*MarkdownListFormat)(nil)
+	fmtr := MarkdownListFormat{}
+
+	data := []byte(`# Places
+
+Some places around town, *not* a list.
+
+## Outdoors
+
+- waterfront
+* dog park
+  - bike path
+    1. bicycle repair
+
+1. pool
+10) beach
+
+` + "```" + `
+- not a keyword
+` + "```" + `
+`)
+
+	keywords, err := fmtr.FormatRead(data, "keywords.md")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{"waterfront", "dog park", "bike path", "bicycle repair", "pool", "beach"}, keywords)
+
+	byts, err := fmtr.FormatWrite(keywords, "keywords.md")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	expected := "- waterfront\n- dog park\n- bike path\n- bicycle repair\n- pool\n- beach\n"
+	if string(byts) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(byts))
+	}
+
+	read, err := fmtr.FormatRead(byts, "keywords.md")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, keywords, read)
+
+	if _, err := fmtr.FormatRead(data, "keywords.txt"); err == nil {
+		t.Errorf("Expected non-nil, got %v", err)
+	}
+}
+
+func TestFormatXML(t *testing.T) {
+	keywords := []string{"bike", "bike path", "fish & chips"}
+
+	for _, fmtr := range []Formatter{DefaultFormat{}, DefaultFormat{Indent: true}, KeywordObjectListFormat{}} {
+		byts, err := fmtr.FormatWrite(keywords, "keywords.xml")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if !strings.Contains(string(byts), "<keyword>fish &amp; chips</keyword>") {
+			t.Errorf("Expected escaped keyword elements, got %s", byts)
+		}
+
+		read, err := fmtr.FormatRead(byts, "keywords.xml")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		assertWords(t, keywords, read)
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{"empty document", "", []string{}},
+		{"empty root", "<keywords/>", []string{}},
+		{"attributes", `<keywords version="2"><keyword lang="en">bike</keyword><other>x</other><keyword>pool</keyword></keywords>`, []string{"bike", "pool"}},
+		{"other root", `<?xml version="1.0"?><list><keyword>bike</keyword></list>`, []string{"bike"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read, err := DefaultFormat{}.FormatRead([]byte(tt.data), "keywords.XML")
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			assertWords(t, tt.expected, read)
+		})
+	}
+
+	if _, err := (DefaultFormat{}).FormatRead([]byte("<keywords><keyword>"), "keywords.xml"); err == nil {
+		t.Errorf("Expected non-nil, got %v", err)
+	}
+}
+
+func TestJSONLinesFormat(t *testing.T) {
+	var _ StreamingFormatter = (*JSONLinesFormat)(nil)
+	fmtr := JSONLinesFormat{}
+
+	data := []byte("\"bike\"\n\n  bike path  \r\n\"fish & \\\"chips\\\"\"\n\"tab\\tbed\"")
+	keywords, err := fmtr.FormatRead(data, "keywords.jsonl")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	expected := []string{"bike", "bike path", `fish & "chips"`, "tab\tbed"}
+	assertWords(t, expected, keywords)
+
+	byts, err := fmtr.FormatWrite(keywords, "keywords.jsonl")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if string(byts) != "\"bike\"\n\"bike path\"\n\"fish & \\\"chips\\\"\"\n\"tab\\tbed\"\n" {
+		t.Errorf("Expected one JSON string per line, got %q", byts)
+	}
+	read, err := fmtr.FormatRead(byts, "keywords.jsonl")
+	if err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, expected, read)
+
+	for _, malformed := range []string{"\"bike\"\n\n\"unterminated\n", "\"bike\"\n\n[\"not\", \"a string\"]\n"} {
+		_, err := fmtr.FormatRead([]byte(malformed), "keywords.jsonl")
+		if err == nil || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("Expected an error on line 3, got %v", err)
+		}
+	}
+}
+
+func TestDelimitedFormat(t *testing.T) {
+	var _ Formatter = (*DelimitedFormat)(nil)
+
+	tests := []struct {
+		name string
+		fmtr DelimitedFormat
+		data string
+	}{
+		{"tab", DelimitedFormat{Delimiter: '\t'}, "keyword1\tkeyword2\nkeyword 3\n"},
+		{"tab with header", DelimitedFormat{Delimiter: '\t', HasHeader: true}, "keywords\nkeyword1\tkeyword2\nkeyword 3\n"},
+		{"semicolon", DelimitedFormat{Delimiter: ';'}, "keyword1;keyword2;keyword 3"},
+		{"semicolon with header", DelimitedFormat{Delimiter: ';', HasHeader: true}, "first;second;third\nkeyword1;keyword2\nkeyword 3;\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := []string{"keyword1", "keyword2", "keyword 3"}
+
+			keywords, err := tt.fmtr.FormatRead([]byte(tt.data), "keywords.tsv")
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			assertWords(t, expected, keywords)
+
+			byts, err := tt.fmtr.FormatWrite(keywords, "keywords.tsv")
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			read, err := tt.fmtr.FormatRead(byts, "keywords.tsv")
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			assertWords(t, expected, read)
+		})
+	}
+
+	// Keywords containing the delimiter are quoted.
+	fmtr := DelimitedFormat{Delimiter: ';'}
+	byts, _ := fmtr.FormatWrite([]string{"a;b"}, "keywords.csv")
+	if string(byts) != "\"a;b\"\n" {
+		t.Errorf("Expected %q, got %q", "\"a;b\"\n", byts)
+	}
+}
+
+func TestGzipFormatter(t *testing.T) {
+	var _ Formatter = (*GzipFormatter)(nil)
+
+	words := []string{"beach", "bike", "pool"}
+	for _, fileName := range []string{"snapshot.json.gz", "snapshot.txt.gz", "snapshot.yaml.gz", "https://host/snapshot.JSON.GZ?v=2"} {
+		t.Run(fileName, func(t *testing.T) {
+			provider, _ := NewInMemoryProvider(nil)
+			src := newTrie()
+			for _, word := range words {
+				src.Insert(word)
+			}
+			if err := provider.DumpData(fileName, src, GzipFormatter{}); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			if byts := provider.Bytes(); len(byts) < 2 || byts[0] != 0x1f || byts[1] != 0x8b {
+				t.Errorf("Expected gzip data, got %q", byts)
+			}
+
+			store := newTrie()
+			if err := provider.ReadData(fileName, store, GzipFormatter{Inner: DefaultFormat{}}); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			assertWords(t, words, store.ListContents())
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		fmtr := GzipFormatter{}
+		byts, err := fmtr.FormatWrite(newTrie().ListContents(), "snapshot.json.gz")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		keywords, err := fmtr.FormatRead(byts, "snapshot.json.gz")
+		if err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if len(keywords) != 0 {
+			t.Errorf("Expected no keywords, got %v", keywords)
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		_, err := GzipFormatter{}.FormatRead([]byte(`["not","gzipped"]`), "snapshot.json.gz")
+		if err == nil || !strings.Contains(err.Error(), "gzipformatter") {
+			t.Errorf("Expected a gzipformatter error, got %v", err)
+		}
+	})
 }
 
 func TestDetectFileType(t *testing.T) {
 
-	_, cleanup := testJsonFile(t, "sample.json")
+	testJsonFile(t, "sample.json")
 
-	cleanup()
+	tests := []struct {
+		fileName     string
+		outer, inner string
+	}{
+		{"keywords.json", "json", ""},
+		{"/var/tmp/autocomplete/snapshot.json", "json", ""},
+		{"Keywords.JSON", "json", ""},
+		{"keywords.Yaml", "yaml", ""},
+		{"keywords", "", ""},
+		{"/var/tmp.d/keywords", "", ""},
+		{"", "", ""},
+		{"data.json.gz", "gz", "json"},
+		{"data.TXT.GZ", "gz", "txt"},
+		{"https://host/keywords.json?v=2", "json", ""},
+		{"https://host/keywords.json#top", "json", ""},
+		{"https://host/data.csv.gz?v=2&format=x.yaml", "gz", "csv"},
+		{"https://host.example.com/keywords", "", ""},
+	}
 
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			outer, inner := detectFileTypes(tt.fileName)
+			if outer != tt.outer || inner != tt.inner {
+				t.Errorf("Expected %q and %q, got %q and %q", tt.outer, tt.inner, outer, inner)
+			}
+			if got := detectFileType(tt.fileName); got != tt.outer {
+				t.Errorf("Expected %q, got %q", tt.outer, got)
+			}
+		})
+	}
 }
 
-func testJsonFile(t *testing.T, filename string) ([]byte, func()) {
+// testJsonFile writes filename to a temporary directory, removed with the test,
+// and returns what was read back.
+func testJsonFile(t *testing.T, filename string) []byte {
 	t.Helper()
 	fData := []byte(`["keyword1", "keyword2", "keyword3"]`)
-	file, err := os.Create(filename)
+	file, err := os.Create(filepath.Join(t.TempDir(), filename))
 	if err != nil {
 		t.Errorf("Expected nil, got %v", err)
 	}
@@ -69,21 +407,19 @@ func testJsonFile(t *testing.T, filename string) ([]byte, func()) {
 
 	file.Close()
 
-	data, err := os.ReadFile(filename)
+	data, err := os.ReadFile(file.Name())
 	if err != nil {
 		t.Errorf("Expected nil, got %v", err)
 	}
 
-	return data, func() {
-		os.Remove(file.Name())
-	}
-
+	return data
 }
 
-func testTxtFile(t *testing.T, filename string) ([]byte, func()) {
+// testTxtFile behaves like testJsonFile for a text file.
+func testTxtFile(t *testing.T, filename string) []byte {
 	t.Helper()
 	fileData := []string{"keywords", "keyword1", "keyword2", "keyword3"}
-	file, err := os.Create(filename)
+	file, err := os.Create(filepath.Join(t.TempDir(), filename))
 	if err != nil {
 		t.Errorf("Expected nil, got %v", err)
 	}
@@ -99,13 +435,10 @@ func testTxtFile(t *testing.T, filename string) ([]byte, func()) {
 		t.Errorf("Expected nil, got %v", err)
 	}
 
-	data, err := os.ReadFile(filename)
+	data, err := os.ReadFile(file.Name())
 	if err != nil {
 		t.Errorf("Expected nil, got %v", err)
 	}
 
-	return data, func() {
-		os.Remove(file.Name())
-	}
-
+	return data
 }
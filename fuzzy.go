@@ -0,0 +1,260 @@
+package autocomplete
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// fuzzyMatch is a word completing a prefix within an edit distance.
+type fuzzyMatch struct {
+	word     string
+	distance int
+}
+
+// fuzzyStore is implemented by the stores that can search for fuzzy matches
+// without comparing the prefix against every stored word.
+type fuzzyStore interface {
+	// fuzzy returns every word with a prefix within maxDistance edits of
+	// prefix, along with the smallest such distance.
+	fuzzy(prefix string, maxDistance int) []fuzzyMatch
+}
+
+// CompleteFuzzy returns the words starting with prefix give or take
+// maxDistance typos, so "biek" still completes "bike". A typo is an inserted,
+// deleted or substituted character (the Levenshtein distance). Results are
+// ordered by how many typos they needed, then lexically. A maxDistance of 0
+// behaves like Complete, only ordered lexically.
+//
+// The built in stores prune the branches that can't match while walking, so
+// only a small part of the store is visited for small distances.
+func (a *AutocompleteService) CompleteFuzzy(prefix string, maxDistance int) []string {
+	if !a.acquire() {
+		return []string{}
+	}
+	defer a.release()
+	a.queries.record(prefix)
+
+	if maxDistance < 0 {
+		return []string{}
+	}
+
+	var matches []fuzzyMatch
+	if fs, ok := a.store.(fuzzyStore); ok {
+		matches = fs.fuzzy(prefix, maxDistance)
+	} else {
+		matches = scanFuzzy(a.store.ListContents(), prefix, maxDistance)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].word < matches[j].word
+	})
+
+	results := make([]string, len(matches))
+	for i, m := range matches {
+		results[i] = m.word
+	}
+	return results
+}
+
+// firstEditRow returns the edit distances between every prefix of query and
+// the empty word.
+func firstEditRow(query []rune) []int {
+	row := make([]int, len(query)+1)
+	for i := range row {
+		row[i] = i
+	}
+	return row
+}
+
+// nextEditRow returns the row of edit distances for a word one character r
+// longer than the word prev was computed for. The last column is the distance
+// between the word and the whole query.
+func nextEditRow(prev []int, query []rune, r rune) []int {
+	row := make([]int, len(prev))
+	row[0] = prev[0] + 1
+	for i := 1; i < len(row); i++ {
+		cost := 1
+		if query[i-1] == r {
+			cost = 0
+		}
+		row[i] = min(row[i-1]+1, prev[i]+1, prev[i-1]+cost)
+	}
+	return row
+}
+
+func minEdit(row []int) int {
+	m := row[0]
+	for _, d := range row[1:] {
+		m = min(m, d)
+	}
+	return m
+}
+
+// scanFuzzy compares prefix against every word, it is used for the stores that
+// can't search for fuzzy matches themselves.
+func scanFuzzy(words []string, prefix string, maxDistance int) []fuzzyMatch {
+	query := []rune(prefix)
+	first := firstEditRow(query)
+
+	var matches []fuzzyMatch
+	for _, word := range words {
+		row := first
+		best := row[len(query)]
+		for _, r := range word {
+			row = nextEditRow(row, query, r)
+			best = min(best, row[len(query)])
+			if minEdit(row) > maxDistance {
+				break
+			}
+		}
+		if best <= maxDistance {
+			matches = append(matches, fuzzyMatch{word: word, distance: best})
+		}
+	}
+	return matches
+}
+
+func (s *spillStore) fuzzy(prefix string, maxDistance int) []fuzzyMatch {
+	var matches []fuzzyMatch
+	seen := make(map[string]struct{})
+	for _, store := range []autocompleter{s.primary, s.secondary} {
+		var found []fuzzyMatch
+		if fs, ok := store.(fuzzyStore); ok {
+			found = fs.fuzzy(prefix, maxDistance)
+		} else {
+			found = scanFuzzy(store.ListContents(), prefix, maxDistance)
+		}
+
+		for _, m := range found {
+			if _, ok := seen[m.word]; ok {
+				continue
+			}
+			seen[m.word] = struct{}{}
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// fuzzy walks the trie computing one row of edit distances per node, the rows
+// of the children are derived from the row of their parent. A branch is
+// pruned once every distance in its row is past maxDistance, no longer word
+// can get any closer.
+func (t *trie) fuzzy(prefix string, maxDistance int) []fuzzyMatch {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	query, ok := t.keys(prefix, false)
+	if !ok {
+		// Contains a cluster no word has, it can only be matched by edits.
+		query = []rune(t.key(prefix))
+	}
+
+	type frame struct {
+		node  *trieNode
+		key   rune
+		depth int
+		// row and best of the parent.
+		row  []int
+		best int
+	}
+
+	var matches []fuzzyMatch
+	var word []byte
+	var stack []frame
+	push := func(parent *trieNode, depth int, row []int, best int) {
+		for i := len(parent.children) - 1; i >= 0; i-- {
+			edge := parent.children[i]
+			stack = append(stack, frame{node: edge.node, key: edge.key, depth: depth, row: row, best: best})
+		}
+	}
+
+	first := firstEditRow(query)
+	push(t.Root, 0, first, first[len(query)])
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		word = t.appendUnit(word[:f.depth], f.key)
+		row := nextEditRow(f.row, query, f.key)
+		best := min(f.best, row[len(query)])
+
+		if minEdit(row) > maxDistance {
+			// Nothing deeper gets closer, but a prefix of the word may
+			// already have matched.
+			if best <= maxDistance {
+				t.visit(f.node, string(word), func(w string, _ *trieNode) {
+					matches = append(matches, fuzzyMatch{word: w, distance: best})
+				})
+			}
+			continue
+		}
+
+		if f.node.isEnd && best <= maxDistance {
+			matches = append(matches, fuzzyMatch{word: f.node.text(string(word)), distance: best})
+		}
+		push(f.node, len(word), row, best)
+	}
+
+	return matches
+}
+
+// fuzzy walks the tree like trie.fuzzy(). The siblings of a node share the
+// row of their parent, only the middle child continues the word.
+func (t *ternarysearchtree) fuzzy(prefix string, maxDistance int) []fuzzyMatch {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	query := t.runes(prefix)
+
+	type frame struct {
+		node  *tstNode
+		depth int
+		// row and best of the parent.
+		row  []int
+		best int
+	}
+
+	var matches []fuzzyMatch
+	var word []byte
+	first := firstEditRow(query)
+	stack := []frame{{node: t.Root, row: first, best: first[len(query)]}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if f.node == nil {
+			continue
+		}
+		stack = append(stack,
+			frame{node: f.node.Right, depth: f.depth, row: f.row, best: f.best},
+			frame{node: f.node.Left, depth: f.depth, row: f.row, best: f.best},
+		)
+
+		word = utf8.AppendRune(word[:f.depth], f.node.Char)
+		row := nextEditRow(f.row, query, f.node.Char)
+		best := min(f.best, row[len(query)])
+
+		if minEdit(row) > maxDistance {
+			if best <= maxDistance {
+				if f.node.IsEnd {
+					matches = append(matches, fuzzyMatch{word: f.node.text(string(word)), distance: best})
+				}
+				t.visit(f.node.Mid, string(word), func(w string, _ *tstNode) {
+					matches = append(matches, fuzzyMatch{word: w, distance: best})
+				})
+			}
+			continue
+		}
+
+		if f.node.IsEnd && best <= maxDistance {
+			matches = append(matches, fuzzyMatch{word: f.node.text(string(word)), distance: best})
+		}
+		stack = append(stack, frame{node: f.node.Mid, depth: len(word), row: row, best: best})
+	}
+
+	return matches
+}
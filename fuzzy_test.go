@@ -0,0 +1,64 @@
+package autocomplete
+
+import (
+	"testing"
+)
+
+func TestCompleteFuzzy(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "beach", "bin", "pool", "naïve", "naïveté", "日本", "日本語"}
+
+	stores := map[string][]ConfigFn{
+		"trie": {},
+		"tst":  {WithLowMemoryMode},
+		// countingStore hides fuzzy(), so the words are scanned.
+		"scan": {WithStore(&countingStore{autocompleter: newTrie()})},
+	}
+
+	for name, opts := range stores {
+		t.Run(name, func(t *testing.T) {
+			service := testService(t, words, opts...)
+
+			// "bik" is one insertion away from "biek".
+			assertWords(t, []string{"bike", "bike path"}, service.CompleteFuzzy("biek", 1))
+
+			// Closest first, then lexically.
+			assertWords(t, []string{"bike", "bike path", "bicycle repair", "bin"}, service.CompleteFuzzy("bik", 1))
+
+			// No typos allowed is a plain prefix match.
+			assertWords(t, []string{"bicycle repair", "bike", "bike path", "bin"}, service.CompleteFuzzy("bi", 0))
+			assertWords(t, []string{}, service.CompleteFuzzy("biek", 0))
+
+			// Multibyte runes count as a single edit.
+			assertWords(t, []string{"naïve", "naïveté"}, service.CompleteFuzzy("naive", 1))
+			assertWords(t, []string{"日本", "日本語"}, service.CompleteFuzzy("日木", 1))
+
+			assertWords(t, []string{}, service.CompleteFuzzy("bi", -1))
+		})
+	}
+}
+
+func BenchmarkCompleteFuzzy(b *testing.B) {
+	trie := newTrie()
+	for _, word := range benchmarkWords(10000) {
+		trie.Insert(word)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		trie.fuzzy("a1b2", 1)
+	}
+}
+
+func BenchmarkCompleteFuzzyScan(b *testing.B) {
+	trie := newTrie()
+	for _, word := range benchmarkWords(10000) {
+		trie.Insert(word)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		scanFuzzy(trie.ListContents(), "a1b2", 1)
+	}
+}
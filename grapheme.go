@@ -0,0 +1,57 @@
+package autocomplete
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// clusterTable interns the grapheme clusters made of more than one rune so
+// they can be used as a single node key. Interned clusters are given keys
+// past unicode.MaxRune, so they never collide with a plain rune.
+type clusterTable struct {
+	ids      map[string]rune
+	clusters []string
+}
+
+func newClusterTable() *clusterTable {
+	return &clusterTable{ids: make(map[string]rune)}
+}
+
+// keys splits word into grapheme clusters and returns their keys, see trie.keys().
+func (c *clusterTable) keys(word string, intern bool) ([]rune, bool) {
+	var keys []rune
+
+	state := -1
+	for len(word) > 0 {
+		var cluster string
+		cluster, word, _, state = uniseg.FirstGraphemeClusterInString(word, state)
+
+		if r, size := utf8.DecodeRuneInString(cluster); size == len(cluster) {
+			keys = append(keys, r)
+			continue
+		}
+
+		id, ok := c.ids[cluster]
+		if !ok {
+			if !intern {
+				return nil, false
+			}
+			id = unicode.MaxRune + 1 + rune(len(c.clusters))
+			c.ids[cluster] = id
+			c.clusters = append(c.clusters, cluster)
+		}
+		keys = append(keys, id)
+	}
+
+	return keys, true
+}
+
+// unit returns the cluster represented by the key r.
+func (c *clusterTable) unit(r rune) string {
+	if r <= unicode.MaxRune {
+		return string(r)
+	}
+	return c.clusters[r-unicode.MaxRune-1]
+}
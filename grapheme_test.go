@@ -0,0 +1,68 @@
+package autocomplete
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestGraphemeClusters(t *testing.T) {
+	words := []string{"🇯🇵 tokyo", "🇯🇵🇫🇷", "🇫🇷 paris", "👍🏽 thumbs up", "👍 ok", "bike"}
+
+	service := testService(t, words, WithGraphemeClusters)
+
+	t.Run("complete", func(t *testing.T) {
+		results := service.Complete("🇯🇵")
+		sort.Strings(results)
+		assertWords(t, []string{"🇯🇵 tokyo", "🇯🇵🇫🇷"}, results)
+
+		// The skin toned thumbs up is a different character.
+		assertWords(t, []string{"👍 ok"}, service.Complete("👍"))
+		assertWords(t, []string{"👍🏽 thumbs up"}, service.Complete("👍🏽"))
+
+		// Half a flag isn't a character we've stored.
+		if len(service.Complete("🇯")) != 0 {
+			t.Errorf("Expected no results, got %v", service.Complete("🇯"))
+		}
+
+		assertWords(t, []string{"bike"}, service.Complete("bi"))
+	})
+
+	t.Run("contents", func(t *testing.T) {
+		contents := service.GetContents()
+		sort.Strings(contents)
+		expected := append([]string{}, words...)
+		sort.Strings(expected)
+		assertWords(t, expected, contents)
+
+		for _, word := range words {
+			if !service.Exists(word) {
+				t.Errorf("Expected %q to exist", word)
+			}
+		}
+	})
+
+	t.Run("visualize", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := service.store.Visualize(&buf); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if !strings.Contains(buf.String(), "🇯🇵") {
+			t.Errorf("Expected the flag to be rendered as a single node")
+		}
+	})
+
+	t.Run("rune mode splits clusters", func(t *testing.T) {
+		service := testService(t, words)
+		if len(service.Complete("🇯")) != 2 {
+			t.Errorf("Expected 2 results, got %v", service.Complete("🇯"))
+		}
+	})
+
+	t.Run("low memory mode", func(t *testing.T) {
+		if _, err := New(NewServiceConfig(WithGraphemeClusters, WithLowMemoryMode), nil); err == nil {
+			t.Errorf("Expected non-nil, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package autocomplete
+
+// lookupStore is implemented by the stores that can tell whether a prefix is
+// a stored word while walking to its completions.
+type lookupStore interface {
+	// lookup returns whether prefix is a stored word, along with every
+	// entry starting with prefix.
+	lookup(prefix string) (bool, []entry)
+}
+
+// storeLookup returns whether prefix is stored along with its entries,
+// falling back to a separate Contains() call for the other stores.
+func storeLookup(store autocompleter, prefix string) (bool, []entry) {
+	if ls, ok := store.(lookupStore); ok {
+		return ls.lookup(prefix)
+	}
+	return store.Contains(prefix), storeEntries(store, prefix)
+}
+
+// lookup merges the lookups of both tiers, the primary wins for the words
+// stored in both.
+func (s *spillStore) lookup(prefix string) (bool, []entry) {
+	exists, results := storeLookup(s.primary, prefix)
+	seen := make(map[string]struct{}, len(results))
+	for _, e := range results {
+		seen[e.word] = struct{}{}
+	}
+
+	secondaryExists, secondary := storeLookup(s.secondary, prefix)
+	for _, e := range secondary {
+		if _, ok := seen[e.word]; ok {
+			continue
+		}
+		results = append(results, e)
+	}
+	return exists || secondaryExists, results
+}
+
+// Lookup returns whether prefix is itself a stored word, along with its
+// completions ordered like Complete(). Both are found in a single walk of the
+// store, which makes it cheaper than calling Exists() and Complete(), e.g. for
+// a shell completion deciding whether to append a space:
+//
+//	exists, completions := service.Lookup("bike")
+//	// true, [bike bike path]
+func (a *AutocompleteService) Lookup(prefix string) (exists bool, completions []string) {
+	if !a.acquire() {
+		return false, []string{}
+	}
+	defer a.release()
+
+	exists, entries := storeLookup(a.store, prefix)
+	return exists, a.completions(entries)
+}
@@ -0,0 +1,58 @@
+package autocomplete
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "beach"}
+	for _, opts := range [][]ConfigFn{{}, {WithBackend(BackendTST)}, {WithBackend(BackendRadix)}, {WithSpillStore(newTrie(), 2)}, {WithStore(&countingStore{autocompleter: newTrie()})}} {
+		service := testService(t, words, opts...)
+		name := service.Stats().Backend
+
+		// A word with longer words under it.
+		exists, completions := service.Lookup("bike")
+		if !exists {
+			t.Errorf("%s: expected %q to exist", name, "bike")
+		}
+		assertWords(t, []string{"bike", "bike path"}, completions)
+
+		// Only a prefix, which ends in the middle of an edge in the radix tree.
+		exists, completions = service.Lookup("bik")
+		if exists {
+			t.Errorf("%s: expected %q to not exist", name, "bik")
+		}
+		assertWords(t, []string{"bike", "bike path"}, completions)
+
+		exists, completions = service.Lookup("beach")
+		if !exists {
+			t.Errorf("%s: expected %q to exist", name, "beach")
+		}
+		assertWords(t, []string{"beach"}, completions)
+
+		for _, prefix := range []string{"", "car", "bikes"} {
+			exists, completions = service.Lookup(prefix)
+			if exists {
+				t.Errorf("%s: expected %q to not exist", name, prefix)
+			}
+			if prefix != "" && len(completions) != 0 {
+				t.Errorf("%s: expected no completions for %q, got %v", name, prefix, completions)
+			}
+		}
+
+		// Ordered like Complete().
+		service.AddWeighted("bike path", 2)
+		_, completions = service.Lookup("bi")
+		assertWords(t, service.Complete("bi"), completions)
+	}
+}
+
+func TestLookupCaseInsensitive(t *testing.T) {
+	for _, opts := range [][]ConfigFn{{}, {WithBackend(BackendTST)}, {WithBackend(BackendRadix)}} {
+		service := testService(t, []string{"Bike", "Bike Path"}, append(opts, WithCaseInsensitive)...)
+
+		exists, completions := service.Lookup("BIKE")
+		if !exists {
+			t.Errorf("Expected %q to exist", "BIKE")
+		}
+		assertWords(t, []string{"Bike", "Bike Path"}, completions)
+	}
+}
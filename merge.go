@@ -0,0 +1,84 @@
+package autocomplete
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MergeFrom inserts every word of other into the service, e.g. to combine
+// indexes built in parallel. The services can use different backends.
+//
+// A word stored by both keeps the higher of its two weights rather than their
+// sum, so merging the same service twice doesn't inflate the weights. Stores
+// that don't keep weights only get the words. Words longer than MaxWordLength
+// are skipped.
+//
+// The words of other are copied before the service is modified, so the two
+// services are never locked at once and other can be the service itself.
+func (a *AutocompleteService) MergeFrom(other *AutocompleteService) error {
+	if other == nil {
+		return errors.New("autocompleteservice: mergefrom: nil service")
+	}
+
+	merged, err := other.mergeEntries()
+	if err != nil {
+		return err
+	}
+
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: mergefrom: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	opts := a.Config.storeOptions()
+	weights := a.mergeWeights()
+	for _, e := range merged {
+		// Stored words aren't inserted again, TrackHits would count them.
+		if _, ok := weights[opts.key(e.text(e.word))]; !ok {
+			a.add(e.text(e.word))
+		}
+	}
+
+	if ws, ok := a.store.(weightStore); ok {
+		weights = a.mergeWeights()
+		for _, e := range merged {
+			word := e.text(e.word)
+			if weight, ok := weights[opts.key(word)]; ok && e.weight > weight {
+				ws.addWeight(word, e.weight-weight)
+			}
+		}
+	}
+	a.cache.invalidate()
+	a.markUpdated()
+	return nil
+}
+
+// mergeEntries returns every stored word along with its bookkeeping, see
+// MergeFrom().
+func (a *AutocompleteService) mergeEntries() ([]entry, error) {
+	if !a.acquire() {
+		return nil, fmt.Errorf("autocompleteservice: mergefrom: source: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	if es, ok := a.store.(entryStore); ok {
+		return es.entries(""), nil
+	}
+	words := a.store.ListContents()
+	entries := make([]entry, len(words))
+	for i, word := range words {
+		entries[i] = entry{word: word}
+	}
+	return entries, nil
+}
+
+// mergeWeights returns the weight of every stored word by key, see
+// MergeFrom().
+func (a *AutocompleteService) mergeWeights() map[string]int {
+	opts := a.Config.storeOptions()
+	weights := make(map[string]int)
+	for _, e := range storeEntries(a.store, "") {
+		weights[opts.key(e.text(e.word))] = e.weight
+	}
+	return weights
+}
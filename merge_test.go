@@ -0,0 +1,84 @@
+package autocomplete
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMergeFrom(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		other []ConfigFn
+	}{
+		{"same backend", nil},
+		{"tst into trie", []ConfigFn{WithBackend(BackendTST)}},
+		{"radix into trie", []ConfigFn{WithBackend(BackendRadix)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(t, []string{"beach", "bike"}, WithSuggestionCache(4))
+			other := testService(t, []string{"bike", "bike path", "pool"}, tt.other...)
+			service.AddWeighted("bike", 5)
+			other.AddWeighted("bike", 2)
+			other.AddWeighted("pool", 3)
+			assertWords(t, []string{"bike"}, service.Complete("bi"))
+
+			if err := service.MergeFrom(other); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			assertWords(t, []string{"beach", "bike", "bike path", "pool"}, service.GetContents())
+			assertWords(t, []string{"bike", "bike path"}, service.Complete("bi"))
+			// The other service is left untouched.
+			assertWords(t, []string{"bike", "bike path", "pool"}, other.GetContents())
+
+			// Merging again changes nothing.
+			if err := service.MergeFrom(other); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			if got := service.Stats().WordCount; got != 4 {
+				t.Errorf("Expected 4 words, got %d", got)
+			}
+			for word, weight := range map[string]int{"bike": 5, "pool": 3, "beach": 0} {
+				if got := service.mergeWeights()[word]; got != weight {
+					t.Errorf("Expected %s to weigh %d, got %d", word, weight, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeFromTrackHits(t *testing.T) {
+	service := testService(t, []string{"bike"}, WithHitTracking)
+	other := testService(t, []string{"bike", "pool"}, WithHitTracking)
+
+	if err := service.MergeFrom(other); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if err := service.MergeFrom(service); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	for word, weight := range map[string]int{"bike": 1, "pool": 1} {
+		if got := service.mergeWeights()[word]; got != weight {
+			t.Errorf("Expected %s to weigh %d, got %d", word, weight, got)
+		}
+	}
+}
+
+func TestMergeFromClosed(t *testing.T) {
+	service := testService(t, []string{"bike"})
+	other := testService(t, []string{"pool"})
+
+	if err := service.MergeFrom(nil); err == nil {
+		t.Errorf("Expected an error for a nil service")
+	}
+
+	other.Close()
+	if err := service.MergeFrom(other); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+	assertWords(t, []string{"bike"}, service.GetContents())
+
+	service.Close()
+	if err := other.MergeFrom(service); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+}
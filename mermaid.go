@@ -0,0 +1,124 @@
+package autocomplete
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// mermaidVisualizer is implemented by the stores that can render themselves as
+// a Mermaid flowchart, see AutocompleteService.VisualizeMermaid().
+type mermaidVisualizer interface {
+	VisualizeMermaid(w io.Writer) error
+}
+
+// nodeIDs numbers the nodes of a diagram in the order they are first seen, so
+// the same tree always gets the same ids.
+type nodeIDs[T comparable] map[T]int
+
+func (ids nodeIDs[T]) id(node T) int {
+	if id, ok := ids[node]; ok {
+		return id
+	}
+	id := len(ids)
+	ids[node] = id
+	return id
+}
+
+// mermaidLabel quotes label, escaping the quotes that would end it early.
+func mermaidLabel(label string) string {
+	return `"` + strings.ReplaceAll(label, `"`, "#quot;") + `"`
+}
+
+// VisualizeMermaid writes the trie as a Mermaid flowchart, which renders in
+// Markdown without the graphviz toolchain. The nodes ending a word are marked
+// with a "*", like Visualize().
+func (t *trie) VisualizeMermaid(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.Root == nil {
+		return errors.New("trie visualizer: root is nil")
+	}
+
+	if _, err := fmt.Fprintln(w, "graph TD"); err != nil {
+		return err
+	}
+
+	ids := make(nodeIDs[*trieNode])
+	return t.walkNodes(t.Root, "root", func(node *trieNode, val string) error {
+		nodeId := ids.id(node)
+		if node.isEnd {
+			val += "*"
+		}
+		if _, err := fmt.Fprintf(w, "\tn%d[%s]\n", nodeId, mermaidLabel(val)); err != nil {
+			return err
+		}
+		for _, edge := range node.children {
+			if _, err := fmt.Fprintf(w, "\tn%d --> n%d\n", nodeId, ids.id(edge.node)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// VisualizeMermaid writes the tree as a Mermaid flowchart, which renders in
+// Markdown without the graphviz toolchain. The edges are labeled l, m and r
+// for the left, middle and right children, and the nodes ending a word are
+// marked with a "*", like Visualize().
+func (t *ternarysearchtree) VisualizeMermaid(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.Root == nil {
+		return errors.New("tst visualizer: root is nil")
+	}
+
+	if _, err := fmt.Fprintln(w, "graph TD"); err != nil {
+		return err
+	}
+
+	ids := make(nodeIDs[*tstNode])
+	return walkNodes(t.Root, func(node *tstNode) error {
+		nodeId := ids.id(node)
+		val := string(node.Char)
+		if node.Char == 0 {
+			val = "root"
+		}
+		if node.IsEnd {
+			val += " *"
+		}
+		if _, err := fmt.Fprintf(w, "\tn%d[%s]\n", nodeId, mermaidLabel(val)); err != nil {
+			return err
+		}
+
+		for _, child := range []struct {
+			label string
+			node  *tstNode
+		}{{"l", node.Left}, {"m", node.Mid}, {"r", node.Right}} {
+			if child.node == nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "\tn%d -->|%s| n%d\n", nodeId, child.label, ids.id(child.node)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// VisualizeMermaid writes the store as a Mermaid flowchart, see
+// DisplayGraph() for the graphviz equivalent. Only the trie and the ternary
+// search tree backends support it.
+func (a *AutocompleteService) VisualizeMermaid(w io.Writer) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: visualizemermaid: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	v, ok := a.store.(mermaidVisualizer)
+	if !ok {
+		return fmt.Errorf("autocompleteservice: visualizemermaid: the %s backend doesn't support mermaid diagrams", backendName(a.store))
+	}
+	return v.VisualizeMermaid(w)
+}
@@ -0,0 +1,109 @@
+package autocomplete
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	mermaidNode = regexp.MustCompile(`^\tn(\d+)\["([^"]*)"\]$`)
+	mermaidEdge = regexp.MustCompile(`^\tn(\d+) -->(?:\|([lmr])\|)? n(\d+)$`)
+)
+
+// parseMermaid checks the structure of a flowchart and returns its node labels
+// by id and its edges.
+func parseMermaid(t *testing.T, diagram string) (map[string]string, [][2]string) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(diagram, "\n"), "\n")
+	if lines[0] != "graph TD" {
+		t.Fatalf("Expected a graph TD header, got %q", lines[0])
+	}
+
+	labels := make(map[string]string)
+	var edges [][2]string
+	for _, line := range lines[1:] {
+		if m := mermaidNode.FindStringSubmatch(line); m != nil {
+			if _, ok := labels[m[1]]; ok {
+				t.Errorf("Expected node n%s to be defined once", m[1])
+			}
+			labels[m[1]] = m[2]
+		} else if m := mermaidEdge.FindStringSubmatch(line); m != nil {
+			edges = append(edges, [2]string{m[1], m[3]})
+		} else {
+			t.Errorf("Unexpected line %q", line)
+		}
+	}
+	for _, edge := range edges {
+		if _, ok := labels[edge[0]]; !ok {
+			t.Errorf("Expected the edge from undefined node n%s to point from a node", edge[0])
+		}
+		if _, ok := labels[edge[1]]; !ok {
+			t.Errorf("Expected the edge to undefined node n%s to point to a node", edge[1])
+		}
+	}
+	return labels, edges
+}
+
+func TestVisualizeMermaid(t *testing.T) {
+	words := []string{"bike", "bin", "be"}
+
+	for _, tt := range []struct {
+		name  string
+		opts  []ConfigFn
+		root  string
+		nodes int
+	}{
+		{"trie", nil, "root", 7},
+		// The ternary search tree has no empty root.
+		{"tst", []ConfigFn{WithBackend(BackendTST)}, "b", 6},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(t, words, tt.opts...)
+
+			var buf bytes.Buffer
+			if err := service.VisualizeMermaid(&buf); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			labels, edges := parseMermaid(t, buf.String())
+
+			if labels["0"] != tt.root {
+				t.Errorf("Expected n0 to be the root %q, got %q", tt.root, labels["0"])
+			}
+			// Every node but the root has a parent.
+			if len(labels) != tt.nodes || len(edges) != tt.nodes-1 {
+				t.Errorf("Expected %d nodes and %d edges, got %d and %d", tt.nodes, tt.nodes-1, len(labels), len(edges))
+			}
+			ends := 0
+			for _, label := range labels {
+				if strings.HasSuffix(label, "*") {
+					ends++
+				}
+			}
+			if ends != len(words) {
+				t.Errorf("Expected %d end markers, got %d", len(words), ends)
+			}
+
+			var again bytes.Buffer
+			service.VisualizeMermaid(&again)
+			if buf.String() != again.String() {
+				t.Errorf("Expected the same diagram twice, got\n%s\nand\n%s", buf.String(), again.String())
+			}
+		})
+	}
+
+	if got := mermaidLabel(`say "hi"`); got != `"say #quot;hi#quot;"` {
+		t.Errorf("Expected the quotes to be escaped, got %s", got)
+	}
+
+	service := testService(t, words, WithBackend(BackendRadix))
+	if err := service.VisualizeMermaid(&bytes.Buffer{}); err == nil {
+		t.Errorf("Expected an error for the radix backend")
+	}
+	service.Close()
+	if err := service.VisualizeMermaid(&bytes.Buffer{}); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected ErrServiceClosed, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package autocomplete
+
+// metaStore is implemented by the stores that can attach a payload to their
+// words.
+type metaStore interface {
+	// setMeta replaces the payload of word, false if it isn't stored.
+	setMeta(word string, meta any) bool
+}
+
+// Entry is a completion along with the payload attached to it with
+// InsertMeta(), nil when there is none.
+type Entry struct {
+	Word string
+	Meta any
+}
+
+// InsertMeta inserts the word if needed, then attaches meta to it, e.g. a
+// category or an ID to render a rich dropdown. Inserting the word again with
+// InsertMeta replaces the payload, while the other inserts keep it.
+//
+// Payloads are only supported by the trie, the ternary search tree and the
+// radix backends. They are kept in memory only, snapshots don't save them.
+func (a *AutocompleteService) InsertMeta(word string, meta any) {
+	if word == "" {
+		return
+	}
+	if !a.acquire() {
+		return
+	}
+	defer a.release()
+
+	if !a.add(word) {
+		return
+	}
+	if ms, ok := a.store.(metaStore); ok {
+		ms.setMeta(word, meta)
+		a.cache.invalidate()
+	}
+}
+
+// CompleteRich behaves like Complete, but returns the payload of every
+// completion along with it, see InsertMeta().
+func (a *AutocompleteService) CompleteRich(prefix string) []Entry {
+	if !a.acquire() {
+		return []Entry{}
+	}
+	defer a.release()
+	a.queries.record(prefix)
+
+	entries := storeEntries(a.store, prefix)
+	rankEntries(entries, false)
+
+	results := make([]Entry, len(entries))
+	for i, e := range entries {
+		results[i] = Entry{Word: e.word, Meta: e.meta}
+	}
+	return results
+}
+
+func (s *spillStore) setMeta(word string, meta any) bool {
+	for _, store := range []autocompleter{s.primary, s.secondary} {
+		if ms, ok := store.(metaStore); ok && ms.setMeta(word, meta) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *trie) setMeta(word string, meta any) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	curr := t.prefixNode(word)
+	if curr == nil || !curr.isEnd {
+		return false
+	}
+	curr.meta = meta
+	return true
+}
+
+func (t *ternarysearchtree) setMeta(word string, meta any) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if word == "" {
+		return false
+	}
+	node := t.contains(t.Root, t.runes(word), 0)
+	if node == nil || !node.IsEnd {
+		return false
+	}
+	node.meta = meta
+	return true
+}
+
+func (t *radixTree) setMeta(word string, meta any) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	curr := t.find(t.key(word))
+	if curr == nil || !curr.isEnd {
+		return false
+	}
+	curr.meta = meta
+	return true
+}
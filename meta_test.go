@@ -0,0 +1,54 @@
+package autocomplete
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompleteRich(t *testing.T) {
+	type product struct {
+		ID       int
+		Category string
+	}
+
+	for _, opts := range [][]ConfigFn{nil, {WithBackend(BackendTST)}, {WithBackend(BackendRadix)}} {
+		service := testService(t, []string{"bike path"}, opts...)
+		service.InsertMeta("bike", product{ID: 1, Category: "outdoors"})
+		service.InsertMeta("bicycle repair", map[string]string{"category": "services"})
+		service.AddWeighted("bicycle repair", 2)
+
+		want := []Entry{
+			{Word: "bicycle repair", Meta: map[string]string{"category": "services"}},
+			{Word: "bike", Meta: product{ID: 1, Category: "outdoors"}},
+			// Never given any.
+			{Word: "bike path", Meta: nil},
+		}
+		if got := service.CompleteRich("bi"); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		assertWords(t, []string{"bicycle repair", "bike", "bike path"}, service.Complete("bi"))
+
+		// A plain insert keeps the payload, InsertMeta replaces it.
+		service.Add("bike")
+		if got := service.CompleteRich("bike")[0].Meta; got != (product{ID: 1, Category: "outdoors"}) {
+			t.Errorf("Expected the payload to be kept, got %v", got)
+		}
+		service.InsertMeta("bike", product{ID: 2})
+		if got := service.CompleteRich("bike")[0].Meta; got != (product{ID: 2}) {
+			t.Errorf("Expected the payload to be replaced, got %v", got)
+		}
+
+		// Dropped along with the word.
+		service.Remove("bike")
+		service.Add("bike")
+		if got := service.CompleteRich("bike")[0].Meta; got != nil {
+			t.Errorf("Expected no payload once the word was removed, got %v", got)
+		}
+	}
+
+	service := testService(t, nil)
+	service.Close()
+	if got := service.CompleteRich("bi"); len(got) != 0 {
+		t.Errorf("Expected no entries once closed, got %v", got)
+	}
+}
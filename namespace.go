@@ -0,0 +1,85 @@
+package autocomplete
+
+import (
+	"sort"
+)
+
+// Namespaces allow keeping separate sets of words in the same service, e.g.
+// a "user" namespace with the words a user has typed before and a "global"
+// namespace shared by everyone. Each namespace gets its own store of the
+// type selected by the config, separate from the main store.
+
+// namespace returns the store for ns, creating it when create is set.
+func (a *AutocompleteService) namespace(ns string, create bool) autocompleter {
+	a.nsMu.RLock()
+	store, ok := a.namespaces[ns]
+	a.nsMu.RUnlock()
+	if ok || !create {
+		return store
+	}
+
+	a.nsMu.Lock()
+	defer a.nsMu.Unlock()
+
+	if store, ok := a.namespaces[ns]; ok {
+		return store
+	}
+	if a.namespaces == nil {
+		a.namespaces = make(map[string]autocompleter)
+	}
+	store = newStore(a.Config)
+	a.namespaces[ns] = store
+	return store
+}
+
+// AddNS adds the word to the namespace ns, creating the namespace if needed.
+func (a *AutocompleteService) AddNS(ns, word string) {
+	if !a.acquire() {
+		return
+	}
+	defer a.release()
+	a.namespace(ns, true).Insert(word)
+}
+
+// CompleteNS returns the completions for prefix in the namespace ns, sorted
+// lexically. An unknown namespace has no completions.
+func (a *AutocompleteService) CompleteNS(ns, prefix string) []string {
+	if !a.acquire() {
+		return []string{}
+	}
+	defer a.release()
+
+	store := a.namespace(ns, false)
+	if store == nil {
+		return []string{}
+	}
+
+	results := store.Autocomplete(prefix)
+	sort.Strings(results)
+	return results
+}
+
+// CompleteAcrossNS blends the completions of several namespaces. It takes up to
+// perNS completions from each namespace in nsOrder, so earlier namespaces rank
+// above later ones, skipping words already returned by an earlier namespace.
+// Leave perNS 0 for no limit.
+func (a *AutocompleteService) CompleteAcrossNS(prefix string, nsOrder []string, perNS int) []string {
+	results := []string{}
+	seen := make(map[string]struct{})
+
+	for _, ns := range nsOrder {
+		taken := 0
+		for _, word := range a.CompleteNS(ns, prefix) {
+			if perNS > 0 && taken == perNS {
+				break
+			}
+			if _, ok := seen[word]; ok {
+				continue
+			}
+			seen[word] = struct{}{}
+			results = append(results, word)
+			taken++
+		}
+	}
+	return results
+}
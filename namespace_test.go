@@ -0,0 +1,45 @@
+package autocomplete
+
+import (
+	"testing"
+)
+
+func TestCompleteAcrossNS(t *testing.T) {
+	service := testService(t, nil)
+
+	for _, word := range []string{"bike", "bike path", "bicycle repair", "bin"} {
+		service.AddNS("global", word)
+	}
+	for _, word := range []string{"bingo", "bike", "bird"} {
+		service.AddNS("user", word)
+	}
+
+	// Namespaces are kept out of the main store.
+	if len(service.Complete("bi")) != 0 {
+		t.Errorf("Expected no results in the main store, got %v", service.Complete("bi"))
+	}
+
+	expected := []string{"bike", "bingo", "bicycle repair", "bike path"}
+	assertWords(t, expected, service.CompleteAcrossNS("bi", []string{"user", "global"}, 2))
+
+	expected = []string{"bicycle repair", "bike", "bike path", "bin", "bingo", "bird"}
+	assertWords(t, expected, service.CompleteAcrossNS("bi", []string{"global", "unknown", "user"}, 0))
+
+	assertWords(t, []string{}, service.CompleteNS("unknown", "bi"))
+}
+
+func TestClearNS(t *testing.T) {
+	service := testService(t, nil)
+	service.AddNS("user", "bike")
+
+	service.Clear(false)
+	assertWords(t, []string{}, service.CompleteNS("user", "bi"))
+
+	// Nor do they survive closing the service.
+	service.AddNS("user", "bike")
+	service.Close()
+	if err := service.Reopen(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, []string{}, service.CompleteNS("user", "bi"))
+}
@@ -0,0 +1,66 @@
+package autocomplete
+
+import (
+	"strings"
+	"unicode"
+)
+
+// storeOptions are the options applied by the stores created by the service
+// themselves, so they hold for every insert and lookup, loads included.
+type storeOptions struct {
+	// trackHits adds one to the weight of a word on every insert.
+	trackHits bool
+	// foldCase stores words under their lower cased keys, see
+	// WithCaseInsensitive().
+	foldCase bool
+	// compactSpace collapses whitespace runs, see WithWhitespaceCompaction().
+	compactSpace bool
+}
+
+func (c *ServiceConfig) storeOptions() storeOptions {
+	return storeOptions{
+		trackHits:    c.TrackHits,
+		foldCase:     c.CaseInsensitive,
+		compactSpace: c.CompactWhitespace,
+	}
+}
+
+// normalize returns the form word is stored and displayed with.
+func (o storeOptions) normalize(word string) string {
+	if o.compactSpace {
+		word = compactWhitespace(word)
+	}
+	return word
+}
+
+// key returns the form word is stored under.
+func (o storeOptions) key(word string) string {
+	word = o.normalize(word)
+	if o.foldCase {
+		word = strings.ToLower(word)
+	}
+	return word
+}
+
+// compactWhitespace collapses every run of whitespace in s into a single space.
+func compactWhitespace(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,634 @@
+package autocomplete
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var _ autocompleter = (*radixTree)(nil)
+
+// radixNode is a node of a radix (patricia) tree: chains of nodes with a
+// single child are collapsed into one edge labelled with the whole chain, so
+// a long word with no branching costs a single node instead of one per rune.
+type radixNode struct {
+	// Kept sorted by the first rune of their label, which is unique among
+	// siblings, so walking the children visits the words in lexical order.
+	children []radixEdge
+	isEnd    bool
+
+	// only meaningful when isEnd is set.
+	wordData
+}
+
+type radixEdge struct {
+	// label is never empty.
+	label string
+	node  *radixNode
+}
+
+// firstRune returns the rune the edges are sorted by.
+func firstRune(label string) rune {
+	r, _ := utf8.DecodeRuneInString(label)
+	return r
+}
+
+// search returns the index of the child whose label starts with r, or where
+// it would be inserted.
+func (n *radixNode) search(r rune) int {
+	lo, hi := 0, len(n.children)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if firstRune(n.children[mid].label) < r {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// edge returns the index of the child whose label starts with the first rune
+// of key, or -1.
+func (n *radixNode) edge(key string) int {
+	r := firstRune(key)
+	i := n.search(r)
+	if i < len(n.children) && firstRune(n.children[i].label) == r {
+		return i
+	}
+	return -1
+}
+
+// commonPrefix returns the length in bytes of the longest common prefix of a
+// and b, cut to a rune boundary so a label is never split mid rune.
+func commonPrefix(a, b string) int {
+	n := min(len(a), len(b))
+	i := 0
+	for i < n && a[i] == b[i] {
+		i++
+	}
+	for i > 0 && i < len(a) && !utf8.RuneStart(a[i]) {
+		i--
+	}
+	return i
+}
+
+type radixTree struct {
+	Root *radixNode
+
+	// seq is the last insertion sequence number handed out.
+	seq uint64
+
+	// count is the number of words stored, kept up to date on every insert
+	// and delete.
+	count int
+
+	// applied on every insert and lookup, see normalize.go.
+	storeOptions
+
+	mu sync.RWMutex
+}
+
+func newRadixTree() *radixTree {
+	return &radixTree{
+		Root: &radixNode{},
+	}
+}
+
+// find returns the node at the end of key, or nil if key doesn't end on a
+// node.
+func (t *radixTree) find(key string) *radixNode {
+	curr := t.Root
+	for key != "" {
+		i := curr.edge(key)
+		if i < 0 || !strings.HasPrefix(key, curr.children[i].label) {
+			return nil
+		}
+		key = key[len(curr.children[i].label):]
+		curr = curr.children[i].node
+	}
+	return curr
+}
+
+// prefixNode returns the first node whose path starts with prefix, along with
+// the rest of its path past prefix. The prefix may end in the middle of an
+// edge, in which case rest is the remainder of the label. node is nil if no
+// stored word starts with prefix.
+func (t *radixTree) prefixNode(prefix string) (node *radixNode, rest string) {
+	key := t.key(prefix)
+	curr := t.Root
+	for key != "" {
+		i := curr.edge(key)
+		if i < 0 {
+			return nil, ""
+		}
+		label := curr.children[i].label
+		if strings.HasPrefix(key, label) {
+			key = key[len(label):]
+			curr = curr.children[i].node
+			continue
+		}
+		if strings.HasPrefix(label, key) {
+			return curr.children[i].node, label[len(key):]
+		}
+		return nil, ""
+	}
+	return curr, ""
+}
+
+func (t *radixTree) Insert(word string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.insert(word)
+}
+
+// InsertReport inserts word and reports whether it was newly added, false
+// when it was already stored or is empty.
+func (t *radixTree) InsertReport(word string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.insert(word)
+}
+
+// InsertBatch inserts every word under a single lock.
+func (t *radixTree) InsertBatch(words []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, word := range words {
+		t.insert(word)
+	}
+}
+
+// insert inserts word and reports whether it was newly added, the caller must
+// hold the lock.
+func (t *radixTree) insert(word string) bool {
+	// Would mark the root as a word.
+	word = t.normalize(word)
+	if word == "" {
+		return false
+	}
+
+	if t.Root == nil {
+		t.Root = &radixNode{}
+	}
+
+	curr := t.Root
+	key := t.key(word)
+	for key != "" {
+		i := curr.edge(key)
+		if i < 0 {
+			// Nothing shares the first rune, the rest of the key becomes
+			// a single edge.
+			leaf := &radixNode{}
+			i = curr.search(firstRune(key))
+			curr.children = append(curr.children, radixEdge{})
+			copy(curr.children[i+1:], curr.children[i:])
+			curr.children[i] = radixEdge{label: key, node: leaf}
+			curr = leaf
+			break
+		}
+
+		edge := &curr.children[i]
+		n := commonPrefix(edge.label, key)
+		if n < len(edge.label) {
+			// The key leaves the edge midway, split it where they part.
+			mid := &radixNode{children: []radixEdge{{label: edge.label[n:], node: edge.node}}}
+			edge.label = edge.label[:n]
+			edge.node = mid
+		}
+		key = key[n:]
+		curr = edge.node
+	}
+
+	// Keep the first seen form, e.g. "Bike" when "bike" is inserted after.
+	if t.foldCase && !curr.isEnd {
+		curr.display = word
+	}
+	added := !curr.isEnd
+	if added {
+		t.count++
+	}
+	curr.isEnd = true
+	t.seq++
+	curr.seq = t.seq
+	if t.trackHits {
+		curr.weight++
+	}
+	return added
+}
+
+// Delete unmarks the end of the word, then prunes the node if it no longer
+// leads to any word and merges back the chains left with a single child, so
+// the tree stays as compact as if the word had never been inserted.
+func (t *radixTree) Delete(word string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := t.key(word)
+	if key == "" || t.Root == nil {
+		return false
+	}
+
+	// parents[i] is the parent of the node reached through its edge
+	// indexes[i].
+	var parents []*radixNode
+	var indexes []int
+	curr := t.Root
+	for key != "" {
+		i := curr.edge(key)
+		if i < 0 || !strings.HasPrefix(key, curr.children[i].label) {
+			return false
+		}
+		parents = append(parents, curr)
+		indexes = append(indexes, i)
+		key = key[len(curr.children[i].label):]
+		curr = curr.children[i].node
+	}
+
+	if !curr.isEnd {
+		return false
+	}
+	curr.isEnd = false
+	curr.wordData = wordData{}
+	t.count--
+
+	parent := parents[len(parents)-1]
+	i := indexes[len(indexes)-1]
+	switch len(curr.children) {
+	case 0:
+		parent.children = append(parent.children[:i], parent.children[i+1:]...)
+		// The parent may be left as a chain link, unless it is the root.
+		if len(parents) > 1 {
+			t.merge(parents[len(parents)-2], indexes[len(indexes)-2])
+		}
+	case 1:
+		t.merge(parent, i)
+	}
+
+	return true
+}
+
+// merge folds the child reached through parent.children[i] into its edge when
+// it is no longer a word and has a single child left.
+func (t *radixTree) merge(parent *radixNode, i int) {
+	edge := &parent.children[i]
+	node := edge.node
+	if node.isEnd || len(node.children) != 1 {
+		return
+	}
+	edge.label += node.children[0].label
+	edge.node = node.children[0].node
+}
+
+func (t *radixTree) Autocomplete(prefix string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	prefix = t.normalize(prefix)
+
+	var results []string
+
+	curr, rest := t.prefixNode(prefix)
+	if curr == nil {
+		return results
+	}
+
+	t.visit(curr, prefix+rest, func(word string, _ *radixNode) {
+		results = append(results, word)
+	})
+
+	return results
+}
+
+// visit calls fn with every word (and its terminal node) in the subtree of
+// node, in lexical order. It walks with an explicit stack like the trie.
+func (t *radixTree) visit(node *radixNode, prefix string, fn func(word string, node *radixNode)) {
+	if node.isEnd {
+		fn(node.text(prefix), node)
+	}
+
+	type frame struct {
+		node  *radixNode
+		label string
+		// depth is the length of the word up to the parent of node.
+		depth int
+	}
+
+	word := []byte(prefix)
+	var stack []frame
+	push := func(parent *radixNode, depth int) {
+		// Pushed in reverse, so the smallest label is popped first.
+		for i := len(parent.children) - 1; i >= 0; i-- {
+			edge := parent.children[i]
+			stack = append(stack, frame{node: edge.node, label: edge.label, depth: depth})
+		}
+	}
+
+	push(node, len(word))
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		word = append(word[:f.depth], f.label...)
+		if f.node.isEnd {
+			fn(f.node.text(string(word)), f.node)
+		}
+		push(f.node, len(word))
+	}
+}
+
+func (t *radixTree) entries(prefix string) []entry {
+	_, results := t.lookup(prefix)
+	return results
+}
+
+// lookup returns whether prefix is a stored word along with the entries
+// starting with it. The prefix is a word when it ends exactly on a word node,
+// not in the middle of an edge.
+func (t *radixTree) lookup(prefix string) (bool, []entry) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	prefix = t.normalize(prefix)
+
+	var results []entry
+
+	curr, rest := t.prefixNode(prefix)
+	if curr == nil {
+		return false, results
+	}
+
+	t.visit(curr, prefix+rest, func(word string, node *radixNode) {
+		results = append(results, entry{word: word, wordData: node.wordData})
+	})
+
+	return rest == "" && curr.isEnd, results
+}
+
+func (t *radixTree) addWeight(word string, delta int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	curr := t.find(t.key(word))
+	if curr == nil || !curr.isEnd {
+		return false
+	}
+	curr.weight += delta
+	return true
+}
+
+func (t *radixTree) resetWeights(base int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.visit(t.Root, "", func(_ string, node *radixNode) {
+		node.weight = base
+	})
+}
+
+func (t *radixTree) addTags(word string, tags []string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	curr := t.find(t.key(word))
+	if curr == nil || !curr.isEnd {
+		return false
+	}
+	curr.tags = mergeTags(curr.tags, tags)
+	return true
+}
+
+func (t *radixTree) Count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.count
+}
+
+func (t *radixTree) PrefixCount(prefix string) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if prefix == "" {
+		return t.count
+	}
+
+	curr, _ := t.prefixNode(t.normalize(prefix))
+	if curr == nil {
+		return 0
+	}
+
+	count := 0
+	stack := []*radixNode{curr}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node.isEnd {
+			count++
+		}
+		for _, edge := range node.children {
+			stack = append(stack, edge.node)
+		}
+	}
+	return count
+}
+
+// NodeCount doesn't count the root, which holds no label.
+func (t *radixTree) NodeCount() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.Root == nil {
+		return 0
+	}
+
+	count := 0
+	stack := []*radixNode{t.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		count += len(node.children)
+		for _, edge := range node.children {
+			stack = append(stack, edge.node)
+		}
+	}
+	return count
+}
+
+func (t *radixTree) Contains(word string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.Root == nil {
+		return false
+	}
+	curr := t.find(t.key(word))
+	return curr != nil && curr.isEnd
+}
+
+func (t *radixTree) ListContents() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var results []string
+
+	if t.Root == nil {
+		return results
+	}
+
+	t.visit(t.Root, "", func(word string, _ *radixNode) {
+		results = append(results, word)
+	})
+
+	return results
+}
+
+// Make the root empty, removing all references to the old data.
+func (t *radixTree) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Root = &radixNode{}
+	t.count = 0
+}
+
+func (t *radixTree) clone() autocompleter {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	c := &radixTree{
+		Root:         &radixNode{},
+		seq:          t.seq,
+		count:        t.count,
+		storeOptions: t.storeOptions,
+	}
+
+	// Copied with an explicit stack, like visit().
+	type pair struct{ src, dst *radixNode }
+	stack := []pair{{t.Root, c.Root}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		p.dst.isEnd = p.src.isEnd
+		p.dst.wordData = p.src.wordData
+		if len(p.src.children) == 0 {
+			continue
+		}
+
+		p.dst.children = make([]radixEdge, len(p.src.children))
+		for i, edge := range p.src.children {
+			child := &radixNode{}
+			p.dst.children[i] = radixEdge{label: edge.label, node: child}
+			stack = append(stack, pair{edge.node, child})
+		}
+	}
+
+	return c
+}
+
+func (t *radixTree) verify() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.Root == nil {
+		return errors.New("radix: root is nil")
+	}
+	if t.Root.isEnd {
+		return errors.New("radix: root is marked as a word")
+	}
+
+	words := 0
+	stack := []*radixNode{t.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node.isEnd {
+			words++
+		} else if node != t.Root {
+			if len(node.children) == 0 {
+				return errors.New("radix: leaf node is not the end of a word")
+			}
+			if len(node.children) == 1 {
+				return fmt.Errorf("radix: node before %q has a single child and should be merged", node.children[0].label)
+			}
+		}
+
+		for i, edge := range node.children {
+			if edge.label == "" {
+				return errors.New("radix: empty edge label")
+			}
+			if edge.node == nil {
+				return fmt.Errorf("radix: child %q is nil", edge.label)
+			}
+			if i > 0 && firstRune(node.children[i-1].label) >= firstRune(edge.label) {
+				return fmt.Errorf("radix: children %q and %q are out of order", node.children[i-1].label, edge.label)
+			}
+			stack = append(stack, edge.node)
+		}
+	}
+
+	if words != t.count {
+		return fmt.Errorf("radix: count is %d, but %d words are stored", t.count, words)
+	}
+	return nil
+}
+
+func (t *radixTree) Visualize(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.Root == nil {
+		return errors.New("radix visualizer: root is nil")
+	}
+
+	nodeAttrs := `[color=lightblue fillcolor=lightblue fontcolor=black shape=record style="filled, rounded"]`
+	// write header
+	if _, err := fmt.Fprintln(w, "digraph {"); err != nil {
+		return err
+	}
+
+	// write node attributes
+	if _, err := fmt.Fprintf(w, "\tnode %s\n", nodeAttrs); err != nil {
+		return err
+	}
+
+	// Walk pre order and call dotwrite func. The nodes are numbered as they are
+	// walked, so the same tree is always written the same way.
+	if err := t.writeDot(w, t.Root, "root", make(nodeIDs[*radixNode])); err != nil {
+		return err
+	}
+
+	// write closing bracket
+	if _, err := fmt.Fprintln(w, "}"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (t *radixTree) writeDot(w io.Writer, node *radixNode, val string, ids nodeIDs[*radixNode]) error {
+	if node == nil {
+		return nil
+	}
+
+	nodeId := ids.id(node)
+	var endLabel string
+	if node.isEnd {
+		endLabel = "*"
+	}
+	if _, err := fmt.Fprintf(w, "\t%d [label=\"<l>|<v> %s%s|<r>\"]\n", nodeId, val, endLabel); err != nil {
+		return err
+	}
+	for _, edge := range node.children {
+		if _, err := fmt.Fprintf(w, "\t%d:v -> %d:v\n", nodeId, ids.id(edge.node)); err != nil {
+			return err
+		}
+		if err := t.writeDot(w, edge.node, edge.label, ids); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
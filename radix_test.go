@@ -0,0 +1,310 @@
+package autocomplete
+
+import (
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRadixTree(t *testing.T) {
+	radix := newRadixTree()
+
+	words := []string{"bike", "bike path", "bicycle repair", "pool", "beach", "waterfront", "dog park", "resteraunts"}
+
+	for _, word := range words {
+		radix.Insert(word)
+	}
+
+	if contents := radix.ListContents(); len(contents) != len(words) {
+		t.Errorf("Expected %d words, got %v", len(words), contents)
+	}
+
+	assertWords(t, []string{"bicycle repair", "bike", "bike path"}, radix.Autocomplete("bi"))
+	// Ending in the middle of an edge.
+	assertWords(t, []string{"bike", "bike path"}, radix.Autocomplete("bik"))
+	assertWords(t, []string{"bike path"}, radix.Autocomplete("bike p"))
+	if results := radix.Autocomplete("bikes"); len(results) != 0 {
+		t.Errorf("Expected no results, got %v", results)
+	}
+
+	for _, word := range words {
+		if !radix.Contains(word) {
+			t.Errorf("Expected %q to be stored", word)
+		}
+	}
+	for _, word := range []string{"", "b", "bik", "bike p", "bikes"} {
+		if radix.Contains(word) {
+			t.Errorf("Expected %q to not be stored", word)
+		}
+	}
+
+	if got := radix.PrefixCount("bik"); got != 2 {
+		t.Errorf("Expected 2 words, got %d", got)
+	}
+
+	// Test visualizer
+	dotFile, err := os.Create("radix.dot")
+	if err != nil {
+		t.Errorf("Error creating dot file: %v", err)
+	}
+	defer dotFile.Close()
+
+	if err := radix.Visualize(dotFile); err != nil {
+		t.Errorf("Error visualizing radix tree: %v", err)
+	}
+
+	os.Remove("radix.dot")
+
+	radix.Clear()
+	if radix.Count() != 0 || len(radix.ListContents()) != 0 || radix.NodeCount() != 0 {
+		t.Errorf("Expected an empty tree, got %v", radix.ListContents())
+	}
+}
+
+// labels lists the edges of node by depth first order, as "label" for the
+// edges leading to a word and "label/" for the others.
+func labels(node *radixNode) []string {
+	var out []string
+	for _, edge := range node.children {
+		label := edge.label
+		if !edge.node.isEnd {
+			label += "/"
+		}
+		out = append(out, label)
+		out = append(out, labels(edge.node)...)
+	}
+	return out
+}
+
+func TestRadixTreeSplit(t *testing.T) {
+	radix := newRadixTree()
+
+	steps := []struct {
+		word     string
+		expected string
+	}{
+		{"romane", "romane"},
+		// Splits the edge, both halves go on.
+		{"romanus", "roman/,e,us"},
+		// Splits the edge further up.
+		{"romulus", "rom/,an/,e,us,ulus"},
+		// Ends on an existing node.
+		{"roman", "rom/,an,e,us,ulus"},
+		// Ends in the middle of an edge.
+		{"ro", "ro,m/,an,e,us,ulus"},
+		{"rubens", "r/,o,m/,an,e,us,ulus,ubens"},
+		// Multibyte runes sharing their first byte are never split.
+		{"résumé", "r/,o,m/,an,e,us,ulus,ubens,ésumé"},
+		{"rèsumé", "r/,o,m/,an,e,us,ulus,ubens,èsumé,ésumé"},
+	}
+	for _, step := range steps {
+		radix.Insert(step.word)
+		if got := strings.Join(labels(radix.Root), ","); got != step.expected {
+			t.Fatalf("After inserting %q, expected %s, got %s", step.word, step.expected, got)
+		}
+		if err := radix.verify(); err != nil {
+			t.Fatalf("After inserting %q: %v", step.word, err)
+		}
+	}
+
+	expected := []string{"ro", "roman", "romane", "romanus", "romulus", "rubens", "résumé", "rèsumé"}
+	sort.Strings(expected)
+	assertWords(t, expected, radix.ListContents())
+	assertWords(t, []string{"résumé"}, radix.Autocomplete("ré"))
+}
+
+func TestRadixTreeDelete(t *testing.T) {
+	radix := newRadixTree()
+	for _, word := range []string{"bike", "bike path", "bicycle", "bi"} {
+		radix.Insert(word)
+	}
+
+	for _, word := range []string{"", "b", "bik", "bikes", "car"} {
+		if radix.Delete(word) {
+			t.Errorf("Expected %q to not be deleted", word)
+		}
+	}
+
+	steps := []struct {
+		word     string
+		expected string
+	}{
+		// The node stays, it still forks.
+		{"bi", "bi/,cycle,ke,\x20path"},
+		// A leaf, its parent is merged back with its last child.
+		{"bicycle", "bike,\x20path"},
+		// A word with a single child left is merged with it.
+		{"bike", "bike path"},
+		{"bike path", ""},
+	}
+	for _, step := range steps {
+		if !radix.Delete(step.word) {
+			t.Fatalf("Expected %q to be deleted", step.word)
+		}
+		if got := strings.Join(labels(radix.Root), ","); got != step.expected {
+			t.Fatalf("After deleting %q, expected %q, got %q", step.word, step.expected, got)
+		}
+		if err := radix.verify(); err != nil {
+			t.Fatalf("After deleting %q: %v", step.word, err)
+		}
+	}
+	if radix.Count() != 0 {
+		t.Errorf("Expected 0 words, got %d", radix.Count())
+	}
+}
+
+// Every word is compared against the trie, which is the reference.
+func TestRadixTreeMatchesTrie(t *testing.T) {
+	radix := newRadixTree()
+	trie := newTrie()
+	words := dictionaryWords()
+	for _, word := range words {
+		radix.Insert(word)
+		trie.Insert(word)
+	}
+	for i, word := range words {
+		if i%3 == 0 {
+			radix.Delete(word)
+			trie.Delete(word)
+		}
+	}
+
+	if err := radix.verify(); err != nil {
+		t.Fatal(err)
+	}
+	if radix.Count() != trie.Count() {
+		t.Errorf("Expected %d words, got %d", trie.Count(), radix.Count())
+	}
+	for _, prefix := range []string{"", "i", "inter", "internation", "re", "unre", "over", "x"} {
+		expected := trie.Autocomplete(prefix)
+		got := radix.Autocomplete(prefix)
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("For %q, expected %d completions, got %d", prefix, len(expected), len(got))
+		}
+	}
+}
+
+// dictionaryWords derives English looking words from a few roots, so they
+// share prefixes and suffixes like a real dictionary does.
+func dictionaryWords() []string {
+	prefixes := []string{"", "un", "re", "pre", "inter", "over", "counter", "mis"}
+	roots := []string{
+		"nation", "act", "form", "direct", "connect", "construct", "represent",
+		"establish", "develop", "organize", "govern", "relate", "commit", "compute",
+		"communicate", "determine", "distribute", "educate", "generate", "inform",
+		"interpret", "manage", "observe", "operate", "participate", "produce",
+		"regulate", "separate", "transport", "value",
+	}
+	suffixes := []string{"", "s", "ing", "ed", "al", "ally", "ation", "ations", "ness", "able", "ment", "ments", "ive", "ively"}
+
+	var words []string
+	for _, prefix := range prefixes {
+		for _, root := range roots {
+			for _, suffix := range suffixes {
+				words = append(words, prefix+root+suffix)
+			}
+		}
+	}
+	return words
+}
+
+// heapSize returns the bytes retained by build's result.
+func heapSize(build func() autocompleter) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	store := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(store)
+	return after.HeapAlloc - before.HeapAlloc
+}
+
+func TestRadixTreeMemory(t *testing.T) {
+	words := dictionaryWords()
+
+	trieBytes := heapSize(func() autocompleter {
+		trie := newTrie()
+		trie.InsertBatch(words)
+		return trie
+	})
+	radixBytes := heapSize(func() autocompleter {
+		radix := newRadixTree()
+		radix.InsertBatch(words)
+		return radix
+	})
+	t.Logf("%d words: trie %d bytes, radix %d bytes", len(words), trieBytes, radixBytes)
+
+	if radixBytes >= trieBytes {
+		t.Errorf("Expected the radix tree to take less memory than the trie, got %d and %d bytes", radixBytes, trieBytes)
+	}
+
+	trie := newTrie()
+	trie.InsertBatch(words)
+	radix := newRadixTree()
+	radix.InsertBatch(words)
+	if radix.NodeCount() >= trie.NodeCount()/2 {
+		t.Errorf("Expected less than half the nodes of the trie, got %d and %d", radix.NodeCount(), trie.NodeCount())
+	}
+}
+
+func TestRadixMode(t *testing.T) {
+	service := testService(t, []string{"bike", "bike path", "beach"}, WithRadixMode)
+	if got := service.Stats().Backend; got != "radix" {
+		t.Errorf("Expected the radix backend, got %s", got)
+	}
+	assertWords(t, []string{"bike", "bike path"}, service.Complete("bik"))
+
+	for _, opts := range [][]ConfigFn{{WithRadixMode, WithLowMemoryMode}, {WithRadixMode, WithGraphemeClusters}} {
+		if _, err := New(NewServiceConfig(opts...), nil); err == nil {
+			t.Errorf("Expected an error, got %v", err)
+		}
+	}
+}
+
+func BenchmarkRadixInsert(b *testing.B) {
+	words := benchmarkWords(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		radix := newRadixTree()
+		for _, word := range words {
+			radix.Insert(word)
+		}
+	}
+}
+
+func BenchmarkRadixAutocomplete(b *testing.B) {
+	radix := newRadixTree()
+	for _, word := range benchmarkWords(10000) {
+		radix.Insert(word)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		radix.Autocomplete("a")
+	}
+}
+
+func BenchmarkDictionaryMemory(b *testing.B) {
+	words := dictionaryWords()
+	for _, tt := range []struct {
+		name string
+		new  func() autocompleter
+	}{
+		{"trie", func() autocompleter { return newTrie() }},
+		{"radix", func() autocompleter { return newRadixTree() }},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				store := tt.new()
+				store.InsertBatch(words)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package autocomplete
+
+import (
+	"container/heap"
+	"sort"
+	"unicode/utf8"
+)
+
+// wordData is the bookkeeping kept on the terminal node of every word.
+type wordData struct {
+	// seq is the insertion sequence number of the word, higher is newer.
+	seq uint64
+
+	// weight ranks the word, higher first.
+	weight int
+
+	// display is the word as first inserted, only kept in case insensitive
+	// mode where the path to the node is the folded word.
+	display string
+
+	// tags is sorted, and replaced rather than modified so entries can share
+	// it, see tags.go.
+	tags []string
+
+	// meta is the payload attached with InsertMeta(), see meta.go.
+	meta any
+}
+
+// text returns the display form of the word at path.
+func (d wordData) text(path string) string {
+	if d.display != "" {
+		return d.display
+	}
+	return path
+}
+
+// entry is a stored word along with its bookkeeping.
+type entry struct {
+	word string
+	wordData
+}
+
+// entryStore is implemented by the stores that keep wordData on their nodes.
+// Stores that don't implement it are still usable, their words are just
+// ranked without any bookkeeping.
+type entryStore interface {
+	// entries returns every word starting with prefix.
+	entries(prefix string) []entry
+}
+
+// storeEntries returns the entries for prefix, falling back to plain
+// completions with empty bookkeeping when the store doesn't keep any.
+func storeEntries(store autocompleter, prefix string) []entry {
+	if es, ok := store.(entryStore); ok {
+		return es.entries(prefix)
+	}
+
+	words := store.Autocomplete(prefix)
+	results := make([]entry, len(words))
+	for i, word := range words {
+		results[i] = entry{word: word}
+	}
+	return results
+}
+
+// weightStore is implemented by the stores that keep a weight per word.
+type weightStore interface {
+	// addWeight adds delta to the weight of word, false if it isn't stored.
+	addWeight(word string, delta int) bool
+	// resetWeights sets the weight of every word to base.
+	resetWeights(base int)
+}
+
+func (s *spillStore) addWeight(word string, delta int) bool {
+	for _, store := range []autocompleter{s.primary, s.secondary} {
+		if ws, ok := store.(weightStore); ok && ws.addWeight(word, delta) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *spillStore) resetWeights(base int) {
+	for _, store := range []autocompleter{s.primary, s.secondary} {
+		if ws, ok := store.(weightStore); ok {
+			ws.resetWeights(base)
+		}
+	}
+}
+
+func (s *spillStore) entries(prefix string) []entry {
+	_, results := s.lookup(prefix)
+	return results
+}
+
+// rankEntries sorts the entries in place by weight, then by insertion recency
+// when recency is set, and lexically otherwise.
+func rankEntries(entries []entry, recency bool) {
+	rankEntriesScored(entries, nil, recency)
+}
+
+// rankEntriesScored behaves like rankEntries, but breaks weight ties by score,
+// highest first, before anything else when score is set.
+func rankEntriesScored(entries []entry, score func(word string) float64, recency bool) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].weight != entries[j].weight {
+			return entries[i].weight > entries[j].weight
+		}
+		if score != nil {
+			if si, sj := score(entries[i].word), score(entries[j].word); si != sj {
+				return si > sj
+			}
+		}
+		if recency && entries[i].seq != entries[j].seq {
+			return entries[i].seq > entries[j].seq
+		}
+		return entries[i].word < entries[j].word
+	})
+}
+
+// CompleteRanked returns the completions for prefix in ranked order, heaviest
+// first. Ties are broken lexically, unless WithRecencyTieBreak() is set in
+// which case the most recently inserted word wins the tie. With
+// WithCoverageRanking() the words the prefix covers the most of win first.
+func (a *AutocompleteService) CompleteRanked(prefix string) []string {
+	if !a.acquire() {
+		return []string{}
+	}
+	defer a.release()
+	a.queries.record(prefix)
+
+	var score func(word string) float64
+	if a.Config.CoverageRanking {
+		prefixLen := utf8.RuneCountInString(prefix)
+		score = func(word string) float64 {
+			return coverage(prefixLen, word)
+		}
+	}
+
+	entries := storeEntries(a.store, prefix)
+	rankEntriesScored(entries, score, a.Config.RecencyTieBreak)
+	return entryWords(entries)
+}
+
+// coverage returns the share of word covered by a prefix of prefixLen runes,
+// 1 when the prefix is the whole word.
+func coverage(prefixLen int, word string) float64 {
+	n := utf8.RuneCountInString(word)
+	if n == 0 {
+		return 0
+	}
+	return float64(prefixLen) / float64(n)
+}
+
+func entryWords(entries []entry) []string {
+	results := make([]string, len(entries))
+	for i, e := range entries {
+		results[i] = e.word
+	}
+	return results
+}
+
+// Suggestion is a completion along with its weight.
+type Suggestion struct {
+	Word  string
+	Score int
+}
+
+// CompleteWithScores behaves like Complete, but returns the weight of every
+// completion along with it.
+func (a *AutocompleteService) CompleteWithScores(prefix string) []Suggestion {
+	if !a.acquire() {
+		return []Suggestion{}
+	}
+	defer a.release()
+	a.queries.record(prefix)
+
+	entries := storeEntries(a.store, prefix)
+	rankEntries(entries, false)
+
+	results := make([]Suggestion, len(entries))
+	for i, e := range entries {
+		results[i] = Suggestion{Word: e.word, Score: e.weight}
+	}
+	return results
+}
+
+// AddWeighted inserts the word if needed, then adds weight to its count. Stores
+// that don't keep weights only insert the word.
+func (a *AutocompleteService) AddWeighted(word string, weight int) {
+	if word == "" {
+		return
+	}
+	if !a.acquire() {
+		return
+	}
+	defer a.release()
+
+	if !a.add(word) {
+		return
+	}
+	if ws, ok := a.store.(weightStore); ok {
+		ws.addWeight(word, weight)
+		a.cache.invalidate()
+	}
+}
+
+// Bump increments the count of a stored word, and reports whether the word was
+// stored.
+func (a *AutocompleteService) Bump(word string) bool {
+	if !a.acquire() {
+		return false
+	}
+	defer a.release()
+
+	ws, ok := a.store.(weightStore)
+	if !ok || !ws.addWeight(word, 1) {
+		return false
+	}
+	a.cache.invalidate()
+	a.touch(word)
+	return true
+}
+
+// ResetWeights starts a fresh popularity epoch: every word is kept, but its
+// weight is reset to zero, or to the base set with WithBaseWeight(). Stores
+// that don't keep weights are left untouched.
+func (a *AutocompleteService) ResetWeights() {
+	if !a.acquire() {
+		return
+	}
+	defer a.release()
+
+	if ws, ok := a.store.(weightStore); ok {
+		ws.resetWeights(a.Config.BaseWeight)
+		a.cache.invalidate()
+	}
+}
+
+// CompleteWithScorer ranks the completions for prefix with an external scorer,
+// e.g. a ML model, and returns the limit best scoring ones, highest first.
+// Equal scores are ordered lexically.
+//
+// Only the limit best candidates are kept in a bounded heap while scoring, so
+// the full candidate set is never sorted.
+func (a *AutocompleteService) CompleteWithScorer(prefix string, score func(word string) float64, limit int) []string {
+	candidates := a.Complete(prefix)
+	if limit <= 0 || len(candidates) == 0 {
+		return []string{}
+	}
+
+	h := make(scoredHeap, 0, limit)
+	for _, word := range candidates {
+		s := scored{word: word, score: score(word)}
+		if len(h) < limit {
+			heap.Push(&h, s)
+			continue
+		}
+		// Replace the worst of the best so far.
+		if h.less(h[0], s) {
+			h[0] = s
+			heap.Fix(&h, 0)
+		}
+	}
+
+	results := make([]string, len(h))
+	for i := len(h) - 1; i >= 0; i-- {
+		results[i] = heap.Pop(&h).(scored).word
+	}
+	return results
+}
+
+type scored struct {
+	word  string
+	score float64
+}
+
+// scoredHeap is a min heap, the worst candidate is at the root.
+type scoredHeap []scored
+
+// less reports whether a ranks below b.
+func (h scoredHeap) less(a, b scored) bool {
+	if a.score != b.score {
+		return a.score < b.score
+	}
+	return a.word > b.word
+}
+
+func (h scoredHeap) Len() int           { return len(h) }
+func (h scoredHeap) Less(i, j int) bool { return h.less(h[i], h[j]) }
+func (h scoredHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *scoredHeap) Push(x any)        { *h = append(*h, x.(scored)) }
+func (h *scoredHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
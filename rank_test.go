@@ -0,0 +1,161 @@
+package autocomplete
+
+import (
+	"testing"
+)
+
+func TestCompleteRanked(t *testing.T) {
+	words := []string{"bike", "bicycle repair", "bike path", "beach"}
+
+	t.Run("lexical", func(t *testing.T) {
+		for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}, {WithRadixMode}} {
+			service := testService(t, words, opts...)
+
+			expected := []string{"bicycle repair", "bike", "bike path"}
+			assertWords(t, expected, service.CompleteRanked("bi"))
+		}
+	})
+
+	t.Run("recency tie break", func(t *testing.T) {
+		for _, opts := range [][]ConfigFn{{WithRecencyTieBreak}, {WithRecencyTieBreak, WithLowMemoryMode}} {
+			service := testService(t, words, opts...)
+
+			expected := []string{"bike path", "bicycle repair", "bike"}
+			assertWords(t, expected, service.CompleteRanked("bi"))
+
+			// Re-inserting a word makes it the freshest.
+			service.Add("bike")
+			expected = []string{"bike", "bike path", "bicycle repair"}
+			assertWords(t, expected, service.CompleteRanked("bi"))
+		}
+	})
+
+	t.Run("coverage", func(t *testing.T) {
+		for _, opts := range [][]ConfigFn{{WithCoverageRanking}, {WithCoverageRanking, WithLowMemoryMode}} {
+			service := testService(t, append(words, "bi"), opts...)
+
+			expected := []string{"bi", "bike", "bike path", "bicycle repair"}
+			assertWords(t, expected, service.CompleteRanked("bi"))
+
+			// Weights still come first.
+			service.AddWeighted("bicycle repair", 1)
+			expected = []string{"bicycle repair", "bi", "bike", "bike path"}
+			assertWords(t, expected, service.CompleteRanked("bi"))
+		}
+	})
+}
+
+func assertWords(t *testing.T, expected, got []string) {
+	t.Helper()
+	if len(expected) != len(got) {
+		t.Errorf("Expected %v, got %v", expected, got)
+		return
+	}
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Errorf("Expected %v, got %v", expected, got)
+			return
+		}
+	}
+}
+
+func TestCompleteWithScorer(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "bin", "bird", "beach"}
+	service := testService(t, words)
+
+	// Longer words score higher, "bike" and "bird" tie.
+	byLength := func(word string) float64 {
+		return float64(len(word))
+	}
+
+	expected := []string{"bicycle repair", "bike path", "bike"}
+	assertWords(t, expected, service.CompleteWithScorer("bi", byLength, 3))
+
+	expected = []string{"bicycle repair", "bike path", "bike", "bird", "bin"}
+	assertWords(t, expected, service.CompleteWithScorer("bi", byLength, 10))
+
+	assertWords(t, []string{}, service.CompleteWithScorer("bi", byLength, 0))
+}
+
+func TestResetWeights(t *testing.T) {
+	words := []string{"bike", "bicycle repair", "bike path", "beach"}
+
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}} {
+		service := testService(t, words, opts...)
+
+		ws := service.store.(weightStore)
+		ws.addWeight("bike path", 3)
+		ws.addWeight("bike", 1)
+		if ws.addWeight("bi", 1) {
+			t.Errorf("Expected adding weight to a missing word to fail")
+		}
+
+		expected := []string{"bike path", "bike", "bicycle repair"}
+		assertWords(t, expected, service.CompleteRanked("bi"))
+
+		service.ResetWeights()
+
+		if len(service.GetContents()) != len(words) {
+			t.Errorf("Expected %d words, got %v", len(words), service.GetContents())
+		}
+		expected = []string{"bicycle repair", "bike", "bike path"}
+		assertWords(t, expected, service.CompleteRanked("bi"))
+	}
+
+	t.Run("base weight", func(t *testing.T) {
+		service := testService(t, words, WithBaseWeight(5))
+		service.store.(weightStore).addWeight("bike", 1)
+		service.ResetWeights()
+
+		for _, e := range storeEntries(service.store, "") {
+			if e.weight != 5 {
+				t.Errorf("Expected %q to weigh 5, got %d", e.word, e.weight)
+			}
+		}
+	})
+}
+
+func TestWeightedSuggestions(t *testing.T) {
+	words := []string{"bike", "bicycle repair", "bike path", "beach"}
+
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}} {
+		service := testService(t, words, opts...)
+
+		// Unweighted completions are lexical.
+		assertWords(t, []string{"bicycle repair", "bike", "bike path"}, service.Complete("bi"))
+
+		service.AddWeighted("bike path", 2)
+		service.AddWeighted("bird", 1)
+		if !service.Bump("bike path") {
+			t.Errorf("Expected %q to be bumped", "bike path")
+		}
+		if service.Bump("bi") {
+			t.Errorf("Expected bumping a missing word to return false")
+		}
+
+		assertWords(t, []string{"bike path", "bird", "bicycle repair", "bike"}, service.Complete("bi"))
+
+		expected := []Suggestion{{"bike path", 3}, {"bird", 1}, {"bicycle repair", 0}, {"bike", 0}}
+		scores := service.CompleteWithScores("bi")
+		if len(scores) != len(expected) {
+			t.Fatalf("Expected %v, got %v", expected, scores)
+		}
+		for i := range expected {
+			if scores[i] != expected[i] {
+				t.Errorf("Expected %v, got %v", expected, scores)
+				break
+			}
+		}
+	}
+
+	t.Run("hit tracking", func(t *testing.T) {
+		for _, opts := range [][]ConfigFn{{WithHitTracking}, {WithHitTracking, WithLowMemoryMode}} {
+			service := testService(t, words, opts...)
+			service.Add("bike")
+			service.Add("bike")
+			service.Add("bike path")
+
+			assertWords(t, []string{"bike", "bike path", "bicycle repair"}, service.Complete("bi"))
+		}
+	})
+}
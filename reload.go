@@ -0,0 +1,75 @@
+package autocomplete
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reload replaces the contents of the store with the ones of the data sources.
+// They are loaded into a fresh store, which is only swapped in once every data
+// source loaded: on failure the current contents are kept untouched and the
+// errors are returned. The completions are served from the current contents
+// during the load.
+//
+// The words added since the data sources were last loaded, reloading included,
+// are dropped. Reload isn't supported when the store was provided with
+// WithStore() or WithSpillStore(), the service can't create a fresh one.
+func (a *AutocompleteService) Reload() error {
+	if a.Config.Store != nil || a.Config.SpillStore != nil {
+		return errors.New("autocompleteservice: reload: not supported with a store provided by the config")
+	}
+
+	scratch, err := a.loadScratch()
+	if err != nil {
+		return err
+	}
+
+	a.lifecycle.Lock()
+	defer a.lifecycle.Unlock()
+	if a.closed.Load() {
+		return fmt.Errorf("autocompleteservice: reload: %w", ErrServiceClosed)
+	}
+
+	a.store = scratch
+	a.cache.invalidate()
+	// Both are rebuilt from scratch, the reloaded words were never inserted
+	// through the service.
+	a.folds.reset()
+	a.substrings.reset()
+	a.trends.reset()
+	a.markUpdated()
+	return nil
+}
+
+// loadScratch loads every data source into a fresh store, skipping none, not
+// even the ones whose circuit breaker is open.
+func (a *AutocompleteService) loadScratch() (autocompleter, error) {
+	if !a.acquire() {
+		return nil, fmt.Errorf("autocompleteservice: reload: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	scratch := newStore(a.Config)
+	var store PublicProviderStore = scratch
+	if a.Config.LoadDedupe {
+		store = &dedupeStore{store: scratch, deduped: &a.deduped}
+	}
+	if a.Config.MaxWordLength > 0 {
+		store = &limitStore{PublicProviderStore: store, service: a}
+	}
+
+	var errs []error
+	for _, source := range a.Config.DataSources {
+		err := source.Provider.ReadData(source.Filepath, store, source.formatter())
+		a.logLoad(source.Filepath, err)
+		if err != nil {
+			a.recordError(err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("autocompleteservice: reload: %d of %d data sources failed: %w", len(errs), len(a.Config.DataSources), errors.Join(errs...))
+	}
+	return scratch, nil
+}
@@ -0,0 +1,120 @@
+package autocomplete
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	first := &mockProvider{words: []string{"bike", "bike path"}}
+	second := &mockProvider{words: []string{"beach"}}
+	service := testService(t, nil, WithDataSources([]DataSource{
+		*NewDataSource(first, nil, "first.json", ""),
+		*NewDataSource(second, nil, "second.json", ""),
+	}), WithSuggestionCache(4))
+	if err := service.LoadDataSources(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	service.Add("pool")
+	assertWords(t, []string{"bike", "bike path"}, service.Complete("bi"))
+
+	first.words = []string{"bicycle repair"}
+	if err := service.Reload(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	// Replaced wholesale, pool was never in a data source.
+	assertWords(t, []string{"beach", "bicycle repair"}, service.GetContents())
+	assertWords(t, []string{"bicycle repair"}, service.Complete("bi"))
+
+	t.Run("failing source", func(t *testing.T) {
+		first.words = []string{"dog park"}
+		second.err = errors.New("source unavailable")
+
+		err := service.Reload()
+		if !errors.Is(err, second.err) {
+			t.Errorf("Expected the error to wrap %v, got %v", second.err, err)
+		}
+		// Nothing of the failed reload is kept, not even the sources that
+		// loaded.
+		assertWords(t, []string{"beach", "bicycle repair"}, service.GetContents())
+		if errs := service.GetErrors(); len(errs) != 1 || errs[0] != second.err {
+			t.Errorf("Expected [%v], got %v", second.err, errs)
+		}
+	})
+
+	service.Close()
+	if err := service.Reload(); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected %v, got %v", ErrServiceClosed, err)
+	}
+}
+
+func TestReloadProvidedStore(t *testing.T) {
+	src := *NewDataSource(&mockProvider{words: []string{"bike"}}, nil, "words.json", "")
+	for _, opts := range [][]ConfigFn{{WithStore(newTrie())}, {WithSpillStore(newTrie(), 10)}} {
+		service := testService(t, []string{"pool"}, append(opts, WithDataSources([]DataSource{src}))...)
+		if err := service.Reload(); err == nil {
+			t.Errorf("Expected an error")
+		}
+		assertWords(t, []string{"pool"}, service.GetContents())
+	}
+}
+
+// Run with -race.
+func TestReloadConcurrentComplete(t *testing.T) {
+	src := *NewDataSource(&mockProvider{words: []string{"bike", "bike path"}}, nil, "words.json", "")
+	service := testService(t, []string{"bike"}, WithDataSources([]DataSource{src}), WithBackend(BackendTST))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if results := service.Complete("bi"); len(results) == 0 {
+					t.Errorf("Expected completions during the reload")
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if err := service.Reload(); err != nil {
+			t.Errorf("Expected nil, got %v", err)
+		}
+	}
+	wg.Wait()
+
+	if got := service.Stats().Backend; got != "tst" {
+		t.Errorf("Expected the reloaded store to keep the tst backend, got %s", got)
+	}
+}
+
+// Run with -race.
+func TestReloadConcurrentClear(t *testing.T) {
+	src := *NewDataSource(&mockProvider{words: []string{"bike", "bike path"}}, nil, "words.json", "")
+	service := testService(t, nil, WithDataSources([]DataSource{src}))
+	service.CompleteFold("b")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			service.Clear(false)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			service.Add("beach")
+			service.CompleteFold("b")
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		if err := service.Reload(); err != nil {
+			t.Errorf("Expected nil, got %v", err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,254 @@
+package autocomplete
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ticker is the part of time.Ticker used by the snapshot loop, so tests can
+// drive the loop without waiting on the clock.
+type ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type timeTicker struct {
+	*time.Ticker
+}
+
+func (t timeTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+func newTimeTicker(d time.Duration) ticker {
+	return timeTicker{time.NewTicker(d)}
+}
+
+// snapshotLoop is the background goroutine creating a snapshot on every tick.
+type snapshotLoop struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// SetSnapshotInterval changes the interval of the automatic snapshots, stopping
+// the running snapshot loop and starting a new one with the new interval. Pass
+// 0 to disable automatic snapshots. The interval is kept across Reopen().
+//
+// NOTE: Config.SnapshotInterval is in seconds, so it is rounded down there.
+func (a *AutocompleteService) SetSnapshotInterval(d time.Duration) error {
+	a.snapshotMu.Lock()
+	defer a.snapshotMu.Unlock()
+
+	if a.closed.Load() {
+		return fmt.Errorf("autocompleteservice: setsnapshotinterval: %w", ErrServiceClosed)
+	}
+
+	a.stopSnapshotLoop()
+
+	a.snapshotInterval = max(d, 0)
+	a.Config.SnapshotInterval = int(d / time.Second)
+	a.Config.SnapshotsEnabled = d > 0
+	if d > 0 {
+		a.startSnapshotLoop(d)
+	}
+	return nil
+}
+
+// snapshotPeriod returns the interval of the automatic snapshots, the one set
+// by SetSnapshotInterval() when called, or Config.SnapshotInterval.
+func (a *AutocompleteService) snapshotPeriod() time.Duration {
+	if a.snapshotInterval > 0 {
+		return a.snapshotInterval
+	}
+	return time.Duration(a.Config.SnapshotInterval) * time.Second
+}
+
+// startSnapshotLoop must be called with snapshotMu held.
+func (a *AutocompleteService) startSnapshotLoop(d time.Duration) {
+	loop := &snapshotLoop{stop: make(chan struct{}), done: make(chan struct{})}
+	t := a.newTicker(d)
+
+	go func() {
+		defer close(loop.done)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-loop.stop:
+				return
+			case <-t.C():
+				// CreateSnapshot keeps track of the error, a failed snapshot
+				// shouldn't stop the following ones.
+				a.CreateSnapshot()
+			}
+		}
+	}()
+
+	a.snapshots = loop
+}
+
+// stopSnapshotLoop stops the snapshot loop, if running, and waits for it to
+// exit. It must be called with snapshotMu held, and never while holding the
+// lifecycle lock since the loop may be waiting on it to create a snapshot.
+func (a *AutocompleteService) stopSnapshotLoop() {
+	if a.snapshots == nil {
+		return
+	}
+
+	close(a.snapshots.stop)
+	<-a.snapshots.done
+	a.snapshots = nil
+}
+
+// snapshotTimeFormat is the timestamp of the rotated snapshots, see
+// WithSnapshotRetention(). Sorting their names sorts them by time.
+const snapshotTimeFormat = "20060102T150405"
+
+// dumpSnapshot writes the snapshot of the store to dest and returns the path
+// it was written to. With a retention, a local file destination gets a new
+// timestamped file every time, and the oldest ones past the retention are
+// deleted.
+func (a *AutocompleteService) dumpSnapshot(dest *DataSource) (string, error) {
+	local, ok := dest.Provider.(*LocalFileProvider)
+	if a.Config.SnapshotRetention <= 0 || !ok {
+		return dest.Filepath, dest.Provider.DumpData(dest.Filepath, a.store, dest.formatter())
+	}
+
+	name := rotatedName(local.Filename, a.now())
+	rotated := &LocalFileProvider{Filename: name}
+	if err := rotated.DumpData(name, a.store, dest.formatter()); err != nil {
+		return name, err
+	}
+	return name, pruneSnapshots(local.Filename, a.Config.SnapshotRetention)
+}
+
+// rotatedName returns the snapshot of base taken at t, e.g.
+// snapshot-20240101T120000.json for snapshot.json.
+func rotatedName(base string, t time.Time) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-" + t.UTC().Format(snapshotTimeFormat) + ext
+}
+
+// rotatedSnapshots returns the rotated snapshots of base, oldest first.
+func rotatedSnapshots(base string) ([]string, error) {
+	dir, file := filepath.Split(base)
+	ext := filepath.Ext(file)
+	prefix := strings.TrimSuffix(file, ext) + "-"
+
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.Parse(snapshotTimeFormat, stamp); err == nil {
+			snapshots = append(snapshots, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(snapshots)
+	return snapshots, nil
+}
+
+// pruneSnapshots deletes the rotated snapshots of base but the keep most
+// recent ones.
+func pruneSnapshots(base string, keep int) error {
+	snapshots, err := rotatedSnapshots(base)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range snapshots[:max(len(snapshots)-keep, 0)] {
+		if err := os.Remove(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// snapshotSource returns the provider and the file path to restore the
+// snapshot of dest from, see newestSnapshot().
+func (a *AutocompleteService) snapshotSource(dest *DataSource) (DataProvider, string, error) {
+	local, ok := dest.Provider.(*LocalFileProvider)
+	if !ok {
+		return dest.Provider, dest.Filepath, nil
+	}
+
+	name, err := a.newestSnapshot(local.Filename)
+	if err != nil {
+		return nil, "", err
+	}
+	if name == local.Filename {
+		return dest.Provider, dest.Filepath, nil
+	}
+	return &LocalFileProvider{Filename: name}, name, nil
+}
+
+// newestSnapshot returns the snapshot to restore for path: the most recently
+// modified file when path is a directory or a glob pattern, the newest rotated
+// snapshot with a retention, or else path itself.
+func (a *AutocompleteService) newestSnapshot(path string) (string, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return "", err
+		}
+		var files []string
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		return newestFile(path, files)
+	}
+
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return "", err
+		}
+		return newestFile(path, matches)
+	}
+
+	if a.Config.SnapshotRetention > 0 {
+		snapshots, err := rotatedSnapshots(path)
+		if err != nil {
+			return "", err
+		}
+		if len(snapshots) > 0 {
+			return snapshots[len(snapshots)-1], nil
+		}
+	}
+	return path, nil
+}
+
+// newestFile returns the most recently modified of files, the greatest name
+// on ties. path is only used in the error when there are none.
+func newestFile(path string, files []string) (string, error) {
+	var newest string
+	var newestTime time.Time
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if t := info.ModTime(); newest == "" || t.After(newestTime) || (t.Equal(newestTime) && name > newest) {
+			newest, newestTime = name, t
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("no snapshot found in %s", path)
+	}
+	return newest, nil
+}
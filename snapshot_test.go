@@ -0,0 +1,387 @@
+package autocomplete
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTicker only ticks when told to.
+type fakeTicker struct {
+	c       chan time.Time
+	stopped chan struct{}
+}
+
+func (f *fakeTicker) C() <-chan time.Time { return f.c }
+
+func (f *fakeTicker) Stop() { close(f.stopped) }
+
+// fakeClock hands out fake tickers and remembers the intervals they were made with.
+type fakeClock struct {
+	mu        sync.Mutex
+	tickers   []*fakeTicker
+	intervals []time.Duration
+}
+
+func (f *fakeClock) newTicker(d time.Duration) ticker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time), stopped: make(chan struct{})}
+	f.tickers = append(f.tickers, t)
+	f.intervals = append(f.intervals, d)
+	return t
+}
+
+func (f *fakeClock) last() *fakeTicker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tickers[len(f.tickers)-1]
+}
+
+// waitFor polls cond until it holds or a second passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSetSnapshotInterval(t *testing.T) {
+	provider := &mockProvider{}
+	service := testService(t, []string{"bike"}, WithSnapshotDest(*NewDataSource(provider, nil, "snapshot.json", "")))
+
+	clock := &fakeClock{}
+	service.newTicker = clock.newTicker
+
+	if err := service.SetSnapshotInterval(time.Second); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	first := clock.last()
+	first.c <- time.Now()
+	waitFor(t, func() bool { return provider.dumpCount() == 1 })
+
+	// Changing the interval restarts the loop with the new cadence.
+	if err := service.SetSnapshotInterval(2 * time.Second); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	select {
+	case <-first.stopped:
+	default:
+		t.Errorf("Expected the previous ticker to be stopped")
+	}
+	if clock.intervals[1] != 2*time.Second {
+		t.Errorf("Expected an interval of %v, got %v", 2*time.Second, clock.intervals[1])
+	}
+	if service.Config.SnapshotInterval != 2 {
+		t.Errorf("Expected a snapshot interval of 2, got %d", service.Config.SnapshotInterval)
+	}
+
+	second := clock.last()
+	second.c <- time.Now()
+	second.c <- time.Now()
+	waitFor(t, func() bool { return provider.dumpCount() == 3 })
+
+	// Zero disables the snapshots.
+	if err := service.SetSnapshotInterval(0); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	<-second.stopped
+	if len(clock.tickers) != 2 {
+		t.Errorf("Expected no new ticker, got %d tickers", len(clock.tickers))
+	}
+	if service.Config.SnapshotsEnabled {
+		t.Errorf("Expected snapshots to be disabled")
+	}
+
+	if err := service.SetSnapshotInterval(time.Second); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if err := service.Close(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	<-clock.last().stopped
+
+	if err := service.SetSnapshotInterval(time.Second); err == nil {
+		t.Errorf("Expected non-nil, got %v", err)
+	}
+}
+
+func TestSetSnapshotIntervalReopen(t *testing.T) {
+	for _, d := range []time.Duration{500 * time.Millisecond, 1500 * time.Millisecond} {
+		t.Run(d.String(), func(t *testing.T) {
+			provider := &mockProvider{}
+			service := testService(t, []string{"bike"}, WithSnapshotDest(*NewDataSource(provider, nil, "snapshot.json", "")))
+
+			clock := &fakeClock{}
+			service.newTicker = clock.newTicker
+
+			if err := service.SetSnapshotInterval(d); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			if err := service.Close(); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			<-clock.last().stopped
+
+			// The loop is restarted with the exact interval.
+			if err := service.Reopen(); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+			if len(clock.tickers) != 2 {
+				t.Fatalf("Expected the loop to be restarted, got %d tickers", len(clock.tickers))
+			}
+			if clock.intervals[1] != d {
+				t.Errorf("Expected an interval of %v, got %v", d, clock.intervals[1])
+			}
+
+			dumps := provider.dumpCount()
+			clock.last().c <- time.Now()
+			waitFor(t, func() bool { return provider.dumpCount() == dumps+1 })
+			service.Close()
+		})
+	}
+}
+
+func TestAutomaticSnapshots(t *testing.T) {
+	provider := &mockProvider{}
+	service := testService(t, []string{"bike"},
+		WithSnapshotDest(*NewDataSource(provider, nil, "snapshot.json", "")),
+		WithSnapshotsEnabled,
+		WithSnapshotInterval(1),
+	)
+
+	// The smallest interval is a second, give the first tick some slack.
+	deadline := time.Now().Add(3 * time.Second)
+	for provider.dumpCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for a snapshot")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := service.Close(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if service.snapshots != nil {
+		t.Errorf("Expected the snapshot loop to be stopped")
+	}
+
+	// The ticker doesn't fire once closed.
+	dumps := provider.dumpCount()
+	time.Sleep(1100 * time.Millisecond)
+	if provider.dumpCount() != dumps {
+		t.Errorf("Expected %d snapshots, got %d", dumps, provider.dumpCount())
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		service := testService(t, []string{"bike"}, WithSnapshotInterval(1))
+		if service.snapshots != nil {
+			t.Errorf("Expected no snapshot loop without WithSnapshotsEnabled")
+		}
+	})
+}
+
+func TestSnapshotLoopKeepsGoingOnError(t *testing.T) {
+	provider := &mockProvider{err: errors.New("disk full")}
+	service := testService(t, []string{"bike"}, WithSnapshotDest(*NewDataSource(provider, nil, "snapshot.json", "")))
+
+	clock := &fakeClock{}
+	service.newTicker = clock.newTicker
+	if err := service.SetSnapshotInterval(time.Second); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	ticker := clock.last()
+	for i := 0; i < 3; i++ {
+		ticker.c <- time.Now()
+	}
+	waitFor(t, func() bool { return provider.dumpCount() == 3 })
+
+	if err := service.Close(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if len(service.Errors) != 3 {
+		t.Errorf("Expected 3 errors, got %v", service.Errors)
+	}
+}
+
+func TestCreateSnapshots(t *testing.T) {
+	words := []string{"beach", "bike", "pool"}
+	service := testService(t, words)
+
+	dir := t.TempDir()
+	jsonFile, _ := NewLocalFileProvider(filepath.Join(dir, "snapshot.json"))
+	csvFile, _ := NewLocalFileProvider(filepath.Join(dir, "snapshot.csv"))
+	dests := []DataSource{
+		*NewDataSource(jsonFile, DefaultFormat{}, jsonFile.Filename, ""),
+		*NewDataSource(csvFile, DefaultFormat{}, csvFile.Filename, ""),
+	}
+	if err := service.CreateSnapshots(dests); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	for _, dest := range dests {
+		store := newTrie()
+		if err := dest.Provider.ReadData(dest.Filepath, store, dest.Formatter); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		assertWords(t, words, store.ListContents())
+	}
+
+	t.Run("failing destination", func(t *testing.T) {
+		errFirst, errLast := errors.New("first"), errors.New("last")
+		ok := &mockProvider{}
+		dests := []DataSource{
+			*NewDataSource(&mockProvider{err: errFirst}, nil, "first.json", ""),
+			*NewDataSource(ok, nil, "ok.json", ""),
+			*NewDataSource(&mockProvider{err: errLast}, nil, "last.json", ""),
+		}
+
+		err := service.CreateSnapshots(dests)
+		if !errors.Is(err, errFirst) || !errors.Is(err, errLast) {
+			t.Errorf("Expected both errors, got %v", err)
+		}
+		assertWords(t, words, ok.dumped)
+	})
+}
+
+func TestSnapshotInMemory(t *testing.T) {
+	var _ ContextDataProvider = (*InMemoryProvider)(nil)
+
+	provider, _ := NewInMemoryProvider(nil)
+	dest := *NewDataSource(provider, DefaultFormat{}, "snapshot.json", "")
+	words := []string{"beach", "bike", "pool"}
+	service := testService(t, words, WithSnapshotDest(dest))
+	if err := service.CreateSnapshot(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	restored := testService(t, nil, WithSnapshotDest(dest))
+	if err := restored.RestoreFromSnapshot(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, words, restored.GetContents())
+}
+
+func TestSnapshotRetention(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "snapshot.json")
+	provider, _ := NewLocalFileProvider(base)
+	dest := *NewDataSource(provider, DefaultFormat{}, base, "")
+	service := testService(t, []string{"bike"}, WithSnapshotDest(dest), WithSnapshotRetention(3))
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := func(i int) string {
+		t.Helper()
+		service.now = func() time.Time { return start.Add(time.Duration(i) * time.Hour) }
+		service.Add(fmt.Sprintf("word %d", i))
+		if err := service.CreateSnapshot(); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		return rotatedName(base, service.now())
+	}
+
+	var names []string
+	for i := 0; i < 2; i++ {
+		names = append(names, snapshot(i))
+	}
+	// Fewer snapshots than the retention, none is deleted.
+	if got, _ := rotatedSnapshots(base); strings.Join(got, ",") != strings.Join(names, ",") {
+		t.Errorf("Expected %v, got %v", names, got)
+	}
+	if filepath.Base(names[0]) != "snapshot-20240101T120000.json" {
+		t.Errorf("Expected a timestamped name, got %s", names[0])
+	}
+
+	for i := 2; i < 5; i++ {
+		names = append(names, snapshot(i))
+	}
+	// Exactly the two oldest ones are deleted.
+	if got, _ := rotatedSnapshots(base); strings.Join(got, ",") != strings.Join(names[2:], ",") {
+		t.Errorf("Expected %v, got %v", names[2:], got)
+	}
+	if _, err := os.Stat(base); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected %s not to be written, got %v", base, err)
+	}
+
+	restored := testService(t, nil, WithSnapshotDest(dest), WithSnapshotRetention(3))
+	if err := restored.RestoreFromSnapshot(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	assertWords(t, service.GetContents(), restored.GetContents())
+}
+
+func TestRestoreNewestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, words []string, age time.Duration) {
+		t.Helper()
+		provider, _ := NewLocalFileProvider(filepath.Join(dir, name))
+		store := newTrie()
+		store.InsertBatch(words)
+		if err := provider.DumpData(name, store, DefaultFormat{}); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		mtime := time.Now().Add(-age)
+		if err := os.Chtimes(provider.Filename, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("old.json", []string{"beach"}, 2*time.Hour)
+	write("new.json", []string{"bike"}, time.Hour)
+	write("older.csv", []string{"pool"}, 3*time.Hour)
+
+	for _, path := range []string{dir, filepath.Join(dir, "*.json"), filepath.Join(dir, "*")} {
+		provider, _ := NewLocalFileProvider(path)
+		service := testService(t, nil, WithSnapshotDest(*NewDataSource(provider, DefaultFormat{}, path, "")))
+		if err := service.RestoreFromSnapshot(); err != nil {
+			t.Fatalf("%s: Expected nil, got %v", path, err)
+		}
+		assertWords(t, []string{"bike"}, service.GetContents())
+	}
+
+	path := filepath.Join(dir, "*.txt")
+	provider, _ := NewLocalFileProvider(path)
+	service := testService(t, nil, WithSnapshotDest(*NewDataSource(provider, DefaultFormat{}, path, "")))
+	if err := service.RestoreFromSnapshot(); err == nil {
+		t.Errorf("Expected an error when nothing matches")
+	}
+}
+
+func TestSnapshotDestNotConfigured(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		dest *DataSource
+	}{
+		{"nil", nil},
+		{"zero", &DataSource{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// Built by hand rather than with NewServiceConfig().
+			service, err := New(&ServiceConfig{ServiceName: "manual", SnapshotDest: tt.dest}, []string{"bike"})
+			if err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+
+			if err := service.CreateSnapshot(); !errors.Is(err, ErrNoSnapshotDest) {
+				t.Errorf("Expected %v, got %v", ErrNoSnapshotDest, err)
+			}
+			if err := service.RestoreFromSnapshot(); !errors.Is(err, ErrNoSnapshotDest) || !strings.Contains(err.Error(), "restorefromsnapshot") {
+				t.Errorf("Expected %v from restorefromsnapshot, got %v", ErrNoSnapshotDest, err)
+			}
+			assertWords(t, []string{"bike"}, service.GetContents())
+
+			if err := service.Close(); err != nil {
+				t.Errorf("Expected nil, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package autocomplete
+
+import (
+	"io"
+	"sync"
+)
+
+var _ autocompleter = (*spillStore)(nil)
+
+// spillStore keeps the first threshold words in the primary (in-memory) store,
+// once the primary is full every new word is spilled over to the secondary
+// store. The secondary is usually a slower disk backed autocompleter.
+//
+// Queries are answered by both tiers and merged together.
+type spillStore struct {
+	primary   autocompleter
+	secondary autocompleter
+	threshold int
+
+	// number of words inserted into the primary.
+	size int
+
+	mu sync.Mutex
+}
+
+func newSpillStore(primary, secondary autocompleter, threshold int) *spillStore {
+	return &spillStore{
+		primary:   primary,
+		secondary: secondary,
+		threshold: threshold,
+	}
+}
+
+func (s *spillStore) Insert(word string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.insert(word)
+}
+
+// InsertReport inserts word and reports whether it was newly added, false
+// when one of the tiers already stores it.
+func (s *spillStore) InsertReport(word string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.insert(word)
+}
+
+// InsertBatch inserts every word under a single lock, each tier still locks
+// once per word.
+func (s *spillStore) InsertBatch(words []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, word := range words {
+		s.insert(word)
+	}
+}
+
+// insert inserts word and reports whether it was newly added, the caller must
+// hold the lock.
+func (s *spillStore) insert(word string) bool {
+	// Already stored in one of the tiers, re-inserting into the other would
+	// duplicate it. Re-inserted into the same tier instead, so its hit count
+	// and recency are still updated.
+	if s.primary.Contains(word) {
+		s.primary.Insert(word)
+		return false
+	}
+	if s.secondary.Contains(word) {
+		s.secondary.Insert(word)
+		return false
+	}
+
+	if s.size < s.threshold {
+		s.primary.Insert(word)
+		s.size++
+		return true
+	}
+
+	s.secondary.Insert(word)
+	return true
+}
+
+func (s *spillStore) Delete(word string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.primary.Delete(word) {
+		// Make room in the primary for the next insert.
+		s.size--
+		return true
+	}
+	return s.secondary.Delete(word)
+}
+
+func (s *spillStore) Autocomplete(prefix string) []string {
+	return mergeUnique(s.primary.Autocomplete(prefix), s.secondary.Autocomplete(prefix))
+}
+
+func (s *spillStore) Contains(word string) bool {
+	return s.primary.Contains(word) || s.secondary.Contains(word)
+}
+
+func (s *spillStore) ListContents() []string {
+	return mergeUnique(s.primary.ListContents(), s.secondary.ListContents())
+}
+
+// Visualize only renders the primary store, the secondary store may not
+// be feasible to render.
+func (s *spillStore) Visualize(w io.Writer) error {
+	return s.primary.Visualize(w)
+}
+
+func (s *spillStore) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.primary.Clear()
+	s.secondary.Clear()
+	s.size = 0
+}
+
+// Count adds up both tiers, a word is only ever stored in one of them.
+func (s *spillStore) Count() int {
+	return s.primary.Count() + s.secondary.Count()
+}
+
+func (s *spillStore) PrefixCount(prefix string) int {
+	return s.primary.PrefixCount(prefix) + s.secondary.PrefixCount(prefix)
+}
+
+func (s *spillStore) NodeCount() int {
+	return s.primary.NodeCount() + s.secondary.NodeCount()
+}
+
+// mergeUnique appends the words in b that are not already in a.
+func mergeUnique(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+
+	seen := make(map[string]struct{}, len(a))
+	for _, word := range a {
+		seen[word] = struct{}{}
+	}
+
+	for _, word := range b {
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		a = append(a, word)
+	}
+	return a
+}
@@ -0,0 +1,84 @@
+package autocomplete
+
+import (
+	"testing"
+)
+
+func TestSpillStore(t *testing.T) {
+	secondary := newTrie()
+	service := testService(t, nil, WithSpillStore(secondary, 2))
+
+	for _, word := range []string{"bike", "bike path", "bicycle repair", "beach", "bike"} {
+		service.Add(word)
+	}
+
+	store, ok := service.store.(*spillStore)
+	if !ok {
+		t.Fatalf("Expected a spill store, got %T", service.store)
+	}
+
+	primary := store.primary.ListContents()
+	if len(primary) != 2 {
+		t.Errorf("Expected 2 words in the primary store, got %v", primary)
+	}
+	for _, word := range []string{"bike", "bike path"} {
+		if !store.primary.Contains(word) {
+			t.Errorf("Expected %q in the primary store", word)
+		}
+	}
+
+	spilled := secondary.ListContents()
+	if len(spilled) != 2 {
+		t.Errorf("Expected 2 words in the secondary store, got %v", spilled)
+	}
+	for _, word := range []string{"bicycle repair", "beach"} {
+		if !secondary.Contains(word) {
+			t.Errorf("Expected %q in the secondary store", word)
+		}
+	}
+
+	// Both tiers are queried.
+	results := service.Complete("bi")
+	if len(results) != 3 {
+		t.Errorf("Expected 3 results, got %v", results)
+	}
+
+	if !service.Exists("beach") {
+		t.Errorf("Expected %q to exist", "beach")
+	}
+
+	if len(service.GetContents()) != 4 {
+		t.Errorf("Expected 4 words, got %v", service.GetContents())
+	}
+}
+
+func TestSpillStoreHitTracking(t *testing.T) {
+	// The secondary is the caller's store, it counts hits on its own terms.
+	secondary := newTrie()
+	secondary.trackHits = true
+	service := testService(t, nil, WithSpillStore(secondary, 1), WithHitTracking)
+
+	// Re-inserts reach the tier storing the word, in both tiers.
+	for _, word := range []string{"bike", "beach", "bike", "beach", "bike"} {
+		service.Add(word)
+	}
+
+	expected := []Suggestion{{Word: "bike", Score: 3}, {Word: "beach", Score: 2}}
+	results := service.CompleteWithScores("b")
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, results)
+		}
+	}
+
+	store := service.store.(*spillStore)
+	if store.size != 1 {
+		t.Errorf("Expected 1 word counted in the primary store, got %d", store.size)
+	}
+	if err := store.verify(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
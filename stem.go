@@ -0,0 +1,92 @@
+package autocomplete
+
+import (
+	"strings"
+)
+
+// stem reduces every word of a phrase to a rough English stem, e.g. "cats",
+// "running" and "runs" are reduced to "cat", "run" and "run". This is a much
+// simpler stemmer than Porter's, it only strips the plural and the most common
+// verb suffixes, which is enough to merge the obvious variants of a word.
+func stem(phrase string) string {
+	words := strings.Fields(strings.ToLower(phrase))
+	for i, word := range words {
+		words[i] = stemWord(word)
+	}
+	return strings.Join(words, " ")
+}
+
+func stemWord(word string) string {
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "zes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ing") && len(word) > 5:
+		return undouble(word[:len(word)-3])
+	case strings.HasSuffix(word, "ed") && len(word) > 4:
+		return undouble(word[:len(word)-2])
+	case strings.HasSuffix(word, "s") && len(word) > 3 &&
+		!strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// undouble drops the last letter of a stem ending in a double consonant, so
+// "running" becomes "run" and not "runn". Like Porter's, l, s and z are kept
+// doubled, e.g. "falling" becomes "fall".
+func undouble(word string) string {
+	n := len(word)
+	if n < 2 || word[n-1] != word[n-2] {
+		return word
+	}
+	switch word[n-1] {
+	case 'a', 'e', 'i', 'o', 'u', 'l', 's', 'z':
+		return word
+	}
+	return word[:n-1]
+}
+
+// mergeStems keeps a single canonical word per stem, in the position of the
+// best ranked word sharing the stem. The canonical word is the one equal to
+// the stem when stored, the shortest one otherwise, ties broken lexically.
+func mergeStems(words []string) []string {
+	stems := make([]string, len(words))
+	canonical := make(map[string]string)
+	for i, word := range words {
+		s := stem(word)
+		stems[i] = s
+
+		current, ok := canonical[s]
+		if !ok || canonicalLess(word, current, s) {
+			canonical[s] = word
+		}
+	}
+
+	results := words[:0]
+	for _, s := range stems {
+		word, ok := canonical[s]
+		if !ok {
+			// Already returned.
+			continue
+		}
+		delete(canonical, s)
+		results = append(results, word)
+	}
+	return results
+}
+
+// canonicalLess reports whether a is a better canonical word than b for stem s.
+func canonicalLess(a, b, s string) bool {
+	if (a == s) != (b == s) {
+		return a == s
+	}
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
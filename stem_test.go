@@ -0,0 +1,57 @@
+package autocomplete
+
+import (
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"cats":       "cat",
+		"runs":       "run",
+		"running":    "run",
+		"stopped":    "stop",
+		"falling":    "fall",
+		"berries":    "berry",
+		"boxes":      "box",
+		"churches":   "church",
+		"classes":    "class",
+		"bus":        "bus",
+		"glass":      "glass",
+		"Bike Paths": "bike path",
+		"is":         "is",
+	}
+
+	for word, expected := range tests {
+		if got := stem(word); got != expected {
+			t.Errorf("Expected %q to stem to %q, got %q", word, expected, got)
+		}
+	}
+}
+
+func TestStemming(t *testing.T) {
+	words := []string{"running", "runs", "rug", "cat", "cats", "catalog"}
+
+	for _, opts := range [][]ConfigFn{{WithStemming}, {WithStemming, WithLowMemoryMode}} {
+		service := testService(t, words, opts...)
+
+		// Without "run" stored, the shortest variant is canonical.
+		assertWords(t, []string{"rug", "runs"}, service.Complete("ru"))
+		// Only the completions of the prefix are merged.
+		assertWords(t, []string{"running"}, service.Complete("runn"))
+
+		service.Add("run")
+		assertWords(t, []string{"rug", "run"}, service.Complete("ru"))
+
+		assertWords(t, []string{"cat", "catalog"}, service.Complete("ca"))
+
+		// The variants are all still stored.
+		if !service.Exists("cats") {
+			t.Errorf("Expected %q to exist", "cats")
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		service := testService(t, words)
+		assertWords(t, []string{"rug", "running", "runs"}, service.Complete("ru"))
+	})
+}
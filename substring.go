@@ -0,0 +1,166 @@
+package autocomplete
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// substringIndex maps every suffix of the stored words back to the words, so
+// the words containing a substring are the ones with a suffix starting with
+// it. See WithSubstringIndex().
+type substringIndex struct {
+	// built is only set while holding mu, it lets the updates skip taking the
+	// lock until the index is needed.
+	built atomic.Bool
+
+	mu   sync.Mutex
+	opts storeOptions
+	// suffixes holds the suffixes of the stored keys, words the keys having
+	// each of them as a suffix, mapped to the word they were inserted as.
+	suffixes *radixTree
+	words    map[string]map[string]string
+}
+
+// build indexes the contents of store, unless the index is already built.
+func (s *substringIndex) build(store autocompleter, opts storeOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buildLocked(store, opts)
+}
+
+// buildLocked behaves like build, the caller must hold the lock.
+func (s *substringIndex) buildLocked(store autocompleter, opts storeOptions) {
+	if s.built.Load() {
+		return
+	}
+
+	s.opts = opts
+	s.suffixes = newRadixTree()
+	s.words = make(map[string]map[string]string)
+	for _, word := range store.ListContents() {
+		s.add(word)
+	}
+	s.built.Store(true)
+}
+
+// add indexes every suffix of word, the caller must hold the lock.
+func (s *substringIndex) add(word string) {
+	key := s.opts.key(word)
+	for i := range key {
+		suffix := key[i:]
+		words, ok := s.words[suffix]
+		if !ok {
+			words = make(map[string]string, 1)
+			s.words[suffix] = words
+			s.suffixes.Insert(suffix)
+		}
+		if _, ok := words[key]; !ok {
+			words[key] = word
+		}
+	}
+}
+
+// insert indexes word when the index is built.
+func (s *substringIndex) insert(word string) {
+	if word == "" || !s.built.Load() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Checked again, the index may have been reset in between.
+	if s.built.Load() {
+		s.add(word)
+	}
+}
+
+// remove drops word from the index when the index is built.
+func (s *substringIndex) remove(word string) {
+	if !s.built.Load() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Checked again, the index may have been reset in between.
+	if !s.built.Load() {
+		return
+	}
+
+	key := s.opts.key(word)
+	for i := range key {
+		suffix := key[i:]
+		words, ok := s.words[suffix]
+		if !ok {
+			continue
+		}
+		delete(words, key)
+		if len(words) == 0 {
+			delete(s.words, suffix)
+			s.suffixes.Delete(suffix)
+		}
+	}
+}
+
+// reset drops the index, it's built again on the next query.
+func (s *substringIndex) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.suffixes = nil
+	s.words = nil
+	s.built.Store(false)
+}
+
+// complete returns the words of store containing substr, sorted lexically.
+// The index is built first if needed, under the same lock so a reset can't
+// drop it in between.
+func (s *substringIndex) complete(store autocompleter, opts storeOptions, substr string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buildLocked(store, opts)
+
+	seen := make(map[string]struct{})
+	results := []string{}
+	for _, suffix := range s.suffixes.Autocomplete(s.opts.key(substr)) {
+		for key, word := range s.words[suffix] {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				results = append(results, word)
+			}
+		}
+	}
+	sort.Strings(results)
+	return results
+}
+
+// CompleteContains returns the words containing substr anywhere, so "path"
+// completes "bike path". Results are sorted lexically.
+//
+// Without WithSubstringIndex(), every stored word is scanned on each call.
+// With it, the words are looked up in the substring index, which is kept up to
+// date by every insert and removal made through the service, and rebuilt on
+// the next call after Clear(), RestoreBinary() or Reload().
+func (a *AutocompleteService) CompleteContains(substr string) []string {
+	if !a.acquire() {
+		return []string{}
+	}
+	defer a.release()
+	a.queries.record(substr)
+
+	opts := a.Config.storeOptions()
+	if a.Config.SubstringIndex {
+		return a.substrings.complete(a.store, opts, substr)
+	}
+
+	key := opts.key(substr)
+	results := []string{}
+	for _, word := range a.store.ListContents() {
+		if strings.Contains(opts.key(word), key) {
+			results = append(results, word)
+		}
+	}
+	sort.Strings(results)
+	return results
+}
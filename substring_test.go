@@ -0,0 +1,90 @@
+package autocomplete
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCompleteContains(t *testing.T) {
+	words := []string{"bike", "bikepath", "bike path", "footpath", "pool", "beach"}
+
+	for _, tt := range []struct {
+		name string
+		opts []ConfigFn
+	}{
+		{"scan", nil},
+		{"index", []ConfigFn{WithSubstringIndex}},
+		{"index tst", []ConfigFn{WithSubstringIndex, WithBackend(BackendTST)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(t, words, tt.opts...)
+
+			// None of them starts with path.
+			assertWords(t, []string{}, service.Complete("path"))
+			assertWords(t, []string{"bike path", "bikepath", "footpath"}, service.CompleteContains("path"))
+			assertWords(t, []string{"bike", "bike path", "bikepath"}, service.CompleteContains("ike"))
+			assertWords(t, []string{"pool"}, service.CompleteContains("ool"))
+			assertWords(t, []string{}, service.CompleteContains("paths"))
+			assertWords(t, service.GetContents(), service.CompleteContains(""))
+
+			service.Add("garden path")
+			service.Remove("footpath")
+			assertWords(t, []string{"bike path", "bikepath", "garden path"}, service.CompleteContains("path"))
+
+			service.Clear(false)
+			assertWords(t, []string{}, service.CompleteContains("path"))
+			service.Add("towpath")
+			assertWords(t, []string{"towpath"}, service.CompleteContains("path"))
+		})
+	}
+}
+
+func TestSubstringIndex(t *testing.T) {
+	service := testService(t, []string{"Bike Path"}, WithSubstringIndex, WithCaseInsensitive)
+	// Built by New, the index is kept up to date from then on.
+	if !service.substrings.built.Load() {
+		t.Fatalf("Expected the index to be built")
+	}
+	service.Add("bike path")
+	service.AddBatch([]string{"FOOTPATH"})
+	if err := service.LoadDataSource(*NewDataSource(&mockProvider{words: []string{"towpath"}}, nil, "words.json", "")); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	assertWords(t, []string{"Bike Path", "FOOTPATH", "towpath"}, service.CompleteContains("PATH"))
+
+	// Every suffix is stored once, whatever the number of words sharing it.
+	if got := len(service.substrings.words["path"]); got != 3 {
+		t.Errorf("Expected 3 words ending with path, got %d", got)
+	}
+	service.Remove("BIKE PATH")
+	if _, ok := service.substrings.words["ike path"]; ok {
+		t.Errorf("Expected the suffixes of bike path to be dropped")
+	}
+	assertWords(t, []string{"FOOTPATH", "towpath"}, service.CompleteContains("path"))
+}
+
+// Run with -race, see TestFoldIndexConcurrentReset.
+func TestSubstringIndexConcurrentReset(t *testing.T) {
+	store := newTrie()
+	store.InsertBatch([]string{"bike path", "footpath"})
+	var s substringIndex
+	s.build(store, storeOptions{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				s.insert("towpath")
+				s.complete(store, storeOptions{}, "path")
+				s.remove("towpath")
+			}
+		}()
+	}
+	for j := 0; j < 200; j++ {
+		s.reset()
+	}
+	wg.Wait()
+}
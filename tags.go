@@ -0,0 +1,107 @@
+package autocomplete
+
+import "sort"
+
+// tagStore is implemented by the stores that can tag their words.
+type tagStore interface {
+	// addTags adds tags to the tags of word, false if it isn't stored.
+	addTags(word string, tags []string) bool
+}
+
+// InsertTagged inserts the word if needed, then adds tags to its tags, e.g.
+// InsertTagged("bike", "product", "outdoors"). Tags are kept when the word is
+// inserted again, and are only supported by the stores created by the service.
+func (a *AutocompleteService) InsertTagged(word string, tags ...string) {
+	if word == "" {
+		return
+	}
+	if !a.acquire() {
+		return
+	}
+	defer a.release()
+
+	if !a.add(word) {
+		return
+	}
+	if ts, ok := a.store.(tagStore); ok && len(tags) > 0 {
+		ts.addTags(word, tags)
+	}
+}
+
+// CompleteByTag behaves like Complete, but only returns the completions tagged
+// with tag, see InsertTagged().
+func (a *AutocompleteService) CompleteByTag(prefix string, tag string) []string {
+	if !a.acquire() {
+		return []string{}
+	}
+	defer a.release()
+	a.queries.record(prefix)
+
+	entries := storeEntries(a.store, prefix)
+	tagged := entries[:0]
+	for _, e := range entries {
+		if e.hasTag(tag) {
+			tagged = append(tagged, e)
+		}
+	}
+	rankEntries(tagged, false)
+	return entryWords(tagged)
+}
+
+func (d wordData) hasTag(tag string) bool {
+	i := sort.SearchStrings(d.tags, tag)
+	return i < len(d.tags) && d.tags[i] == tag
+}
+
+// mergeTags returns a new sorted set with the tags of both, never modifying
+// existing.
+func mergeTags(existing, tags []string) []string {
+	merged := make([]string, 0, len(existing)+len(tags))
+	merged = append(merged, existing...)
+	for _, tag := range tags {
+		i := sort.SearchStrings(merged, tag)
+		if i < len(merged) && merged[i] == tag {
+			continue
+		}
+		merged = append(merged, "")
+		copy(merged[i+1:], merged[i:])
+		merged[i] = tag
+	}
+	return merged
+}
+
+func (s *spillStore) addTags(word string, tags []string) bool {
+	for _, store := range []autocompleter{s.primary, s.secondary} {
+		if ts, ok := store.(tagStore); ok && ts.addTags(word, tags) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *trie) addTags(word string, tags []string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	curr := t.prefixNode(word)
+	if curr == nil || !curr.isEnd {
+		return false
+	}
+	curr.tags = mergeTags(curr.tags, tags)
+	return true
+}
+
+func (t *ternarysearchtree) addTags(word string, tags []string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if word == "" {
+		return false
+	}
+	node := t.contains(t.Root, t.runes(word), 0)
+	if node == nil || !node.IsEnd {
+		return false
+	}
+	node.tags = mergeTags(node.tags, tags)
+	return true
+}
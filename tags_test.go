@@ -0,0 +1,31 @@
+package autocomplete
+
+import "testing"
+
+func TestCompleteByTag(t *testing.T) {
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}, {WithRadixMode}} {
+		service := testService(t, []string{"bicycle repair"}, opts...)
+		service.InsertTagged("bike", "product", "outdoors")
+		service.InsertTagged("bike path", "outdoors", "place")
+		service.InsertTagged("bistro", "place")
+		// Tags are added to the existing ones, duplicates ignored.
+		service.InsertTagged("bistro", "food", "place")
+
+		assertWords(t, []string{"bike"}, service.CompleteByTag("bi", "product"))
+		assertWords(t, []string{"bike", "bike path"}, service.CompleteByTag("bi", "outdoors"))
+		assertWords(t, []string{"bike path", "bistro"}, service.CompleteByTag("bi", "place"))
+		assertWords(t, []string{"bistro"}, service.CompleteByTag("b", "food"))
+		assertWords(t, []string{}, service.CompleteByTag("bike", "food"))
+		assertWords(t, []string{}, service.CompleteByTag("bi", "missing"))
+
+		// Untagged words are still completed as usual.
+		assertWords(t, []string{"bicycle repair", "bike", "bike path", "bistro"}, service.Complete("bi"))
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	existing := []string{"b", "d"}
+	merged := mergeTags(existing, []string{"c", "a", "d", "e"})
+	assertWords(t, []string{"a", "b", "c", "d", "e"}, merged)
+	assertWords(t, []string{"b", "d"}, existing)
+}
@@ -0,0 +1,96 @@
+package autocomplete
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"unicode/utf8"
+)
+
+// treeDumper is implemented by the stores that can write their node structure
+// as JSON, see DumpTreeJSON().
+type treeDumper interface {
+	DumpTreeJSON(w io.Writer) error
+}
+
+// trieNodeJSON is the JSON form of a trieNode, the children are keyed by their
+// rune so they come out in lexical order.
+type trieNodeJSON struct {
+	IsEnd    bool                 `json:"isEnd,omitempty"`
+	Weight   int                  `json:"weight,omitempty"`
+	Children map[string]*trieNode `json:"children,omitempty"`
+}
+
+func (n *trieNode) MarshalJSON() ([]byte, error) {
+	v := trieNodeJSON{IsEnd: n.isEnd, Weight: n.weight}
+	if len(n.children) > 0 {
+		v.Children = make(map[string]*trieNode, len(n.children))
+		for _, edge := range n.children {
+			v.Children[string(edge.key)] = edge.node
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON restores the structure and the weights written by
+// MarshalJSON(), the rest of the bookkeeping isn't dumped.
+func (n *trieNode) UnmarshalJSON(data []byte) error {
+	var v trieNodeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*n = trieNode{isEnd: v.IsEnd}
+	n.weight = v.Weight
+	for key, child := range v.Children {
+		r, size := utf8.DecodeRuneInString(key)
+		if size == 0 || size != len(key) || child == nil {
+			return fmt.Errorf("invalid trie child %q", key)
+		}
+		n.children = append(n.children, trieEdge{key: r, node: child})
+	}
+	sort.Slice(n.children, func(i, j int) bool { return n.children[i].key < n.children[j].key })
+	return nil
+}
+
+// DumpTreeJSON writes the nodes of the trie as indented JSON, starting from
+// the root, for debugging.
+func (t *trie) DumpTreeJSON(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.Root == nil {
+		return errors.New("trie dump: root is nil")
+	}
+	return dumpJSON(w, t.Root)
+}
+
+// DumpTreeJSON writes the nodes of the ternary search tree as indented JSON,
+// starting from the root, for debugging. An empty tree is written as null.
+func (t *ternarysearchtree) DumpTreeJSON(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return dumpJSON(w, t.Root)
+}
+
+func dumpJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// DumpTreeJSON writes the node structure of the store as indented JSON, for
+// debugging. Only the trie and the ternary search tree backends support it.
+func (a *AutocompleteService) DumpTreeJSON(w io.Writer) error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: dumptreejson: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	d, ok := a.store.(treeDumper)
+	if !ok {
+		return fmt.Errorf("autocompleteservice: dumptreejson: the %s backend doesn't support json dumps", backendName(a.store))
+	}
+	return d.DumpTreeJSON(w)
+}
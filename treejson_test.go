@@ -0,0 +1,77 @@
+package autocomplete
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDumpTreeJSON(t *testing.T) {
+	words := []string{"bike", "bin", "be", "café"}
+
+	t.Run("trie", func(t *testing.T) {
+		service := testService(t, words)
+		service.AddWeighted("bin", 3)
+
+		var buf bytes.Buffer
+		if err := service.DumpTreeJSON(&buf); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		root := &trieNode{}
+		if err := json.Unmarshal(buf.Bytes(), root); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		restored := &trie{Root: root}
+		assertWords(t, service.GetContents(), restored.ListContents())
+		if got := restored.prefixNode("bin").weight; got != 3 {
+			t.Errorf("Expected bin to weigh 3, got %d", got)
+		}
+
+		var again bytes.Buffer
+		if err := restored.DumpTreeJSON(&again); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if buf.String() != again.String() {
+			t.Errorf("Expected the same dump, got\n%s\nand\n%s", buf.String(), again.String())
+		}
+	})
+
+	t.Run("tst", func(t *testing.T) {
+		service := testService(t, words, WithBackend(BackendTST))
+
+		var buf bytes.Buffer
+		if err := service.DumpTreeJSON(&buf); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		root := &tstNode{}
+		if err := json.Unmarshal(buf.Bytes(), root); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		restored := &ternarysearchtree{Root: root}
+		assertWords(t, service.GetContents(), restored.ListContents())
+
+		var again bytes.Buffer
+		if err := restored.DumpTreeJSON(&again); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+		if buf.String() != again.String() {
+			t.Errorf("Expected the same dump, got\n%s\nand\n%s", buf.String(), again.String())
+		}
+	})
+
+	if err := json.Unmarshal([]byte(`{"children":{"ab":{}}}`), &trieNode{}); err == nil {
+		t.Errorf("Expected an error for a child keyed by several runes")
+	}
+
+	service := testService(t, words, WithBackend(BackendRadix))
+	if err := service.DumpTreeJSON(&bytes.Buffer{}); err == nil {
+		t.Errorf("Expected an error for the radix backend")
+	}
+	service.Close()
+	if err := service.DumpTreeJSON(&bytes.Buffer{}); !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("Expected ErrServiceClosed, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package autocomplete
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// trendTracker keeps the recent insert and touch times of every word, oldest
+// first, see WithTrending().
+type trendTracker struct {
+	mu     sync.Mutex
+	events map[string][]time.Time
+}
+
+// record adds an event for key at now, dropping its events older than
+// retention.
+func (t *trendTracker) record(key string, now time.Time, retention time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.events == nil {
+		t.events = make(map[string][]time.Time)
+	}
+	events := append(t.events[key], now)
+	t.events[key] = events[since(events, now.Add(-retention)):]
+}
+
+// count returns the number of events of key at or after start. Events before
+// expired, which can't be counted anymore, are dropped along the way.
+func (t *trendTracker) count(key string, start, expired time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	events, ok := t.events[key]
+	if !ok {
+		return 0
+	}
+	events = events[since(events, expired):]
+	if len(events) == 0 {
+		delete(t.events, key)
+		return 0
+	}
+	t.events[key] = events
+
+	if start.Before(expired) {
+		start = expired
+	}
+	return len(events) - since(events, start)
+}
+
+func (t *trendTracker) forget(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.events, key)
+}
+
+func (t *trendTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = nil
+}
+
+// since returns the index of the first of the sorted events at or after start.
+func since(events []time.Time, start time.Time) int {
+	return sort.Search(len(events), func(i int) bool {
+		return !events[i].Before(start)
+	})
+}
+
+// touch records an event for word when trending is enabled.
+func (a *AutocompleteService) touch(word string) {
+	if a.Config.TrendingRetention <= 0 {
+		return
+	}
+	a.trends.record(a.Config.storeOptions().key(word), a.now(), a.Config.TrendingRetention)
+}
+
+// Touch records a use of a stored word, e.g. when a completion is picked, so
+// it trends without being inserted again. It reports whether the word is
+// stored. Only recorded when WithTrending() is set.
+func (a *AutocompleteService) Touch(word string) bool {
+	if !a.acquire() {
+		return false
+	}
+	defer a.release()
+
+	if !a.store.Contains(word) {
+		return false
+	}
+	a.touch(word)
+	return true
+}
+
+// CompleteTrending returns the completions for prefix ranked by how many times
+// they were inserted or touched within the last window, most first, with ties
+// broken lexically. Windows longer than the retention set with WithTrending()
+// only see the retained events.
+//
+// Inserts made through Add(), AddWeighted(), InsertTagged(), Bump() and
+// Touch() are counted, loads from data sources aren't.
+func (a *AutocompleteService) CompleteTrending(prefix string, window time.Duration) []string {
+	if !a.acquire() {
+		return []string{}
+	}
+	defer a.release()
+	a.queries.record(prefix)
+
+	now := a.now()
+	start, expired := now.Add(-window), now.Add(-a.Config.TrendingRetention)
+	opts := a.Config.storeOptions()
+
+	words := a.store.Autocomplete(prefix)
+	counts := make(map[string]int, len(words))
+	for _, word := range words {
+		counts[word] = a.trends.count(opts.key(word), start, expired)
+	}
+
+	sort.SliceStable(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	return words
+}
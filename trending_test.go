@@ -0,0 +1,51 @@
+package autocomplete
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompleteTrending(t *testing.T) {
+	service := testService(t, []string{"beach", "bicycle repair", "bike", "bike path"}, WithTrending(time.Hour))
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	// Nothing trends yet, ordered lexically.
+	assertWords(t, []string{"bicycle repair", "bike", "bike path"}, service.CompleteTrending("bi", time.Minute))
+
+	service.Add("bike path")
+	service.Add("bike path")
+	service.Add("bike path")
+
+	now = now.Add(30 * time.Minute)
+	service.Add("bike")
+	service.Touch("bike")
+	if service.Touch("bistro") {
+		t.Errorf("Expected false for a word not stored")
+	}
+
+	// Both bursts are within the hour.
+	assertWords(t, []string{"bike path", "bike", "bicycle repair"}, service.CompleteTrending("bi", time.Hour))
+	// Only the second one is within the last 10 minutes.
+	assertWords(t, []string{"bike", "bicycle repair", "bike path"}, service.CompleteTrending("bi", 10*time.Minute))
+
+	// The first burst slides out of the window.
+	now = now.Add(45 * time.Minute)
+	service.Bump("bicycle repair")
+	assertWords(t, []string{"bike", "bicycle repair", "bike path"}, service.CompleteTrending("bi", time.Hour))
+
+	// Past the retention, even a longer window doesn't see them.
+	assertWords(t, []string{"bike", "bicycle repair", "bike path"}, service.CompleteTrending("bi", 24*time.Hour))
+	service.Remove("bike")
+	assertWords(t, []string{"bicycle repair", "bike path"}, service.CompleteTrending("bi", 24*time.Hour))
+}
+
+func TestCompleteTrendingDisabled(t *testing.T) {
+	service := testService(t, []string{"bike", "bike path"})
+	service.Add("bike path")
+	if service.trends.events != nil {
+		t.Errorf("Expected no events to be tracked, got %v", service.trends.events)
+	}
+	assertWords(t, []string{"bike", "bike path"}, service.CompleteTrending("bi", time.Hour))
+}
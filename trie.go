@@ -4,66 +4,262 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"slices"
 	"sync"
+	"unicode/utf8"
 )
 
 // Make sure we implement the auto completer
 var _ autocompleter = (*trie)(nil)
 
 type trieNode struct {
-	// Using rune for future extensibility
-	children map[rune]*trieNode
+	// Using rune for future extensibility. Kept sorted by key, so
+	// walking the children visits the words in lexical order.
+	children []trieEdge
 	isEnd    bool
+
+	// only meaningful when isEnd is set.
+	wordData
+}
+
+type trieEdge struct {
+	key  rune
+	node *trieNode
+}
+
+// search returns the index of the child with key r, or where it would be
+// inserted. A plain binary search, so lookups don't allocate.
+func (n *trieNode) search(r rune) int {
+	lo, hi := 0, len(n.children)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if n.children[mid].key < r {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// child returns the child with key r, or nil.
+func (n *trieNode) child(r rune) *trieNode {
+	i := n.search(r)
+	if i < len(n.children) && n.children[i].key == r {
+		return n.children[i].node
+	}
+	return nil
+}
+
+// addChild returns the child with key r, creating it if needed.
+func (n *trieNode) addChild(r rune) *trieNode {
+	i := n.search(r)
+	if i < len(n.children) && n.children[i].key == r {
+		return n.children[i].node
+	}
+
+	child := &trieNode{}
+	n.children = append(n.children, trieEdge{})
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = trieEdge{key: r, node: child}
+	return child
+}
+
+func (n *trieNode) removeChild(r rune) {
+	i := n.search(r)
+	if i < len(n.children) && n.children[i].key == r {
+		n.children = append(n.children[:i], n.children[i+1:]...)
+	}
 }
 
 type trie struct {
 	Root *trieNode
 
+	// seq is the last insertion sequence number handed out.
+	seq uint64
+
+	// count is the number of words stored, kept up to date on every insert
+	// and delete.
+	count int
+
+	// clusters is set in grapheme mode, see newGraphemeTrie().
+	clusters *clusterTable
+
+	// applied on every insert and lookup, see normalize.go.
+	storeOptions
+
 	mu sync.RWMutex
 }
 
 func newTrie() *trie {
 	return &trie{
-		Root: &trieNode{children: make(map[rune]*trieNode)},
+		Root: &trieNode{},
+	}
+}
+
+// newGraphemeTrie creates a trie that uses grapheme clusters instead of runes
+// as its unit, so user perceived characters made of several runes (e.g. flags
+// or emoji with skin tone modifiers) are never split across nodes.
+//
+// NOTE: Clusters made of several runes are ordered after every plain rune,
+// in the order they were first inserted.
+func newGraphemeTrie() *trie {
+	t := newTrie()
+	t.clusters = newClusterTable()
+	return t
+}
+
+// keys splits word into the keys used by the nodes, one per rune or one per
+// grapheme cluster in grapheme mode. Clusters that were never stored are only
+// added to the cluster table when intern is set, otherwise ok is false as no
+// stored word can contain them.
+func (t *trie) keys(word string, intern bool) (keys []rune, ok bool) {
+	word = t.key(word)
+	if t.clusters == nil {
+		return []rune(word), true
+	}
+	return t.clusters.keys(word, intern)
+}
+
+// unit returns the string represented by a node key.
+func (t *trie) unit(r rune) string {
+	if t.clusters == nil {
+		return string(r)
 	}
+	return t.clusters.unit(r)
+}
+
+// prefixNode returns the node at the end of prefix, or nil if no stored word
+// starts with prefix.
+func (t *trie) prefixNode(prefix string) *trieNode {
+	keys, ok := t.keys(prefix, false)
+	if !ok {
+		return nil
+	}
+
+	curr := t.Root
+	for _, r := range keys {
+		if curr = curr.child(r); curr == nil {
+			return nil
+		}
+	}
+	return curr
 }
 
 func (t *trie) Insert(word string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	t.insert(word)
+}
+
+// InsertReport inserts word and reports whether it was newly added, false
+// when it was already stored or is empty.
+func (t *trie) InsertReport(word string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.insert(word)
+}
+
+// InsertBatch inserts every word under a single lock.
+func (t *trie) InsertBatch(words []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, word := range words {
+		t.insert(word)
+	}
+}
+
+// insert inserts word and reports whether it was newly added, the caller must
+// hold the lock.
+func (t *trie) insert(word string) bool {
+	// Would mark the root as a word.
+	word = t.normalize(word)
+	if word == "" {
+		return false
+	}
 
 	if t.Root == nil {
-		t.Root = &trieNode{children: make(map[rune]*trieNode)}
+		t.Root = &trieNode{}
 	}
 
 	curr := t.Root
 
-	for _, r := range word {
-		if _, ok := curr.children[r]; !ok {
-			curr.children[r] = &trieNode{children: make(map[rune]*trieNode)}
-		}
-		curr = curr.children[r]
+	keys, _ := t.keys(word, true)
+	for _, r := range keys {
+		curr = curr.addChild(r)
 	}
 
+	// Keep the first seen form, e.g. "Bike" when "bike" is inserted after.
+	if t.foldCase && !curr.isEnd {
+		curr.display = word
+	}
+	added := !curr.isEnd
+	if added {
+		t.count++
+	}
 	curr.isEnd = true
+	t.seq++
+	curr.seq = t.seq
+	if t.trackHits {
+		curr.weight++
+	}
+	return added
 }
 
-func (t *trie) Autocomplete(prefix string) []string {
-	// 	t.mu.RLock()
-	// 	defer t.mu.RUnlock()
+// Delete unmarks the end of the word, then prunes the nodes that no longer
+// lead to any word so their memory can be reclaimed.
+func (t *trie) Delete(word string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	var results []string
+	keys, ok := t.keys(word, false)
+	if !ok || t.Root == nil {
+		return false
+	}
 
+	// path[i] is the parent of the node at keys[i].
+	path := make([]*trieNode, 0, len(keys))
 	curr := t.Root
+	for _, r := range keys {
+		child := curr.child(r)
+		if child == nil {
+			return false
+		}
+		path = append(path, curr)
+		curr = child
+	}
 
-	// loop through prefix and find the last node of the prefix.
-	for _, r := range prefix {
-		// return empty results if we encounter a letter not in the prefix path in the trie.
-		if _, ok := curr.children[r]; !ok {
-			return results
+	if !curr.isEnd {
+		return false
+	}
+	curr.isEnd = false
+	curr.wordData = wordData{}
+	t.count--
+
+	// Walk back up removing the nodes that are now dead ends.
+	for i := len(path) - 1; i >= 0; i-- {
+		if curr.isEnd || len(curr.children) > 0 {
+			break
 		}
-		curr = curr.children[r]
+		path[i].removeChild(keys[i])
+		curr = path[i]
+	}
+
+	return true
+}
+
+func (t *trie) Autocomplete(prefix string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	prefix = t.normalize(prefix)
+
+	var results []string
+
+	// find the last node of the prefix, no results if we encounter a letter
+	// not in the prefix path in the trie.
+	curr := t.prefixNode(prefix)
+	if curr == nil {
+		return results
 	}
 
 	// Need to search on the last node to find all children.
@@ -74,55 +270,212 @@ func (t *trie) Autocomplete(prefix string) []string {
 
 // This is also known as dfs.
 func (t *trie) findAllChildren(node *trieNode, prefix string, results *[]string) {
+	t.visit(node, prefix, func(word string, _ *trieNode) {
+		*results = append(*results, word)
+	})
+}
+
+// visit calls fn with every word (and its terminal node) in the subtree of node,
+// in lexical order of their keys.
+func (t *trie) visit(node *trieNode, prefix string, fn func(word string, node *trieNode)) {
+	t.walk(node, prefix, func(word string, node *trieNode) bool {
+		fn(word, node)
+		return true
+	})
+}
+
+// walk behaves like visit, but stops as soon as yield returns false, and
+// reports whether it walked the whole subtree. It walks with an explicit
+// stack, recursing would grow the goroutine stack with the length of the
+// longest word.
+func (t *trie) walk(node *trieNode, prefix string, yield func(word string, node *trieNode) bool) bool {
+	return t.walkChecked(node, prefix, nil, yield)
+}
+
+// walkChecked behaves like walk, but also stops as soon as check, when not
+// nil, returns false. check is called on every node visited, words or not.
+func (t *trie) walkChecked(node *trieNode, prefix string, check func() bool, yield func(word string, node *trieNode) bool) bool {
+	if check != nil && !check() {
+		return false
+	}
 	// if node is end we need to make sure to update results with the
 	// prefix which is the full word.
-	if node.isEnd {
-		*results = append(*results, prefix)
+	if node.isEnd && !yield(node.text(prefix), node) {
+		return false
+	}
+
+	type frame struct {
+		node *trieNode
+		key  rune
+		// depth is the length of the word up to the parent of node.
+		depth int
+	}
+
+	word := []byte(prefix)
+	var stack []frame
+	push := func(parent *trieNode, depth int) {
+		// Pushed in reverse, so the smallest key is popped first.
+		for i := len(parent.children) - 1; i >= 0; i-- {
+			edge := parent.children[i]
+			stack = append(stack, frame{node: edge.node, key: edge.key, depth: depth})
+		}
+	}
+
+	push(node, len(word))
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if check != nil && !check() {
+			return false
+		}
+
+		// Everything popped since the parent was visited is deeper, so
+		// word[:depth] still holds the word up to the parent.
+		word = t.appendUnit(word[:f.depth], f.key)
+		if f.node.isEnd && !yield(f.node.text(string(word)), f.node) {
+			return false
+		}
+		push(f.node, len(word))
 	}
+	return true
+}
 
-	for r, child := range node.children {
-		// since we're going to have to search through all the child's children
-		// and all their children might as well just call ourselves with the child node.
-		t.findAllChildren(child, prefix+string(r), results)
+// appendUnit appends the string represented by a node key to buf.
+func (t *trie) appendUnit(buf []byte, r rune) []byte {
+	if t.clusters == nil {
+		return utf8.AppendRune(buf, r)
 	}
+	return append(buf, t.clusters.unit(r)...)
 }
 
-func (t *trie) Contains(word string) bool {
+func (t *trie) entries(prefix string) []entry {
+	_, results := t.lookup(prefix)
+	return results
+}
+
+// lookup returns whether prefix is a stored word along with the entries
+// starting with it, the node at the end of the prefix tells both.
+func (t *trie) lookup(prefix string) (bool, []entry) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
+	prefix = t.normalize(prefix)
 
-	curr := t.Root
+	var results []entry
 
-	for _, r := range word {
-		if _, ok := curr.children[r]; !ok {
-			// we don't have that character in this chain.
-			return false
+	curr := t.prefixNode(prefix)
+	if curr == nil {
+		return false, results
+	}
+
+	t.visit(curr, prefix, func(word string, node *trieNode) {
+		results = append(results, entry{word: word, wordData: node.wordData})
+	})
+
+	return curr.isEnd, results
+}
+
+func (t *trie) addWeight(word string, delta int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	curr := t.prefixNode(word)
+	if curr == nil || !curr.isEnd {
+		return false
+	}
+	curr.weight += delta
+	return true
+}
+
+func (t *trie) resetWeights(base int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.visit(t.Root, "", func(_ string, node *trieNode) {
+		node.weight = base
+	})
+}
+
+func (t *trie) Count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.count
+}
+
+func (t *trie) PrefixCount(prefix string) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if prefix == "" {
+		return t.count
+	}
+
+	curr := t.prefixNode(prefix)
+	if curr == nil {
+		return 0
+	}
+
+	// Only the end markers are counted, so unlike visit() this doesn't need
+	// to build the words.
+	count := 0
+	stack := []*trieNode{curr}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node.isEnd {
+			count++
+		}
+		for _, edge := range node.children {
+			stack = append(stack, edge.node)
 		}
-		// move to the next child node
-		curr = curr.children[r]
 	}
-	// is this node marked as the end? If not technically the word doesn't exist.
-	return curr.isEnd
+	return count
 }
 
-func (t *trie) ListContents() []string {
-	var results []string
+// NodeCount doesn't count the root, which holds no character.
+func (t *trie) NodeCount() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 
 	if t.Root == nil {
-		return results
+		return 0
 	}
 
-	curr := t.Root
-	for r, child := range curr.children {
-		t.findAllChildren(child, string(r), &results)
+	count := 0
+	stack := []*trieNode{t.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		count += len(node.children)
+		for _, edge := range node.children {
+			stack = append(stack, edge.node)
+		}
 	}
+	return count
+}
 
-	return results
+func (t *trie) Contains(word string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	// nil when we don't have one of the characters in this chain.
+	curr := t.prefixNode(word)
+
+	// is this node marked as the end? If not technically the word doesn't exist.
+	return curr != nil && curr.isEnd
+}
+
+func (t *trie) ListContents() []string {
+	return slices.Collect(t.Walk())
 }
 
 // Make the root empty, removing all references to the old data.
 func (t *trie) Clear() {
-	t.Root = &trieNode{children: make(map[rune]*trieNode)}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Root = &trieNode{}
+	t.count = 0
 }
 
 func (t *trie) Visualize(w io.Writer) error {
@@ -143,8 +496,9 @@ func (t *trie) Visualize(w io.Writer) error {
 		return err
 	}
 
-	// Walk pre order and call dotwrite func.
-	if err := writeDot(w, t.Root, "root"); err != nil {
+	// Walk pre order and call dotwrite func. The nodes are numbered as they are
+	// walked, so the same trie is always written the same way.
+	if err := t.writeDot(w, t.Root, "root", make(nodeIDs[*trieNode])); err != nil {
 		return err
 	}
 
@@ -157,36 +511,39 @@ func (t *trie) Visualize(w io.Writer) error {
 
 }
 
-func (n *trieNode) dotId() int64 {
-	addr := fmt.Sprintf("%p", n)
-	id, err := strconv.ParseInt(addr[2:], 16, 64)
-	if err != nil {
-		panic(err)
-	}
-	return id
+func (t *trie) writeDot(w io.Writer, node *trieNode, val string, ids nodeIDs[*trieNode]) error {
+	return t.walkNodes(node, val, func(curr *trieNode, val string) error {
+		nodeId := ids.id(curr)
+		var endLabel string
+		if curr.isEnd {
+			endLabel = "*"
+		} else {
+			endLabel = ""
+		}
+		if _, err := fmt.Fprintf(w, "\t%d [label=\"<l>|<v> %s%s|<r>\"]\n", nodeId, val, endLabel); err != nil {
+			return err
+		}
+		for _, edge := range curr.children {
+			if _, err := fmt.Fprintf(w, "\t%d:v -> %d:v\n", nodeId, ids.id(edge.node)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func writeDot(w io.Writer, node *trieNode, val string) error {
+// walkNodes calls fn on every node under node pre order, with the character
+// of the node as val. Shared by the diagram writers.
+func (t *trie) walkNodes(node *trieNode, val string, fn func(node *trieNode, val string) error) error {
 	if node == nil {
 		return nil
 	}
 
-	curr := node
-	nodeId := curr.dotId()
-	var endLabel string
-	if curr.isEnd {
-		endLabel = "*"
-	} else {
-		endLabel = ""
-	}
-	if _, err := fmt.Fprintf(w, "\t%d [label=\"<l>|<v> %s%s|<r>\"]\n", nodeId, val, endLabel); err != nil {
+	if err := fn(node, val); err != nil {
 		return err
 	}
-	for r, child := range curr.children {
-		if _, err := fmt.Fprintf(w, "\t%d:v -> %d:v\n", nodeId, child.dotId()); err != nil {
-			return err
-		}
-		if err := writeDot(w, child, string(r)); err != nil {
+	for _, edge := range node.children {
+		if err := t.walkNodes(edge.node, t.unit(edge.key), fn); err != nil {
 			return err
 		}
 	}
@@ -1,8 +1,12 @@
 package autocomplete
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -46,3 +50,189 @@ func TestTrie(t *testing.T) {
 	os.Remove("trie.dot")
 
 }
+
+func TestVisualizeDeterministic(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "beach"}
+	for _, tt := range []struct {
+		name string
+		new  func() autocompleter
+	}{
+		{"trie", func() autocompleter { return newTrie() }},
+		{"tst", func() autocompleter { return newTernarySearchTree("") }},
+		{"radix", func() autocompleter { return newRadixTree() }},
+	} {
+		// Built twice, so the nodes live at different addresses.
+		var outputs [2]string
+		for i := range outputs {
+			store := tt.new()
+			store.InsertBatch(words)
+
+			var buf bytes.Buffer
+			if err := store.Visualize(&buf); err != nil {
+				t.Fatalf("%s: Expected nil, got %v", tt.name, err)
+			}
+			outputs[i] = buf.String()
+		}
+		if outputs[0] != outputs[1] {
+			t.Errorf("%s: Expected the same output twice, got\n%s\nand\n%s", tt.name, outputs[0], outputs[1])
+		}
+		if !strings.Contains(outputs[0], "\t0 [label=") {
+			t.Errorf("%s: Expected the nodes to be numbered from 0, got\n%s", tt.name, outputs[0])
+		}
+	}
+}
+func TestTrieDelete(t *testing.T) {
+	trie := newTrie()
+	for _, word := range []string{"bike", "bike path", "bicycle"} {
+		trie.Insert(word)
+	}
+
+	// Deleting a prefix of another word keeps the longer word.
+	if !trie.Delete("bike") {
+		t.Errorf("Expected %q to be deleted", "bike")
+	}
+	if trie.Contains("bike") {
+		t.Errorf("Expected %q to be gone", "bike")
+	}
+	if !trie.Contains("bike path") {
+		t.Errorf("Expected %q to be kept", "bike path")
+	}
+
+	// Deleting a word that doesn't exist is a no-op.
+	for _, word := range []string{"bike", "bi", "bikes", "car"} {
+		if trie.Delete(word) {
+			t.Errorf("Expected %q to not be deleted", word)
+		}
+	}
+	if len(trie.ListContents()) != 2 {
+		t.Errorf("Expected 2 words, got %v", trie.ListContents())
+	}
+
+	// The dead branch is pruned back to where it forks.
+	if !trie.Delete("bike path") {
+		t.Errorf("Expected %q to be deleted", "bike path")
+	}
+	fork := trie.Root.child('b').child('i')
+	if len(fork.children) != 1 {
+		t.Errorf("Expected the %q branch to be pruned, got %d children", "bik", len(fork.children))
+	}
+
+	if !trie.Delete("bicycle") {
+		t.Errorf("Expected %q to be deleted", "bicycle")
+	}
+	if len(trie.Root.children) != 0 {
+		t.Errorf("Expected an empty trie, got %d children", len(trie.Root.children))
+	}
+}
+
+func TestTrieOrder(t *testing.T) {
+	words := []string{"waterfront", "bike path", "bike", "beach", "résumé", "bicycle repair", "dog park", "pool", "resume"}
+
+	trie := newTrie()
+	for _, word := range words {
+		trie.Insert(word)
+	}
+
+	expected := append([]string{}, words...)
+	sort.Strings(expected)
+
+	contents := trie.ListContents()
+	if strings.Join(contents, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, contents)
+	}
+
+	results := trie.Autocomplete("b")
+	if strings.Join(results, ",") != "beach,bicycle repair,bike,bike path" {
+		t.Errorf("Expected sorted results, got %v", results)
+	}
+}
+
+// Run with -race, concurrent inserts used to race with completions.
+func TestTrieConcurrentAccess(t *testing.T) {
+	trie := newTrie()
+	trie.Insert("bike")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				trie.Insert(fmt.Sprintf("bike %d %d", i, j))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if len(trie.Autocomplete("bi")) == 0 {
+					t.Errorf("Expected completions for %q", "bi")
+					return
+				}
+				trie.ListContents()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(trie.ListContents()); n != 801 {
+		t.Errorf("Expected 801 words, got %d", n)
+	}
+}
+
+func benchmarkWords(n int) []string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("%x", i*2654435761)
+	}
+	return words
+}
+
+func BenchmarkTrieInsert(b *testing.B) {
+	words := benchmarkWords(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		trie := newTrie()
+		for _, word := range words {
+			trie.Insert(word)
+		}
+	}
+}
+
+func BenchmarkInsertOneByOne(b *testing.B) {
+	words := benchmarkWords(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		trie := newTrie()
+		for _, word := range words {
+			trie.Insert(word)
+		}
+	}
+}
+
+func BenchmarkInsertBatch(b *testing.B) {
+	words := benchmarkWords(100000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		trie := newTrie()
+		trie.InsertBatch(words)
+	}
+}
+
+func BenchmarkTrieAutocomplete(b *testing.B) {
+	trie := newTrie()
+	for _, word := range benchmarkWords(10000) {
+		trie.Insert(word)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		trie.Autocomplete("a")
+	}
+}
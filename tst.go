@@ -2,12 +2,13 @@ package autocomplete
 
 import (
 	"container/list"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"os"
+	"slices"
 	"sync"
+	"unicode/utf8"
 )
 
 var _ autocompleter = (*ternarysearchtree)(nil)
@@ -16,11 +17,24 @@ type tstNode struct {
 	Char             rune
 	Left, Mid, Right *tstNode
 	IsEnd            bool
+
+	// only meaningful when IsEnd is set.
+	wordData
 }
 
 type ternarysearchtree struct {
 	Root *tstNode
 
+	// seq is the last insertion sequence number handed out.
+	seq uint64
+
+	// count is the number of words stored, kept up to date on every insert
+	// and delete.
+	count int
+
+	// applied on every insert and lookup, see normalize.go.
+	storeOptions
+
 	mu sync.RWMutex
 }
 
@@ -37,49 +51,176 @@ func newTernarySearchTree(word string) *ternarysearchtree {
 	return tst
 }
 
+// Insert ignores empty words, the recursive helpers expect at least one rune.
 func (t *ternarysearchtree) Insert(word string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.Root = t.insert(t.Root, word, 0)
+	t.insertWord(word)
+}
+
+// InsertReport inserts word and reports whether it was newly added, false
+// when it was already stored or is empty.
+func (t *ternarysearchtree) InsertReport(word string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.insertWord(word)
+}
+
+// InsertBatch inserts every word under a single lock.
+func (t *ternarysearchtree) InsertBatch(words []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, word := range words {
+		t.insertWord(word)
+	}
+}
+
+// insertWord inserts word and reports whether it was newly added, the caller
+// must hold the lock.
+func (t *ternarysearchtree) insertWord(word string) bool {
+	word = t.normalize(word)
+	if word == "" {
+		return false
+	}
+
+	var added bool
+	t.Root = t.insert(t.Root, t.runes(word), 0, word, &added)
+	return added
+}
+
+// runes returns the keys of word, see storeOptions.key().
+func (t *ternarysearchtree) runes(word string) []rune {
+	return []rune(t.key(word))
 }
 
-func (t *ternarysearchtree) insert(node *tstNode, word string, index int) *tstNode {
-	char := rune(word[index])
+// The recursive helpers index the word as a []rune, indexing the string
+// directly would split multibyte characters into bytes.
+func (t *ternarysearchtree) insert(node *tstNode, word []rune, index int, display string, added *bool) *tstNode {
+	char := word[index]
 
 	if node == nil {
 		node = newTSTNode(char)
 	}
 
 	if char < node.Char {
-		node.Left = t.insert(node.Left, word, index)
+		node.Left = t.insert(node.Left, word, index, display, added)
 	} else if char > node.Char {
-		node.Right = t.insert(node.Right, word, index)
+		node.Right = t.insert(node.Right, word, index, display, added)
 	} else if index < len(word)-1 {
 		// if the char is equal/not less than or greater than node char
 		// we know we're in the mid, now we need to make sure that we still have
 		// characters left in the word. So we set mid, and increment the index
-		node.Mid = t.insert(node.Mid, word, index+1)
+		node.Mid = t.insert(node.Mid, word, index+1, display, added)
 	} else {
+		// Keep the first seen form, e.g. "Bike" when "bike" is inserted after.
+		if t.foldCase && !node.IsEnd {
+			node.display = display
+		}
+		if !node.IsEnd {
+			t.count++
+			*added = true
+		}
 		node.IsEnd = true
+		t.seq++
+		node.seq = t.seq
+		if t.trackHits {
+			node.weight++
+		}
 	}
 
 	return node
 }
 
+// Delete unmarks the end of the word, then prunes the nodes on its path that
+// no longer lead to any word.
+func (t *ternarysearchtree) Delete(word string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if word == "" {
+		return false
+	}
+
+	var deleted bool
+	t.Root = t.delete(t.Root, t.runes(word), 0, &deleted)
+	return deleted
+}
+
+func (t *ternarysearchtree) delete(node *tstNode, word []rune, index int, deleted *bool) *tstNode {
+	if node == nil {
+		return nil
+	}
+
+	char := word[index]
+
+	if char < node.Char {
+		node.Left = t.delete(node.Left, word, index, deleted)
+	} else if char > node.Char {
+		node.Right = t.delete(node.Right, word, index, deleted)
+	} else if index < len(word)-1 {
+		node.Mid = t.delete(node.Mid, word, index+1, deleted)
+	} else if node.IsEnd {
+		node.IsEnd = false
+		node.wordData = wordData{}
+		*deleted = true
+		t.count--
+	}
+
+	// Still part of a word.
+	if node.IsEnd || node.Mid != nil {
+		return node
+	}
+
+	return t.unlink(node)
+}
+
+// unlink removes a node that is no longer part of any word, and returns the
+// node taking its place among its siblings. This is a plain binary search
+// tree removal over the Left and Right links.
+func (t *ternarysearchtree) unlink(node *tstNode) *tstNode {
+	if node.Left == nil {
+		return node.Right
+	}
+	if node.Right == nil {
+		return node.Left
+	}
+
+	// Both siblings are set, replace the node with its in order successor,
+	// the smallest node of the right subtree.
+	parent := node
+	successor := node.Right
+	for successor.Left != nil {
+		parent = successor
+		successor = successor.Left
+	}
+
+	if parent != node {
+		parent.Left = successor.Right
+		successor.Right = node.Right
+	}
+	successor.Left = node.Left
+
+	return successor
+}
+
 func (t *ternarysearchtree) Contains(word string) bool {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	node := t.contains(t.Root, word, 0)
+
+	if word == "" {
+		return false
+	}
+	node := t.contains(t.Root, t.runes(word), 0)
 	return node != nil && node.IsEnd
 }
 
-func (t *ternarysearchtree) contains(node *tstNode, word string, index int) *tstNode {
-	char := rune(word[index])
-
+func (t *ternarysearchtree) contains(node *tstNode, word []rune, index int) *tstNode {
 	if node == nil {
 		return nil
 	}
 
+	char := word[index]
+
 	if char < node.Char {
 		return t.contains(node.Left, word, index)
 	} else if char > node.Char {
@@ -92,16 +233,113 @@ func (t *ternarysearchtree) contains(node *tstNode, word string, index int) *tst
 
 }
 
+func (t *ternarysearchtree) addWeight(word string, delta int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if word == "" {
+		return false
+	}
+	node := t.contains(t.Root, t.runes(word), 0)
+	if node == nil || !node.IsEnd {
+		return false
+	}
+	node.weight += delta
+	return true
+}
+
+func (t *ternarysearchtree) resetWeights(base int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.visit(t.Root, "", func(_ string, node *tstNode) {
+		node.weight = base
+	})
+}
+
+func (t *ternarysearchtree) NodeCount() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	count := 0
+	stack := []*tstNode{t.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node == nil {
+			continue
+		}
+
+		count++
+		stack = append(stack, node.Left, node.Mid, node.Right)
+	}
+	return count
+}
+
+// Count returns the number of words stored.
+func (t *ternarysearchtree) Count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.count
+}
+
+func (t *ternarysearchtree) PrefixCount(prefix string) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if prefix == "" {
+		return t.count
+	}
+
+	node := t.getPrefixNode(t.Root, t.runes(prefix), 0)
+	if node == nil {
+		return 0
+	}
+
+	count := 0
+	if node.IsEnd {
+		count++
+	}
+
+	// Every node under the middle child continues the prefix.
+	stack := []*tstNode{node.Mid}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node == nil {
+			continue
+		}
+		if node.IsEnd {
+			count++
+		}
+		stack = append(stack, node.Left, node.Mid, node.Right)
+	}
+	return count
+}
+
+// Autocomplete returns every word when prefix is empty, like the trie.
 func (t *ternarysearchtree) Autocomplete(prefix string) []string {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
+	prefix = t.normalize(prefix)
 
 	var results []string
-	node := t.getPrefixNode(t.Root, prefix, 0)
+	if prefix == "" {
+		t.collect(t.Root, "", &results)
+		return results
+	}
+
+	node := t.getPrefixNode(t.Root, t.runes(prefix), 0)
 	if node == nil {
 		return results
 	}
 
+	// The prefix completes itself when it is a word, like in the trie.
+	if node.IsEnd {
+		results = append(results, node.text(prefix))
+	}
+
 	// middle node continues a word. So we know that every
 	// word in the subtree of the middle child of this node
 	// is a valid completion of the prefix.
@@ -110,13 +348,13 @@ func (t *ternarysearchtree) Autocomplete(prefix string) []string {
 	return results
 }
 
-func (t *ternarysearchtree) getPrefixNode(node *tstNode, prefix string, index int) *tstNode {
+func (t *ternarysearchtree) getPrefixNode(node *tstNode, prefix []rune, index int) *tstNode {
 	// recursive so make sure to check first
 	if node == nil {
 		return nil
 	}
 
-	char := rune(prefix[index])
+	char := prefix[index]
 
 	if char < node.Char {
 		return t.getPrefixNode(node.Left, prefix, index)
@@ -131,31 +369,118 @@ func (t *ternarysearchtree) getPrefixNode(node *tstNode, prefix string, index in
 
 // dfs, also in order traversal (left, parent, middle, right)
 func (t *ternarysearchtree) collect(node *tstNode, prefix string, results *[]string) {
-	// recursive so return early.
-	if node == nil {
-		return
+	t.visit(node, prefix, func(word string, _ *tstNode) {
+		*results = append(*results, word)
+	})
+}
+
+// visit calls fn with every word (and its terminal node) in the subtree of node,
+// in order.
+func (t *ternarysearchtree) visit(node *tstNode, prefix string, fn func(word string, node *tstNode)) {
+	t.walk(node, prefix, func(word string, node *tstNode) bool {
+		fn(word, node)
+		return true
+	})
+}
+
+// walk behaves like visit, but stops as soon as yield returns false, and
+// reports whether it walked the whole subtree. It walks with an explicit
+// stack, recursing would grow the goroutine stack with the length of the
+// longest word.
+func (t *ternarysearchtree) walk(node *tstNode, prefix string, yield func(word string, node *tstNode) bool) bool {
+	return t.walkChecked(node, prefix, nil, yield)
+}
+
+// walkChecked behaves like walk, but also stops as soon as check, when not
+// nil, returns false. check is called on every node visited, words or not.
+func (t *ternarysearchtree) walkChecked(node *tstNode, prefix string, check func() bool, yield func(word string, node *tstNode) bool) bool {
+	type frame struct {
+		node *tstNode
+		// depth is the length of the word before node.Char.
+		depth int
+		// visit is set once the left subtree has been pushed, the node
+		// itself is next.
+		visit bool
 	}
 
-	t.collect(node.Left, prefix, results)
-	if node.IsEnd {
-		*results = append(*results, prefix+string(node.Char))
+	word := []byte(prefix)
+	stack := []frame{{node: node, depth: len(word)}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if f.node == nil {
+			continue
+		}
+
+		if !f.visit {
+			// Pushed in reverse of the order they are walked in: left,
+			// the node, then right. The middle is pushed along the node.
+			stack = append(stack,
+				frame{node: f.node.Right, depth: f.depth},
+				frame{node: f.node, depth: f.depth, visit: true},
+				frame{node: f.node.Left, depth: f.depth},
+			)
+			continue
+		}
+		if check != nil && !check() {
+			return false
+		}
+
+		word = utf8.AppendRune(word[:f.depth], f.node.Char)
+		if f.node.IsEnd && !yield(f.node.text(string(word)), f.node) {
+			return false
+		}
+		stack = append(stack, frame{node: f.node.Mid, depth: len(word)})
 	}
-	t.collect(node.Mid, prefix+string(node.Char), results)
-	t.collect(node.Right, prefix, results)
+	return true
+}
 
+func (t *ternarysearchtree) entries(prefix string) []entry {
+	_, results := t.lookup(prefix)
+	return results
 }
 
-func (t *ternarysearchtree) ListContents() []string {
-	var results []string
+// lookup returns whether prefix is a stored word along with the entries
+// starting with it, the node at the end of the prefix tells both.
+func (t *ternarysearchtree) lookup(prefix string) (bool, []entry) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	prefix = t.normalize(prefix)
 
-	t.collect(t.Root, "", &results)
+	var results []entry
+	fn := func(word string, node *tstNode) {
+		results = append(results, entry{word: word, wordData: node.wordData})
+	}
 
-	return results
+	if prefix == "" {
+		t.visit(t.Root, "", fn)
+		return false, results
+	}
+
+	node := t.getPrefixNode(t.Root, t.runes(prefix), 0)
+	if node == nil {
+		return false, results
+	}
+	if node.IsEnd {
+		fn(node.text(prefix), node)
+	}
+	t.visit(node.Mid, prefix, fn)
+
+	return node.IsEnd, results
 }
 
-// Make the root empty, removing all references to the old data.
+func (t *ternarysearchtree) ListContents() []string {
+	return slices.Collect(t.Walk())
+}
+
+// Drop the root, removing all references to the old data. An empty tree has
+// no root, a placeholder root would be a node leading to no word.
 func (t *ternarysearchtree) Clear() {
-	t.Root = &tstNode{}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Root = nil
+	t.count = 0
 }
 
 func (t *ternarysearchtree) Visualize(w io.Writer) error {
@@ -176,8 +501,9 @@ func (t *ternarysearchtree) Visualize(w io.Writer) error {
 		return err
 	}
 
-	// Walk pre order and call dotwrite func.
-	if err := t.writeDot(w, t.Root, nil); err != nil {
+	// Walk pre order and call dotwrite func. The nodes are numbered as they are
+	// walked, so the same tree is always written the same way.
+	if err := t.writeDot(w, t.Root, make(nodeIDs[*tstNode])); err != nil {
 		return err
 	}
 
@@ -189,16 +515,15 @@ func (t *ternarysearchtree) Visualize(w io.Writer) error {
 	return nil
 }
 
-func (n *tstNode) dotId() int64 {
-	addr := fmt.Sprintf("%p", n)
-	id, err := strconv.ParseInt(addr[2:], 16, 64)
-	if err != nil {
-		panic(err)
-	}
-	return id
+func (t *ternarysearchtree) writeDot(w io.Writer, node *tstNode, ids nodeIDs[*tstNode]) error {
+	return walkNodes(node, func(node *tstNode) error {
+		return dotWriteFunc(w, node, ids)
+	})
 }
 
-func (t *ternarysearchtree) writeDot(w io.Writer, node *tstNode, err error) error {
+// walkNodes calls fn on every node under node pre order, the left child
+// before the middle one before the right one. Shared by the diagram writers.
+func walkNodes(node *tstNode, fn func(node *tstNode) error) error {
 	if node == nil {
 		return nil
 	}
@@ -209,7 +534,7 @@ func (t *ternarysearchtree) writeDot(w io.Writer, node *tstNode, err error) erro
 	for list.Len() > 0 {
 		node = list.Remove(list.Front()).(*tstNode)
 
-		if err := dotWriteFunc(w, node); err != nil {
+		if err := fn(node); err != nil {
 			return err
 		}
 
@@ -229,8 +554,8 @@ func (t *ternarysearchtree) writeDot(w io.Writer, node *tstNode, err error) erro
 	return nil
 }
 
-func dotWriteFunc(w io.Writer, n *tstNode) error {
-	nodeId := n.dotId()
+func dotWriteFunc(w io.Writer, n *tstNode, ids nodeIDs[*tstNode]) error {
+	nodeId := ids.id(n)
 	val := string(n.Char)
 	if n.Char == 0 {
 		val = "root"
@@ -253,19 +578,19 @@ func dotWriteFunc(w io.Writer, n *tstNode) error {
 	}
 
 	if n.Left != nil {
-		if _, err := fmt.Fprintf(w, "\t%d:l -> %d:v\n", nodeId, n.Left.dotId()); err != nil {
+		if _, err := fmt.Fprintf(w, "\t%d:l -> %d:v\n", nodeId, ids.id(n.Left)); err != nil {
 			return err
 		}
 	}
 
 	if n.Mid != nil {
-		if _, err := fmt.Fprintf(w, "\t%d:v -> %d:v\n", nodeId, n.Mid.dotId()); err != nil {
+		if _, err := fmt.Fprintf(w, "\t%d:v -> %d:v\n", nodeId, ids.id(n.Mid)); err != nil {
 			return err
 		}
 	}
 
 	if n.Right != nil {
-		if _, err := fmt.Fprintf(w, "\t%d:r -> %d:v\n", nodeId, n.Right.dotId()); err != nil {
+		if _, err := fmt.Fprintf(w, "\t%d:r -> %d:v\n", nodeId, ids.id(n.Right)); err != nil {
 			return err
 		}
 	}
@@ -273,12 +598,9 @@ func dotWriteFunc(w io.Writer, n *tstNode) error {
 	return nil
 }
 
+// PrintJSON writes the tree to stdout, see DumpTreeJSON().
 func (t *ternarysearchtree) PrintJSON() {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-	b, err := json.MarshalIndent(t.Root, "", "  ")
-	if err != nil {
+	if err := t.DumpTreeJSON(os.Stdout); err != nil {
 		fmt.Println(err)
 	}
-	fmt.Println(string(b))
 }
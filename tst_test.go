@@ -3,6 +3,8 @@ package autocomplete
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -66,3 +68,112 @@ func TestTernarySearchTree(t *testing.T) {
 	})
 
 }
+
+func countTSTNodes(node *tstNode) int {
+	if node == nil {
+		return 0
+	}
+	return 1 + countTSTNodes(node.Left) + countTSTNodes(node.Mid) + countTSTNodes(node.Right)
+}
+
+func TestTernarySearchTreeDelete(t *testing.T) {
+	words := []string{"code", "cob", "be", "ax", "war", "we", "bike", "bike path"}
+
+	tree := newTernarySearchTree("")
+	for _, word := range words {
+		tree.Insert(word)
+	}
+
+	// Deleting a prefix of another word keeps the longer word.
+	if !tree.Delete("bike") {
+		t.Errorf("Expected %q to be deleted", "bike")
+	}
+	if tree.Contains("bike") {
+		t.Errorf("Expected %q to be gone", "bike")
+	}
+	if !tree.Contains("bike path") {
+		t.Errorf("Expected %q to be kept", "bike path")
+	}
+
+	// Deleting a word that doesn't exist is a no-op.
+	for _, word := range []string{"bike", "co", "cobs", "zebra", ""} {
+		if tree.Delete(word) {
+			t.Errorf("Expected %q to not be deleted", word)
+		}
+	}
+
+	// Delete everything, including the root and nodes with both siblings set,
+	// checking the remaining words along the way.
+	remaining := map[string]bool{}
+	for _, word := range words {
+		remaining[word] = word != "bike"
+	}
+	for _, word := range []string{"code", "be", "war", "ax", "bike path", "cob", "we"} {
+		if !tree.Delete(word) {
+			t.Errorf("Expected %q to be deleted", word)
+		}
+		remaining[word] = false
+
+		for other, ok := range remaining {
+			if tree.Contains(other) != ok {
+				t.Errorf("Expected Contains(%q) to be %v after deleting %q", other, ok, word)
+			}
+		}
+	}
+
+	if n := countTSTNodes(tree.Root); n != 0 {
+		t.Errorf("Expected every node to be pruned, got %d nodes", n)
+	}
+}
+
+func TestTernarySearchTreeMultibyte(t *testing.T) {
+	words := []string{"naïve", "naïveté", "日本"}
+
+	tree := newTernarySearchTree("")
+	for _, word := range words {
+		tree.Insert(word)
+	}
+
+	for _, word := range words {
+		if !tree.Contains(word) {
+			t.Errorf("Expected %q to be stored", word)
+		}
+	}
+	if tree.Contains("日") {
+		t.Errorf("Expected %q to not be stored", "日")
+	}
+
+	contents := tree.ListContents()
+	sort.Strings(contents)
+	if strings.Join(contents, ",") != strings.Join(words, ",") {
+		t.Errorf("Expected %v, got %v", words, contents)
+	}
+
+	results := tree.Autocomplete("na")
+	sort.Strings(results)
+	if strings.Join(results, ",") != "naïve,naïveté" {
+		t.Errorf("Expected [naïve naïveté], got %v", results)
+	}
+
+	results = tree.Autocomplete("日")
+	if len(results) != 1 || results[0] != "日本" {
+		t.Errorf("Expected [日本], got %v", results)
+	}
+}
+
+func TestTernarySearchTreeEmptyWord(t *testing.T) {
+	tree := newTernarySearchTree("")
+	for _, word := range []string{"bike", "", "beach", ""} {
+		tree.Insert(word)
+	}
+
+	if tree.Contains("") {
+		t.Errorf("Expected the empty word to not be stored")
+	}
+	if n := len(tree.ListContents()); n != 2 {
+		t.Errorf("Expected 2 words, got %d", n)
+	}
+	if n := len(tree.Autocomplete("")); n != 2 {
+		t.Errorf("Expected every word for an empty prefix, got %d", n)
+	}
+}
@@ -0,0 +1,133 @@
+package autocomplete
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// verifier is implemented by the stores that can check their own invariants.
+type verifier interface {
+	// verify returns an error describing the first broken invariant found.
+	verify() error
+}
+
+// Verify checks that the structure of the store is consistent: the word count
+// matches the words actually stored, no node is left that leads to no word,
+// and the nodes are ordered. It walks the whole store, so it's meant for tests
+// and debugging after complex operations, not for the hot path.
+//
+// Stores that can't check themselves are assumed consistent.
+func (a *AutocompleteService) Verify() error {
+	if !a.acquire() {
+		return fmt.Errorf("autocompleteservice: verify: %w", ErrServiceClosed)
+	}
+	defer a.release()
+
+	if v, ok := a.store.(verifier); ok {
+		if err := v.verify(); err != nil {
+			return fmt.Errorf("autocompleteservice: verify: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *spillStore) verify() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if count := s.primary.Count(); count != s.size {
+		return fmt.Errorf("spill: size is %d, but the primary holds %d words", s.size, count)
+	}
+	for _, store := range []autocompleter{s.primary, s.secondary} {
+		if v, ok := store.(verifier); ok {
+			if err := v.verify(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *trie) verify() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.Root == nil {
+		return errors.New("trie: root is nil")
+	}
+	if t.Root.isEnd {
+		return errors.New("trie: root is marked as a word")
+	}
+
+	words := 0
+	stack := []*trieNode{t.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node.isEnd {
+			words++
+		} else if len(node.children) == 0 && node != t.Root {
+			return errors.New("trie: leaf node is not the end of a word")
+		}
+
+		for i, edge := range node.children {
+			if edge.node == nil {
+				return fmt.Errorf("trie: child %q is nil", t.unit(edge.key))
+			}
+			if i > 0 && node.children[i-1].key >= edge.key {
+				return fmt.Errorf("trie: children %q and %q are out of order", t.unit(node.children[i-1].key), t.unit(edge.key))
+			}
+			stack = append(stack, edge.node)
+		}
+	}
+
+	if words != t.count {
+		return fmt.Errorf("trie: count is %d, but %d words are stored", t.count, words)
+	}
+	return nil
+}
+
+func (t *ternarysearchtree) verify() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	// The siblings reachable through Left and Right form a binary search
+	// tree, so every node has to fall within the bounds set by its ancestors.
+	type frame struct {
+		node   *tstNode
+		lo, hi rune
+	}
+
+	words := 0
+	stack := []frame{{node: t.Root, lo: -1, hi: utf8.MaxRune + 1}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		node := f.node
+		if node == nil {
+			continue
+		}
+
+		if node.Char <= f.lo || node.Char >= f.hi {
+			return fmt.Errorf("tst: node %q is out of order among its siblings", node.Char)
+		}
+		if node.IsEnd {
+			words++
+		} else if node.Mid == nil {
+			return fmt.Errorf("tst: node %q is not the end of a word and has no middle child", node.Char)
+		}
+
+		stack = append(stack,
+			frame{node: node.Left, lo: f.lo, hi: node.Char},
+			frame{node: node.Right, lo: node.Char, hi: f.hi},
+			frame{node: node.Mid, lo: -1, hi: utf8.MaxRune + 1},
+		)
+	}
+
+	if words != t.count {
+		return fmt.Errorf("tst: count is %d, but %d words are stored", t.count, words)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package autocomplete
+
+import (
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	words := []string{"beach", "bicycle repair", "bike", "bike path", "bikes", "dog park", "pool", "café"}
+
+	for _, opts := range [][]ConfigFn{{}, {WithLowMemoryMode}, {WithRadixMode}, {WithGraphemeClusters}, {WithSpillStore(newTrie(), 3)}} {
+		service := testService(t, words, opts...)
+		if err := service.Verify(); err != nil {
+			t.Fatalf("Expected nil, got %v", err)
+		}
+
+		for _, word := range []string{"bike", "bicycle repair", "pool", "missing"} {
+			service.Remove(word)
+			if err := service.Verify(); err != nil {
+				t.Fatalf("Expected nil after removing %q, got %v", word, err)
+			}
+		}
+
+		service.Clear(false)
+		if err := service.Verify(); err != nil {
+			t.Fatalf("Expected nil after clearing, got %v", err)
+		}
+	}
+}
+
+func TestVerifyCorrupted(t *testing.T) {
+	words := []string{"bike", "bike path", "pool"}
+
+	tests := []struct {
+		name    string
+		opts    []ConfigFn
+		corrupt func(store autocompleter)
+	}{
+		{"trie count", nil, func(store autocompleter) {
+			store.(*trie).count++
+		}},
+		{"trie dead leaf", nil, func(store autocompleter) {
+			store.(*trie).prefixNode("pool").addChild('s')
+		}},
+		{"trie order", nil, func(store autocompleter) {
+			root := store.(*trie).Root
+			root.children[0], root.children[1] = root.children[1], root.children[0]
+		}},
+		{"tst count", []ConfigFn{WithLowMemoryMode}, func(store autocompleter) {
+			store.(*ternarysearchtree).count--
+		}},
+		{"tst dead leaf", []ConfigFn{WithLowMemoryMode}, func(store autocompleter) {
+			tst := store.(*ternarysearchtree)
+			node := tst.contains(tst.Root, []rune("pool"), 0)
+			node.Mid = newTSTNode('s')
+		}},
+		{"tst order", []ConfigFn{WithLowMemoryMode}, func(store autocompleter) {
+			tst := store.(*ternarysearchtree)
+			tst.Root.Left = newTSTNode('z')
+			tst.Root.Left.IsEnd = true
+			tst.count++
+		}},
+		{"spill size", []ConfigFn{WithSpillStore(newTrie(), 1)}, func(store autocompleter) {
+			store.(*spillStore).size++
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := testService(t, words, tt.opts...)
+			if err := service.Verify(); err != nil {
+				t.Fatalf("Expected nil, got %v", err)
+			}
+
+			tt.corrupt(service.store)
+			if err := service.Verify(); err == nil {
+				t.Errorf("Expected non-nil, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package autocomplete
+
+import (
+	"iter"
+	"slices"
+)
+
+// walker is implemented by the stores that can stream their words, see
+// AutocompleteService.Walk().
+type walker interface {
+	Walk() iter.Seq[string]
+}
+
+// Walk returns an iterator over the stored words, in the order of
+// ListContents(). The trie is walked as the iteration goes, so breaking out of
+// the loop early skips the rest of it.
+//
+// The read lock is held for the whole iteration: finish it promptly, and
+// don't write to the trie from the loop body, it would deadlock.
+func (t *trie) Walk() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		t.mu.RLock()
+		defer t.mu.RUnlock()
+
+		if t.Root == nil {
+			return
+		}
+		// The root is never a word, so this only walks its children.
+		t.walk(t.Root, "", func(word string, _ *trieNode) bool {
+			return yield(word)
+		})
+	}
+}
+
+// Walk returns an iterator over the stored words, in the order of
+// ListContents(). The tree is walked as the iteration goes, so breaking out of
+// the loop early skips the rest of it.
+//
+// The read lock is held for the whole iteration: finish it promptly, and
+// don't write to the tree from the loop body, it would deadlock.
+func (t *ternarysearchtree) Walk() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		t.mu.RLock()
+		defer t.mu.RUnlock()
+
+		t.walk(t.Root, "", func(word string, _ *tstNode) bool {
+			return yield(word)
+		})
+	}
+}
+
+// Walk returns an iterator over the stored words, streamed from the store
+// instead of collected in a slice like GetContents() when the backend supports
+// it (the trie and the ternary search tree).
+//
+// The service and the store stay locked for the whole iteration, which blocks
+// Close() and the writes: finish it promptly, and don't call the service from
+// the loop body, it may deadlock with a concurrent writer.
+func (a *AutocompleteService) Walk() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if !a.acquire() {
+			return
+		}
+		defer a.release()
+
+		if w, ok := a.store.(walker); ok {
+			w.Walk()(yield)
+			return
+		}
+		slices.Values(a.store.ListContents())(yield)
+	}
+}
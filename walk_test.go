@@ -0,0 +1,96 @@
+package autocomplete
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	words := []string{"bike", "bike path", "bicycle repair", "pool", "beach"}
+
+	for _, tt := range []struct {
+		name  string
+		store walker
+	}{
+		{"trie", newTrie()},
+		{"tst", newTernarySearchTree("")},
+	} {
+		store := tt.store.(autocompleter)
+		if got := slices.Collect(tt.store.Walk()); len(got) != 0 {
+			t.Errorf("%s: Expected no words, got %v", tt.name, got)
+		}
+		store.InsertBatch(words)
+
+		if got := slices.Collect(tt.store.Walk()); strings.Join(got, ",") != strings.Join(store.ListContents(), ",") {
+			t.Errorf("%s: Expected the order of ListContents, got %v", tt.name, got)
+		}
+
+		var walked []string
+		for word := range tt.store.Walk() {
+			walked = append(walked, word)
+			if len(walked) == 2 {
+				break
+			}
+		}
+		assertWords(t, []string{"beach", "bicycle repair"}, walked)
+
+		// The read lock is released once the loop is left.
+		store.Insert("dog park")
+		if !store.Contains("dog park") {
+			t.Errorf("%s: Expected dog park to be stored", tt.name)
+		}
+	}
+}
+
+func TestWalkStopsEarly(t *testing.T) {
+	words := benchmarkWords(20000)
+
+	for _, tt := range []struct {
+		name  string
+		store walker
+	}{
+		{"trie", newTrie()},
+		{"tst", newTernarySearchTree("")},
+	} {
+		tt.store.(autocompleter).InsertBatch(words)
+
+		walk := func(n int) {
+			walked := 0
+			for range tt.store.Walk() {
+				if walked++; walked == n {
+					break
+				}
+			}
+		}
+
+		// Every word walked costs at least its string, breaking after 10 words
+		// only pays for the path to them.
+		early := testing.AllocsPerRun(10, func() { walk(10) })
+		full := testing.AllocsPerRun(10, func() { walk(len(words)) })
+		if early > 100 || full < float64(len(words)) {
+			t.Errorf("%s: Expected the walk to stop early, got %v allocations for 10 words and %v for all of them", tt.name, early, full)
+		}
+	}
+}
+
+func TestServiceWalk(t *testing.T) {
+	words := []string{"bike", "bike path", "pool", "beach"}
+
+	for _, opts := range [][]ConfigFn{nil, {WithBackend(BackendTST)}, {WithBackend(BackendRadix)}} {
+		service := testService(t, words, opts...)
+		assertWords(t, service.GetContents(), slices.Collect(service.Walk()))
+
+		for word := range service.Walk() {
+			if word != "beach" {
+				t.Errorf("Expected beach first, got %s", word)
+			}
+			break
+		}
+
+		service.Close()
+		if got := slices.Collect(service.Walk()); len(got) != 0 {
+			t.Errorf("Expected no words once closed, got %v", got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package autocomplete
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultUpdateDebounce is used when Config.UpdateDebounce is unset.
+const defaultUpdateDebounce = 250 * time.Millisecond
+
+// fileWatcher is the background goroutine reloading the local file data
+// sources when their file changes, see WithAutomaticUpdates().
+type fileWatcher struct {
+	watcher *fsnotify.Watcher
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// startWatcher watches the files of the LocalFileProvider data sources. It
+// is a no-op when there are none.
+//
+// The directories are watched rather than the files themselves, so a file
+// replaced by a rename, like most editors save, keeps being watched.
+func (a *AutocompleteService) startWatcher() error {
+	// Keyed by the cleaned file path, the indexes of the sources reading it.
+	sources := make(map[string][]int)
+	for i, src := range a.Config.DataSources {
+		if _, ok := src.Provider.(*LocalFileProvider); ok {
+			name := filepath.Clean(src.Filepath)
+			sources[name] = append(sources[name], i)
+		}
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("autocompleteservice: watch: %w", err)
+	}
+	dirs := make(map[string]struct{})
+	for name := range sources {
+		dir := filepath.Dir(name)
+		if _, ok := dirs[dir]; ok {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("autocompleteservice: watch: %s: %w", dir, err)
+		}
+		dirs[dir] = struct{}{}
+	}
+
+	debounce := a.Config.UpdateDebounce
+	if debounce <= 0 {
+		debounce = defaultUpdateDebounce
+	}
+
+	w := &fileWatcher{watcher: watcher, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+
+		// The sources changed since the last reload. Every event pushes the
+		// reload back, so a burst of writes only reloads once.
+		pending := make(map[int]struct{})
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+					continue
+				}
+				for _, i := range sources[filepath.Clean(event.Name)] {
+					pending[i] = struct{}{}
+				}
+				if len(pending) > 0 {
+					timer.Reset(debounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				a.Config.Logger.Error("data source watch failed", "error", err)
+			case <-timer.C:
+				for i := range pending {
+					// reloadSource keeps track of the error, a failed reload
+					// shouldn't stop the following ones. A source that keeps
+					// failing is skipped by its circuit breaker.
+					a.reloadSource(i)
+				}
+				clear(pending)
+			}
+		}
+	}()
+
+	a.watcher = w
+	return nil
+}
+
+// stopWatcher stops the file watcher, if running, and waits for it to exit. It
+// must be called with watcherMu held, and never while holding the lifecycle
+// lock since the watcher may be waiting on it to reload a source.
+func (a *AutocompleteService) stopWatcher() error {
+	if a.watcher == nil {
+		return nil
+	}
+
+	close(a.watcher.stop)
+	<-a.watcher.done
+	err := a.watcher.watcher.Close()
+	a.watcher = nil
+	return err
+}
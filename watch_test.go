@@ -0,0 +1,88 @@
+package autocomplete
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAutomaticUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keywords.txt")
+	if err := os.WriteFile(path, []byte("bike\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, _ := NewLocalFileProvider(path)
+	handler := &captureHandler{}
+	service := testService(t, nil,
+		WithDataSources([]DataSource{*NewDataSource(provider, nil, path, "")}),
+		WithLoadDataSourcesOnStart,
+		WithAutomaticUpdates,
+		WithUpdateDebounce(50*time.Millisecond),
+		WithLogger(slog.New(handler)),
+	)
+	defer service.Close()
+
+	loads := func() int {
+		handler.mu.Lock()
+		defer handler.mu.Unlock()
+		n := 0
+		for _, r := range handler.records {
+			if r.Message == "data source loaded" {
+				n++
+			}
+		}
+		return n
+	}
+	if !service.Exists("bike") || loads() != 1 {
+		t.Fatalf("Expected the source to be loaded once on start, got %d loads", loads())
+	}
+	lastUpdated := service.LastUpdated
+
+	// A burst of writes reloads once.
+	for _, content := range []string{"bike\npool\n", "bike\npool\nbeach\n", "bike\npool\nbeach\ndog park\n"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, func() bool { return service.Exists("dog park") })
+	assertWords(t, []string{"beach", "bike", "dog park", "pool"}, service.GetContents())
+
+	time.Sleep(100 * time.Millisecond)
+	if n := loads(); n != 2 {
+		t.Errorf("Expected a single reload, got %d", n-1)
+	}
+	if service.GetLastUpdated() < lastUpdated {
+		t.Errorf("Expected LastUpdated to be updated")
+	}
+
+	// Torn down by Close, later writes are ignored.
+	if err := service.Close(); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+	if service.watcher != nil {
+		t.Errorf("Expected the watcher to be stopped")
+	}
+	if err := os.WriteFile(path, []byte("car\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := loads(); n != 2 {
+		t.Errorf("Expected no reload after Close, got %d", n-2)
+	}
+}
+
+func TestAutomaticUpdatesWithoutFiles(t *testing.T) {
+	service := testService(t, nil,
+		WithDataSources([]DataSource{*NewDataSource(&mockProvider{}, nil, "keywords.json", "")}),
+		WithAutomaticUpdates,
+	)
+	if service.watcher != nil {
+		t.Errorf("Expected no watcher without local file sources")
+	}
+	if err := service.Close(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}